@@ -1,3 +1,5 @@
 module gh.tarampamp.am/describe-commit
 
-go 1.24
+go 1.24.0
+
+require golang.org/x/sync v0.19.0
@@ -0,0 +1,144 @@
+// Package aitest provides the tools for testing the code using the [ai.Provider]: the [MockProvider] with the
+// canned responses, and the assertions of the calls it received.
+package aitest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+type (
+	// Result is the canned result of a single [MockProvider.Query] call: the response, or the error.
+	Result struct {
+		Response *ai.Response
+		Err      error
+	}
+
+	// Call is the recorded [MockProvider.Query] call.
+	Call struct {
+		Changes, Commits string
+		Opts             []ai.Option
+	}
+)
+
+// Answer returns the [Result] with the response containing the answer (and, optionally, the alternatives).
+func Answer(answer string, alternatives ...string) Result {
+	var answers = append([]string{answer}, alternatives...)
+
+	return Result{Response: &ai.Response{Answer: answer, Answers: answers, Alternatives: answers[1:]}}
+}
+
+// Fail returns the [Result] with the error.
+func Fail(err error) Result { return Result{Err: err} }
+
+// ErrNoResults is returned by the [MockProvider] without the canned results.
+var ErrNoResults = errors.New("aitest: no canned results")
+
+// MockName is the name of the [MockProvider] (see [ai.Provider]).
+const MockName = "mock"
+
+// MockProvider is the [ai.Provider] returning the canned results in order (the last one is repeated once they are
+// exhausted), and recording the calls. It's safe for concurrent use.
+type MockProvider struct {
+	mu      sync.Mutex
+	results []Result
+	calls   []Call
+}
+
+var _ ai.Provider = (*MockProvider)(nil) // ensure the interface is implemented
+
+// NewMockProvider creates a new [MockProvider] with the canned results (see [Answer] and [Fail]).
+func NewMockProvider(results ...Result) *MockProvider { return &MockProvider{results: results} }
+
+// Query implements the [ai.Provider] interface. Every call gets its own copy of the canned response, with the
+// provider name set (unless the canned one has it). The context error is returned if it's done.
+func (m *MockProvider) Query(ctx context.Context, changes, commits string, opts ...ai.Option) (*ai.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, Call{Changes: changes, Commits: commits, Opts: slices.Clone(opts)})
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(m.results) == 0 {
+		return nil, ErrNoResults
+	}
+
+	var res = m.results[min(len(m.calls), len(m.results))-1]
+
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	var resp ai.Response
+
+	if res.Response != nil {
+		resp = *res.Response
+		resp.Answers = slices.Clone(res.Response.Answers)
+		resp.Warnings = slices.Clone(res.Response.Warnings)
+
+		if len(resp.Answers) > 0 {
+			resp.Alternatives = resp.Answers[1:]
+		}
+	}
+
+	if resp.Provider == "" {
+		resp.Provider = MockName
+	}
+
+	return &resp, nil
+}
+
+// Name implements the [ai.Provider] interface.
+func (*MockProvider) Name() string { return MockName }
+
+// Calls returns the recorded calls, in order.
+func (m *MockProvider) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.calls)
+}
+
+// AssertCalls checks the number of the recorded calls, and returns them.
+func (m *MockProvider) AssertCalls(t testing.TB, want int) []Call {
+	t.Helper()
+
+	var calls = m.Calls()
+
+	if len(calls) != want {
+		t.Fatalf("aitest: got %d calls, want %d", len(calls), want)
+	}
+
+	return calls
+}
+
+// AssertOptions checks that the options of the call are equal to the given ones. The options are compared by their
+// effect on the output (see [ai.GenerationKey]), so the order of the options does not matter, and the options not
+// affecting the output (e.g. [ai.WithRetries]) are ignored.
+func AssertOptions(t testing.TB, call Call, want ...ai.Option) {
+	t.Helper()
+
+	if ai.GenerationKey("", "", "", call.Opts...) != ai.GenerationKey("", "", "", want...) {
+		t.Errorf("aitest: the call options differ from the expected ones")
+	}
+}
+
+// AssertHasOption checks that the call received the option (it has no effect being applied on top of the options of
+// the call). Like in [AssertOptions], the options not affecting the output are ignored.
+func AssertHasOption(t testing.TB, call Call, opt ai.Option) {
+	t.Helper()
+
+	var opts = append(slices.Clone(call.Opts), opt)
+
+	if ai.GenerationKey("", "", "", call.Opts...) != ai.GenerationKey("", "", "", opts...) {
+		t.Errorf("aitest: the call did not receive the option")
+	}
+}
@@ -0,0 +1,171 @@
+package aitest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+	"gh.tarampamp.am/describe-commit/internal/ai/aitest"
+)
+
+// recordingT records the failures instead of failing the test.
+type recordingT struct {
+	testing.TB
+
+	failures []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...any) { t.Errorf(format, args...) }
+
+func TestMockProvider(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errBoom = errors.New("boom")
+		mock    = aitest.NewMockProvider(aitest.Answer("feat: Add foo", "fix: Fix foo"), aitest.Fail(errBoom),
+			aitest.Answer("docs: Describe foo"),
+		)
+		ctx = context.Background()
+	)
+
+	var p ai.Provider = mock
+
+	assertEqual(t, p.Name(), "mock")
+
+	resp, err := p.Query(ctx, "diff 1", "log 1", ai.WithEmoji(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, fmt.Sprint(resp.Alternatives), "[fix: Fix foo]")
+	assertEqual(t, resp.Provider, "mock")
+
+	if _, err = p.Query(ctx, "diff 2", "log 2"); !errors.Is(err, errBoom) {
+		t.Fatalf("expected the canned error, got %v", err)
+	}
+
+	for range 2 { // the last result is repeated
+		resp, err = p.Query(ctx, "diff 3", "log 3")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertEqual(t, resp.Answer, "docs: Describe foo")
+	}
+
+	var calls = mock.AssertCalls(t, 4)
+
+	assertEqual(t, calls[0].Changes, "diff 1")
+	assertEqual(t, calls[1].Commits, "log 2")
+
+	aitest.AssertOptions(t, calls[0], ai.WithEmoji(true), ai.WithRetries(3, time.Second))
+	aitest.AssertHasOption(t, calls[0], ai.WithEmoji(true))
+	aitest.AssertOptions(t, calls[1])
+
+	t.Run("canned response is copied", func(t *testing.T) {
+		t.Parallel()
+
+		var mock = aitest.NewMockProvider(aitest.Answer("feat: Add foo"))
+
+		for range 2 {
+			resp, err := mock.Query(ctx, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertEqual(t, resp.Answers[0], "feat: Add foo")
+
+			resp.Answers[0] = "changed"
+		}
+	})
+}
+
+func TestMockProvider_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := aitest.NewMockProvider().Query(context.Background(), "", ""); !errors.Is(err, aitest.ErrNoResults) {
+		t.Fatalf("expected ErrNoResults, got %v", err)
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+
+	cancel()
+
+	var mock = aitest.NewMockProvider(aitest.Answer("feat: Add foo"))
+
+	if _, err := mock.Query(ctx, "", ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	mock.AssertCalls(t, 1) // recorded anyway
+}
+
+func TestMockProvider_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mock = aitest.NewMockProvider(aitest.Answer("feat: Add foo"))
+		wg   sync.WaitGroup
+	)
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = mock.Query(context.Background(), "diff", "log")
+		}()
+	}
+
+	wg.Wait()
+
+	mock.AssertCalls(t, 10)
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+
+	var mock = aitest.NewMockProvider(aitest.Answer("feat: Add foo"))
+
+	_, _ = mock.Query(context.Background(), "diff", "log", ai.WithLanguage("de"))
+
+	var (
+		call = mock.Calls()[0]
+		rt   = &recordingT{TB: t}
+	)
+
+	mock.AssertCalls(rt, 2)
+	aitest.AssertOptions(rt, call)
+	aitest.AssertOptions(rt, call, ai.WithLanguage("fr"))
+	aitest.AssertHasOption(rt, call, ai.WithEmoji(true))
+
+	assertEqual(t, len(rt.failures), 4)
+
+	rt.failures = nil
+
+	mock.AssertCalls(rt, 1)
+	aitest.AssertOptions(rt, call, ai.WithLanguage("de"))
+	aitest.AssertHasOption(rt, call, ai.WithLanguage("de"))
+
+	assertEqual(t, len(rt.failures), 0)
+}
+
+func assertEqual[T comparable](t *testing.T, got, want T) {
+	t.Helper()
+
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
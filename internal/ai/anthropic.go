@@ -0,0 +1,233 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Anthropic is a provider for the Anthropic (Claude) Messages API.
+type Anthropic struct {
+	httpClient        httpClient
+	apiKey, modelName string
+}
+
+var _ Provider = (*Anthropic)(nil) // ensure the interface is implemented
+
+type (
+	anthropicOptions struct {
+		HttpClient httpClient
+		Timeout    time.Duration
+	}
+
+	// AnthropicOption allows to customize the Anthropic provider.
+	AnthropicOption func(*anthropicOptions)
+)
+
+// anthropicVersion is the version of the Messages API (the `anthropic-version` header).
+const anthropicVersion = "2023-06-01"
+
+// WithAnthropicHttpClient sets the HTTP client for the Anthropic provider.
+func WithAnthropicHttpClient(c httpClient) AnthropicOption {
+	return func(o *anthropicOptions) { o.HttpClient = c }
+}
+
+// WithAnthropicTimeout sets the timeout of the default HTTP client (60 seconds by default). It has no effect if the
+// custom HTTP client is set (see [WithAnthropicHttpClient]).
+func WithAnthropicTimeout(d time.Duration) AnthropicOption {
+	return func(o *anthropicOptions) { o.Timeout = d }
+}
+
+// NewAnthropic creates a new Anthropic provider.
+func NewAnthropic(apiKey, model string, opt ...AnthropicOption) *Anthropic {
+	var opts = anthropicOptions{Timeout: defaultTimeout}
+
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	var p = Anthropic{
+		httpClient: opts.HttpClient,
+		apiKey:     apiKey,
+		modelName:  model,
+	}
+
+	if p.httpClient == nil { // set default HTTP client
+		p.httpClient = &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: &http.Transport{ForceAttemptHTTP2: true}, // use HTTP/2 (why not?)
+		}
+	}
+
+	return &p
+}
+
+// Query implements the [Provider] interface.
+func (p *Anthropic) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
+
+// Name implements the [Provider] interface.
+func (*Anthropic) Name() string { return ProviderAnthropic }
+
+// model returns the model name.
+func (p *Anthropic) model() string { return p.modelName }
+
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (*Anthropic) batchesCandidates() bool { return false }
+
+// complete performs the requests to the Anthropic API. The Messages API can't generate several candidates at once,
+// so a separate request is made for every candidate.
+func (p *Anthropic) complete(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
+	var result completion
+
+	for range max(opt.Candidates, 1) {
+		res, err := p.completeOne(ctx, instructions, changes, commits, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(result.Answers) == 0 {
+			result.Truncated = res.Truncated // the first answer comes from the first request
+		}
+
+		result.Answers = append(result.Answers, res.Answers...)
+		result.Usage = result.Usage.add(res.Usage)
+	}
+
+	return &result, nil
+}
+
+// completeOne performs a single request to the Anthropic API.
+func (p *Anthropic) completeOne(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
+	req, rErr := p.newRequest(ctx, instructions, changes, commits, opt)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	resp, rErr := doRequest("Anthropic", p.httpClient, req)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.responseToError(resp)
+	}
+
+	return p.parseResponse(resp)
+}
+
+// newRequest creates a new HTTP request for the Anthropic API. Unlike the OpenAI-compatible APIs, the instructions
+// are passed as the top-level `system` field, and the messages contain the user turns only.
+func (p *Anthropic) newRequest(
+	ctx context.Context,
+	instructions, changes, commits string,
+	o options,
+) (*http.Request, error) {
+	var turns = userTurns(changes, commits, o)
+
+	var messages = make([]chatMessage, 0, len(turns))
+
+	for _, turn := range turns {
+		messages = append(messages, chatMessage{Role: "user", Content: turn})
+	}
+
+	// https://docs.anthropic.com/en/api/messages
+	j, jErr := json.Marshal(struct {
+		Model       string        `json:"model"`
+		System      string        `json:"system"`
+		Messages    []chatMessage `json:"messages"`
+		Temperature float64       `json:"temperature"`
+		TopP        *float64      `json:"top_p,omitempty"` // the models may reject both set, so only if requested
+		MaxTokens   int64         `json:"max_tokens"`
+	}{
+		Model:       o.modelOr(p.modelName),
+		System:      instructions,
+		Messages:    messages,
+		Temperature: o.temperature(),
+		TopP:        o.TopP,
+		MaxTokens:   o.MaxOutputTokens,
+	})
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	req, rErr := http.NewRequestWithContext(ctx,
+		http.MethodPost,
+		"https://api.anthropic.com/v1/messages",
+		bytes.NewReader(j),
+	)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.userAgent())
+	req.Header.Set("X-Api-Key", p.apiKey)
+	req.Header.Set("Anthropic-Version", anthropicVersion)
+
+	return req, nil
+}
+
+// responseToError converts the response from the Anthropic API to an error.
+func (p *Anthropic) responseToError(resp *http.Response) error {
+	return newAPIError("Anthropic", resp)
+}
+
+// parseResponse parses the response from the Anthropic API. The text blocks of the content are joined into the
+// single answer.
+func (p *Anthropic) parseResponse(resp *http.Response) (*completion, error) {
+	var answer struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+
+	if dErr := json.NewDecoder(resp.Body).Decode(&answer); dErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, dErr)
+	}
+
+	var b strings.Builder
+
+	for _, block := range answer.Content {
+		if block.Type == "text" {
+			b.WriteString(block.Text)
+		}
+	}
+
+	var text = finalizeAnswer(b.String())
+	if text == "" {
+		return nil, errors.New("no content found")
+	}
+
+	return &completion{
+		Answers: []string{text},
+		Usage: Usage{
+			PromptTokens:     answer.Usage.InputTokens,
+			CompletionTokens: answer.Usage.OutputTokens,
+			TotalTokens:      answer.Usage.InputTokens + answer.Usage.OutputTokens,
+		},
+		Truncated: answer.StopReason == "max_tokens",
+	}, nil
+}
@@ -0,0 +1,100 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestAnthropic_Query(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+		assertEqual(t, req.URL.String(), "https://api.anthropic.com/v1/messages")
+		assertEqual(t, req.Header.Get("x-api-key"), "sk-ant-key")
+		assertEqual(t, req.Header.Get("anthropic-version"), "2023-06-01")
+
+		return newHttpResponse(http.StatusOK, `{
+			"type":"message",
+			"role":"assistant",
+			"content":[{"type":"text","text":"feat: Add foo\n\n"},{"type":"text","text":"- Bar"}],
+			"usage":{"input_tokens":10,"output_tokens":5}
+		}`), nil
+	}}
+
+	resp, err := ai.NewAnthropic("sk-ant-key", "claude-3-5-haiku-latest", ai.WithAnthropicHttpClient(&client)).
+		Query(context.Background(), "diff", "log")
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo\n\n- Bar")
+	assertEqual(t, resp.Usage, ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+
+	var req struct {
+		Model    string `json:"model"`
+		System   string `json:"system"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		MaxTokens int `json:"max_tokens"`
+	}
+
+	assertNoError(t, json.Unmarshal([]byte(client.Requests()[0]), &req))
+
+	assertEqual(t, req.Model, "claude-3-5-haiku-latest")
+	assertEqual(t, req.System, resp.Prompt)
+	assertEqual(t, req.MaxTokens, 500)
+	assertEqual(t, len(req.Messages), 2)
+
+	for _, m := range req.Messages {
+		assertEqual(t, m.Role, "user")
+	}
+}
+
+func TestAnthropic_ShortMessageAndCandidates(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+		var answers = []string{"feat: Add foo\n\n- Bar", "fix: Fix foo\n\n- Baz"}
+
+		j, _ := json.Marshal(answers[n%len(answers)])
+
+		return newHttpResponse(http.StatusOK, `{"content":[{"type":"text","text":`+string(j)+`}]}`), nil
+	}}
+
+	resp, err := ai.NewAnthropic("", "", ai.WithAnthropicHttpClient(&client)).
+		Query(context.Background(), "diff", "log", ai.WithShortMessageOnly(true), ai.WithCandidates(2))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, len(resp.Alternatives), 1)
+	assertEqual(t, resp.Alternatives[0], "fix: Fix foo")
+	assertEqual(t, len(client.Requests()), 2)
+}
+
+func TestAnthropic_Error(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusBadRequest,
+			`{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens"}}`,
+		), nil
+	}}
+
+	_, err := ai.NewAnthropic("", "", ai.WithAnthropicHttpClient(&client)).
+		Query(context.Background(), "diff", "log")
+
+	var apiErr *ai.APIError
+
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+
+	assertEqual(t, apiErr.Provider, "Anthropic")
+	assertEqual(t, apiErr.Message, "prompt is too long: 210000 tokens")
+	assertEqual(t, errors.Is(err, ai.ErrContextTooLong), true, "context too long")
+}
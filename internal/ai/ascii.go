@@ -0,0 +1,68 @@
+package ai
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// asciiFolds maps the common non-ASCII characters (the accented Latin letters, the ligatures, the typographic
+// punctuation, and the special spaces) to their ASCII replacements.
+var asciiFolds = func() map[rune]string { //nolint:gochecknoglobals
+	var folds = make(map[rune]string)
+
+	for ascii, runes := range map[string]string{
+		"a": "àáâãäåāăą", "A": "ÀÁÂÃÄÅĀĂĄ", "c": "çćĉċč", "C": "ÇĆĈĊČ", "d": "ďđð", "D": "ĎĐÐ",
+		"e": "èéêëēĕėęě", "E": "ÈÉÊËĒĔĖĘĚ", "g": "ĝğġģ", "G": "ĜĞĠĢ", "h": "ĥħ", "H": "ĤĦ",
+		"i": "ìíîïĩīĭįı", "I": "ÌÍÎÏĨĪĬĮİ", "j": "ĵ", "J": "Ĵ", "k": "ķ", "K": "Ķ", "l": "ĺļľŀł", "L": "ĹĻĽĿŁ",
+		"n": "ñńņňŉ", "N": "ÑŃŅŇ", "o": "òóôõöøōŏő", "O": "ÒÓÔÕÖØŌŎŐ", "r": "ŕŗř", "R": "ŔŖŘ",
+		"s": "śŝşšș", "S": "ŚŜŞŠȘ", "t": "ţťŧț", "T": "ŢŤŦȚ", "u": "ùúûüũūŭůűų", "U": "ÙÚÛÜŨŪŬŮŰŲ",
+		"w": "ŵ", "W": "Ŵ", "y": "ýÿŷ", "Y": "ÝŸŶ", "z": "źżž", "Z": "ŹŻŽ",
+		"ss": "ß", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ", "th": "þ", "TH": "Þ",
+		"'": "‘’‚‛′", `"`: "“”„‟″«»", "-": "‐‑‒–—―−•", "...": "…", "x": "×", " ": "\u00a0\u2002\u2003\u2009\u202f",
+	} {
+		for _, r := range runes {
+			folds[r] = ascii
+		}
+	}
+
+	return folds
+}()
+
+// toASCII transliterates the message to ASCII: the characters listed in [asciiFolds] are replaced, and the rest of
+// the non-ASCII characters (e.g. emoji) are dropped, along with the spaces left doubled (or dangling) by the drop.
+func toASCII(s string) string {
+	var (
+		b       strings.Builder
+		dropped bool // the previous character is dropped
+	)
+
+	b.Grow(len(s))
+
+	for _, r := range s {
+		switch fold, ok := asciiFolds[r]; {
+		case r < utf8.RuneSelf:
+			if dropped && r == ' ' && (b.Len() == 0 || strings.HasSuffix(b.String(), " ") ||
+				strings.HasSuffix(b.String(), "\n")) {
+				continue
+			}
+
+			b.WriteRune(r)
+		case ok:
+			b.WriteString(fold)
+		default:
+			dropped = true
+
+			continue
+		}
+
+		dropped = false
+	}
+
+	var lines = strings.Split(b.String(), "\n")
+
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,54 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// auditRecord is a single line of the audit log.
+type auditRecord struct {
+	Time       time.Time `json:"time"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	DiffSHA256 string    `json:"diff_sha256"`
+	Usage      Usage     `json:"usage"`
+	Subject    string    `json:"subject"`
+	Body       string    `json:"body,omitempty"`
+}
+
+// writeAuditRecord writes the audit record for the given response as a single JSON line.
+func writeAuditRecord(w io.Writer, changes string, r *Response, withBody bool) error {
+	var (
+		hash             = sha256.Sum256([]byte(normalizeNewlines(changes))) // the same as for [GenerationKey]
+		subject, body, _ = strings.Cut(r.Answer, "\n")
+		record           = auditRecord{
+			Time:       time.Now().UTC(),
+			Provider:   r.Provider,
+			Model:      r.Model,
+			DiffSHA256: hex.EncodeToString(hash[:]),
+			Usage:      r.Usage,
+			Subject:    strings.TrimSpace(subject),
+		}
+	)
+
+	if withBody {
+		record.Body = strings.TrimSpace(body)
+	}
+
+	j, jErr := json.Marshal(record)
+	if jErr != nil {
+		return jErr
+	}
+
+	// the record is written using a single call to keep the lines intact when the writer is shared
+	if _, err := w.Write(append(j, '\n')); err != nil {
+		return fmt.Errorf("failed to write the audit record: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package ai_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestWithAuditLog(t *testing.T) {
+	t.Parallel()
+
+	const diff = "diff --git a/secret.go b/secret.go\n+const password = \"hunter2\""
+
+	var (
+		client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK, `{
+				"choices":[{"message":{"content":"feat: Add foo\n\nSome details"}}],
+				"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}
+			}`), nil
+		}}
+		provider = ai.NewOpenAI("key", "gpt-test", ai.WithOpenAIHttpClient(&client))
+		buf      bytes.Buffer
+	)
+
+	for _, changes := range []string{diff, strings.ReplaceAll(diff, "\n", "\r\n")} { // the line endings are normalized
+		_, err := provider.Query(context.Background(), changes, "log", ai.WithAuditLog(&buf))
+		assertNoError(t, err)
+	}
+
+	var lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	assertEqual(t, len(lines), 2)
+
+	for _, line := range lines {
+		if strings.Contains(line, "hunter2") || strings.Contains(line, "Some details") {
+			t.Errorf("the audit record must not contain the diff or the body: %s", line)
+		}
+
+		var record struct {
+			Time       string `json:"time"`
+			Provider   string `json:"provider"`
+			Model      string `json:"model"`
+			DiffSHA256 string `json:"diff_sha256"`
+			Usage      struct {
+				TotalTokens int `json:"total_tokens"`
+			} `json:"usage"`
+			Subject string `json:"subject"`
+		}
+
+		assertNoError(t, json.Unmarshal([]byte(line), &record))
+
+		var hash = sha256.Sum256([]byte(diff))
+
+		assertEqual(t, record.Time != "", true, "time")
+		assertEqual(t, record.Provider, ai.ProviderOpenAI)
+		assertEqual(t, record.Model, "gpt-test")
+		assertEqual(t, record.DiffSHA256, hex.EncodeToString(hash[:]))
+		assertEqual(t, record.Usage.TotalTokens, 15)
+		assertEqual(t, record.Subject, "feat: Add foo")
+	}
+}
+
+func TestWithAuditLogBody(t *testing.T) {
+	t.Parallel()
+
+	var (
+		client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo\n\nSome details"}}]}`), nil
+		}}
+		buf bytes.Buffer
+	)
+
+	_, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(&client)).Query(
+		context.Background(), "diff", "log", ai.WithAuditLog(&buf), ai.WithAuditLogBody(true),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, strings.Contains(buf.String(), `"body":"Some details"`), true)
+}
+
+func TestWithAuditLog_OverflowModel(t *testing.T) {
+	t.Parallel()
+
+	var (
+		client = fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+			if n == 0 {
+				return newHttpResponse(http.StatusBadRequest, `{"error":{"code":"context_length_exceeded"}}`), nil
+			}
+
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+		}}
+		buf bytes.Buffer
+	)
+
+	_, err := ai.NewOpenAI("key", "gpt-test", ai.WithOpenAIHttpClient(&client)).Query(
+		context.Background(), "diff", "log", ai.WithAuditLog(&buf), ai.WithOverflowModel("gpt-large"),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, strings.Contains(buf.String(), `"model":"gpt-large"`), true, "the model that served the query")
+}
@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxRevertedPatterns limits the number of the reverted commits taken from the log (see [WithAvoidReverted]).
+const maxRevertedPatterns = 10
+
+// revertedSubjectRegex matches the subjects of the reverted commits: `revert: <subject>` (Conventional Commits,
+// with the optional scope) and `Revert "<subject>"` (the git default).
+var revertedSubjectRegex = regexp.MustCompile(`^(?:revert(?:\([^)]*\))?!?:\s*|Revert\s+)(.+)$`)
+
+// revertedSubjects scans the log (one subject per line, the newest first) for the revert commits, and returns the
+// subjects of the original (reverted) commits, without the duplicates.
+func revertedSubjects(log string) []string {
+	var subjects []string
+
+	for _, line := range strings.Split(log, "\n") {
+		var m = revertedSubjectRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		var subject = strings.TrimSpace(m[1])
+
+		if len(subject) > 1 && subject[0] == '"' && subject[len(subject)-1] == '"' {
+			subject = strings.TrimSpace(subject[1 : len(subject)-1])
+		}
+
+		if subject != "" && !slices.Contains(subjects, subject) {
+			if subjects = append(subjects, subject); len(subjects) == maxRevertedPatterns {
+				break
+			}
+		}
+	}
+
+	return subjects
+}
+
+// withRevertedPatterns adds the subjects of the commits reverted in the log to the patterns to avoid, if enabled
+// (see [WithAvoidReverted]).
+func withRevertedPatterns(commits string, opts []Option) []Option {
+	if !(options{}).Apply(opts...).AvoidReverted {
+		return opts
+	}
+
+	var subjects = revertedSubjects(commits)
+	if len(subjects) == 0 {
+		return opts
+	}
+
+	return append(opts[:len(opts):len(opts)], func(o *options) {
+		o.AvoidPatterns = append(slices.Clip(o.AvoidPatterns), subjects...)
+	})
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRevertedSubjects(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		give string
+		want []string
+	}{
+		"no reverts": {give: "feat: Add foo\nfix: Fix bar\n"},
+		"conventional": {
+			give: "feat: Add foo\nrevert: feat(api): Add the rate-limiting\nrevert(ui)!: \"fix: Tweak the colors\"\n",
+			want: []string{"feat(api): Add the rate-limiting", "fix: Tweak the colors"},
+		},
+		"git default": {
+			give: "Revert \"chore: Bump the deps\"\nfix: Fix bar\n",
+			want: []string{"chore: Bump the deps"},
+		},
+		"duplicates": {
+			give: "revert: feat: Add foo\nRevert \"feat: Add foo\"\n",
+			want: []string{"feat: Add foo"},
+		},
+		"not a revert": {give: "feat: Add the revert button\nreverted: typo\nrevert:\n"},
+		"limited": {
+			give: "revert: a\nrevert: b\nrevert: c\nrevert: d\n" +
+				"revert: e\nrevert: f\nrevert: g\nrevert: h\nrevert: i\nrevert: j\nrevert: k\n",
+			want: []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := revertedSubjects(tc.give); !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
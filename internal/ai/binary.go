@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// formatBinaryChanges formats the metadata of the changed binary files (see [WithBinaryMetadata]) as a list, e.g.
+// `- logo.png (image/png, 512x512): 10.0 KiB -> 6.0 KiB (-40%)`.
+func formatBinaryChanges(changes []git.BinaryChange) string {
+	var b strings.Builder
+
+	for _, c := range changes {
+		var (
+			current = c.New
+			details []string
+		)
+
+		if current == nil {
+			current = c.Old
+		}
+
+		if current.Type != "" {
+			details = append(details, current.Type)
+		}
+
+		switch {
+		case c.Old != nil && c.New != nil && c.Old.Width > 0 && c.New.Width > 0 &&
+			(c.Old.Width != c.New.Width || c.Old.Height != c.New.Height):
+			details = append(details, fmt.Sprintf("%dx%d -> %dx%d", c.Old.Width, c.Old.Height, c.New.Width, c.New.Height))
+		case current.Width > 0:
+			details = append(details, fmt.Sprintf("%dx%d", current.Width, current.Height))
+		}
+
+		b.WriteString("- " + c.Path)
+
+		if len(details) > 0 {
+			b.WriteString(" (" + strings.Join(details, ", ") + ")")
+		}
+
+		switch {
+		case c.Old == nil:
+			b.WriteString(": added, " + formatSize(c.New.Size))
+		case c.New == nil:
+			b.WriteString(": deleted, " + formatSize(c.Old.Size))
+		default:
+			b.WriteString(": " + formatSize(c.Old.Size) + " -> " + formatSize(c.New.Size))
+
+			if c.Old.Size > 0 {
+				_, _ = fmt.Fprintf(&b, " (%+.0f%%)", float64(c.New.Size-c.Old.Size)*100/float64(c.Old.Size)) //nolint:mnd
+			}
+		}
+
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// formatSize formats the size in bytes using the binary units (e.g. "1.5 KiB").
+func formatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	var div, exp = int64(unit), 0
+
+	for n := size / unit; n >= unit && exp < 3; n /= unit { //nolint:mnd
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,44 @@
+package ai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestWithBinaryMetadata(t *testing.T) {
+	t.Parallel()
+
+	var dir = newGitRepo(t, map[string]string{"assets/logo.png": "\x00" + strings.Repeat("\x01", 2047)})
+
+	runGit(t, dir, "commit", "--quiet", "-m", "feat: Add the logo")
+	writeFiles(t, dir, map[string]string{"assets/logo.png": "\x00" + strings.Repeat("\x02", 1228)})
+
+	changes, err := git.Diff(context.Background(), dir)
+	assertNoError(t, err)
+
+	var provider = ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(okClient("chore(assets): Optimize logo")))
+
+	resp, err := provider.Query(context.Background(), changes, "", ai.WithGitDir(dir), ai.WithBinaryMetadata(true))
+	assertNoError(t, err)
+
+	for _, want := range []string{
+		"Metadata of the changed binary files",
+		"- assets/logo.png (image/png): 2.0 KiB -> 1.2 KiB (-40%)",
+	} {
+		if !strings.Contains(resp.Prompt, want) {
+			t.Errorf("expected the prompt to contain %q:\n%s", want, resp.Prompt)
+		}
+	}
+
+	// disabled by default
+	resp, err = provider.Query(context.Background(), changes, "", ai.WithGitDir(dir))
+	assertNoError(t, err)
+
+	if strings.Contains(resp.Prompt, "Metadata of the changed binary files") {
+		t.Error("expected no binary metadata by default")
+	}
+}
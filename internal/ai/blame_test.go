@@ -0,0 +1,50 @@
+package ai_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestWithBlameContext(t *testing.T) {
+	t.Parallel()
+
+	var dir = newGitRepo(t, map[string]string{"main.go": "package main\n\nconst answer = 41\n"})
+
+	runGit(t, dir, "commit", "--quiet", "-m", "feat: Add the answer")
+	writeFiles(t, dir, map[string]string{"main.go": "package main\n\nconst answer = 42\n"})
+
+	changes, err := git.Diff(context.Background(), dir)
+	assertNoError(t, err)
+
+	var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"fix: Correct the answer"}}]}`), nil
+	}}
+
+	var provider = ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(&client))
+
+	resp, err := provider.Query(context.Background(), changes, "", ai.WithGitDir(dir), ai.WithBlameContext(true))
+	assertNoError(t, err)
+
+	for _, want := range []string{"Prior authorship of the changed lines", `by Test: \"feat: Add the answer\"`} {
+		if !strings.Contains(resp.Prompt, strings.ReplaceAll(want, `\"`, `"`)) {
+			t.Errorf("expected the prompt to contain %q", want)
+		}
+
+		if !strings.Contains(client.Requests()[0], want) {
+			t.Errorf("expected the request to contain %q", want)
+		}
+	}
+
+	// disabled by default
+	resp, err = provider.Query(context.Background(), changes, "", ai.WithGitDir(dir))
+	assertNoError(t, err)
+
+	if strings.Contains(resp.Prompt, "Prior authorship") {
+		t.Error("expected no blame context by default")
+	}
+}
@@ -0,0 +1,375 @@
+package ai
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// defaultMaxLineLength is the default limit of the diff line length (see [WithMaxLineLength]).
+const defaultMaxLineLength = 500
+
+// truncatedLineMarker is appended to the truncated diff lines.
+const truncatedLineMarker = "…(truncated)"
+
+// prepareChanges preprocesses the diff before sending it to the model. The number of the omitted hunks (see
+// [WithMaxHunks]) is returned as well.
+func prepareChanges(changes string, o options) (_ string, omittedHunks int) {
+	if o.MaxLineLength >= 0 {
+		changes = truncateLines(changes, cmp.Or(o.MaxLineLength, defaultMaxLineLength))
+	}
+
+	if !o.KeepIndexLines {
+		changes = stripIndexLines(changes)
+	}
+
+	if o.AdditionsOnly {
+		changes = stripDeletions(changes)
+	}
+
+	if o.MaxHunks > 0 {
+		changes, omittedHunks = limitHunks(changes, o.MaxHunks)
+	}
+
+	if o.MaxBytesPerFile > 0 {
+		changes = truncateFiles(changes, o.MaxBytesPerFile)
+	}
+
+	if o.MaxDiffBytes > 0 {
+		changes = truncateDiff(changes, o.MaxDiffBytes)
+	}
+
+	return changes, omittedHunks
+}
+
+// truncateLines truncates the diff lines longer than the given number of bytes (at the UTF-8 character boundary),
+// appending the [truncatedLineMarker] to them.
+func truncateLines(diff string, maxLen int) string {
+	if len(diff) <= maxLen { // fast path: no line can be longer than the whole diff
+		return diff
+	}
+
+	var (
+		lines     = strings.SplitAfter(diff, "\n")
+		b         strings.Builder
+		truncated bool
+	)
+
+	for _, line := range lines {
+		var content, eol = strings.TrimSuffix(line, "\n"), ""
+
+		if len(content) < len(line) {
+			eol = "\n"
+		}
+
+		if len(content) <= maxLen {
+			b.WriteString(line)
+
+			continue
+		}
+
+		var cut = maxLen
+
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+
+		b.WriteString(content[:cut])
+		b.WriteString(truncatedLineMarker)
+		b.WriteString(eol)
+
+		truncated = true
+	}
+
+	if !truncated {
+		return diff
+	}
+
+	return b.String()
+}
+
+// stripIndexLines removes the `index <hash>..<hash>`, `new file mode`, `old mode` and `new mode` lines from the
+// diff, since they carry no meaning for the model and waste tokens. The `diff --git` and `---`/`+++` headers are
+// kept. Lines of the hunks always start with a space, "+", "-" or "\", so they are never affected.
+func stripIndexLines(diff string) string {
+	var (
+		lines = strings.SplitAfter(diff, "\n")
+		b     strings.Builder
+	)
+
+	b.Grow(len(diff))
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "index ") ||
+			strings.HasPrefix(line, "new file mode ") ||
+			strings.HasPrefix(line, "old mode ") ||
+			strings.HasPrefix(line, "new mode ") {
+			continue
+		}
+
+		b.WriteString(line)
+	}
+
+	return b.String()
+}
+
+// stripDeletions removes the deleted lines (starting with "-") from the hunks, leaving only the context and the
+// additions. The file headers (including `--- a/<path>`) are kept, so the deleted files are still visible. Note
+// that the hunk headers are left as is, so the line counts in them no longer match.
+func stripDeletions(diff string) string {
+	var (
+		lines  = strings.SplitAfter(diff, "\n")
+		b      strings.Builder
+		inHunk bool
+	)
+
+	b.Grow(len(diff))
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			inHunk = false
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case inHunk && strings.HasPrefix(line, "-"):
+			continue
+		}
+
+		b.WriteString(line)
+	}
+
+	return b.String()
+}
+
+// truncateFiles truncates every file section of the diff to the given number of bytes (at a line boundary), so one
+// huge file doesn't crowd out the others. A note about the omitted bytes is added to every truncated section.
+func truncateFiles(diff string, maxBytes int) string {
+	var files = git.SplitPatch(diff)
+	if len(files) == 0 {
+		return diff
+	}
+
+	var b strings.Builder
+
+	b.Grow(len(diff))
+
+	if i := strings.Index(diff, files[0].Text); i > 0 {
+		b.WriteString(diff[:i]) // keep anything before the first section as is
+	}
+
+	for _, f := range files {
+		if len(f.Text) <= maxBytes {
+			b.WriteString(f.Text)
+
+			continue
+		}
+
+		var cut = strings.LastIndexByte(f.Text[:maxBytes], '\n') + 1
+		if cut == 0 { // the first line is longer than the limit, keep it anyway
+			if cut = strings.IndexByte(f.Text, '\n') + 1; cut == 0 {
+				cut = len(f.Text)
+			}
+		}
+
+		b.WriteString(f.Text[:cut])
+
+		if omitted := len(f.Text) - cut; omitted > 0 {
+			b.WriteString(fmt.Sprintf("... (the rest of the file diff is truncated, %d bytes omitted)\n", omitted))
+		}
+	}
+
+	return b.String()
+}
+
+// truncateDiff truncates the diff to the given number of bytes, keeping the file and hunk headers, and the same
+// number of the first lines of every hunk (as many as fit). A "... (truncated)" marker is added to every truncated
+// file section. If even the headers don't fit, the diff is cut at a line boundary (see [truncateChanges]).
+func truncateDiff(diff string, maxBytes int) string {
+	if len(diff) <= maxBytes {
+		return diff
+	}
+
+	type section struct {
+		header string
+		hunks  [][]string // the lines of every hunk, the first one is the `@@` header
+	}
+
+	var (
+		files    = git.SplitPatch(diff)
+		prefix   string
+		sections = make([]section, len(files))
+		longest  int // the max number of the hunk lines (excluding the header)
+	)
+
+	if len(files) == 0 {
+		return truncateChanges(diff, maxBytes)
+	}
+
+	if i := strings.Index(diff, files[0].Text); i > 0 {
+		prefix = diff[:i] // keep anything before the first section as is
+	}
+
+	for i, f := range files {
+		var header, hunks = f.SplitHunks()
+
+		sections[i].header = header
+
+		for _, h := range hunks {
+			var lines = strings.SplitAfter(strings.TrimSuffix(h, "\n"), "\n")
+
+			sections[i].hunks = append(sections[i].hunks, lines)
+			longest = max(longest, len(lines)-1)
+		}
+	}
+
+	var render = func(n int) string { // keeps at most n lines of every hunk
+		var b strings.Builder
+
+		b.WriteString(prefix)
+
+		for _, s := range sections {
+			var truncated bool
+
+			b.WriteString(s.header)
+
+			for _, lines := range s.hunks {
+				var keep = min(len(lines), n+1)
+
+				for _, line := range lines[:keep] {
+					b.WriteString(line)
+				}
+
+				if !strings.HasSuffix(lines[keep-1], "\n") {
+					b.WriteByte('\n')
+				}
+
+				truncated = truncated || keep < len(lines)
+			}
+
+			if truncated {
+				b.WriteString("... (truncated)\n")
+			}
+		}
+
+		return b.String()
+	}
+
+	var lo, hi = 0, longest // the number of the kept lines per hunk
+
+	for lo < hi {
+		var mid = (lo + hi + 1) / 2 //nolint:mnd
+
+		if len(render(mid)) <= maxBytes {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return truncateChanges(render(lo), maxBytes) // no-op, unless even the headers don't fit
+}
+
+// limitHunks keeps at most the given number of hunks of the diff and returns the number of the omitted ones. The
+// hunks of the source files are preferred, then the larger ones; the kept hunks stay in their original order. The
+// file headers are always kept, so the model still knows which files were changed.
+func limitHunks(diff string, maxHunks int) (string, int) {
+	type hunk struct {
+		pos, file int // the position in the diff and the index of the file section
+		source    bool
+		text      string
+	}
+
+	var (
+		files   = git.SplitPatch(diff)
+		headers = make([]string, len(files))
+		hunks   []hunk
+	)
+
+	for i, f := range files {
+		var header, texts = f.SplitHunks()
+
+		headers[i] = header
+
+		for _, text := range texts {
+			hunks = append(hunks, hunk{pos: len(hunks), file: i, source: isSourceFile(f.Path), text: text})
+		}
+	}
+
+	if len(hunks) <= maxHunks {
+		return diff, 0
+	}
+
+	var ranked = slices.Clone(hunks)
+
+	slices.SortStableFunc(ranked, func(a, b hunk) int {
+		if a.source != b.source {
+			if a.source {
+				return -1
+			}
+
+			return 1
+		}
+
+		return cmp.Compare(len(b.text), len(a.text))
+	})
+
+	var keep = make([]bool, len(hunks))
+
+	for _, h := range ranked[:maxHunks] {
+		keep[h.pos] = true
+	}
+
+	var b strings.Builder
+
+	b.Grow(len(diff))
+
+	if i := strings.Index(diff, files[0].Text); i > 0 {
+		b.WriteString(diff[:i]) // keep anything before the first section as is
+	}
+
+	for i, next := 0, 0; i < len(files); i++ {
+		var (
+			last    = headers[i]
+			omitted int
+		)
+
+		b.WriteString(headers[i])
+
+		for ; next < len(hunks) && hunks[next].file == i; next++ {
+			if !keep[next] {
+				omitted++
+
+				continue
+			}
+
+			last = hunks[next].text
+			b.WriteString(last)
+		}
+
+		if omitted > 0 {
+			if !strings.HasSuffix(last, "\n") {
+				b.WriteByte('\n')
+			}
+
+			b.WriteString(fmt.Sprintf("... (%d of the file hunks omitted)\n", omitted))
+		}
+	}
+
+	return b.String(), len(hunks) - maxHunks
+}
+
+// truncateChanges truncates the diff to the given number of bytes (at a line boundary), adding a note about the
+// omitted part.
+func truncateChanges(diff string, maxBytes int) string {
+	if len(diff) <= maxBytes {
+		return diff
+	}
+
+	var cut = strings.LastIndexByte(diff[:maxBytes], '\n') + 1
+
+	return diff[:cut] + fmt.Sprintf("... (the diff is truncated, %d bytes omitted)\n", len(diff)-cut)
+}
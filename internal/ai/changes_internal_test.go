@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTruncateLines(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		give   string
+		maxLen int
+		want   string
+	}{
+		"short lines":      {give: "+a\n+b\n", maxLen: 2, want: "+a\n+b\n"},
+		"long line":        {give: "+abcdef\n+b\n", maxLen: 4, want: "+abc…(truncated)\n+b\n"},
+		"no trailing eol":  {give: "+a\n+abcdef", maxLen: 4, want: "+a\n+abc…(truncated)"},
+		"utf-8 boundary":   {give: "+ыыы\n", maxLen: 4, want: "+ы…(truncated)\n"}, // "ы" is 2 bytes long
+		"exactly at limit": {give: "+abc\n", maxLen: 4, want: "+abc\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := truncateLines(tc.give, tc.maxLen); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	t.Parallel()
+
+	const (
+		fooHeader = "diff --git a/foo.go b/foo.go\n--- a/foo.go\n+++ b/foo.go\n"
+		barHeader = "diff --git a/bar.go b/bar.go\n--- a/bar.go\n+++ b/bar.go\n"
+	)
+
+	var (
+		lines = func(prefix string, n int) (s string) {
+			for i := range n {
+				s += fmt.Sprintf("+%s%d\n", prefix, i+1)
+			}
+
+			return s
+		}
+		multi = fooHeader +
+			"@@ -1,9 +1,9 @@\n" + lines("a", 9) +
+			"@@ -20,2 +20,2 @@\n" + lines("b", 2) +
+			barHeader +
+			"@@ -1,9 +1,9 @@\n" + lines("c", 9)
+		huge = fooHeader + "@@ -1,1000 +1,1000 @@\n" + strings.Repeat("+line\n", 1000)
+	)
+
+	t.Run("fits", func(t *testing.T) {
+		t.Parallel()
+
+		assertEqual(t, truncateDiff(multi, len(multi)), multi)
+	})
+
+	t.Run("multiple files", func(t *testing.T) {
+		t.Parallel()
+
+		const want = fooHeader +
+			"@@ -1,9 +1,9 @@\n+a1\n+a2\n" +
+			"@@ -20,2 +20,2 @@\n+b1\n+b2\n" +
+			"... (truncated)\n" +
+			barHeader +
+			"@@ -1,9 +1,9 @@\n+c1\n+c2\n" +
+			"... (truncated)\n"
+
+		assertEqual(t, truncateDiff(multi, len(want)), want)
+		assertEqual(t, truncateDiff(multi, len(want)+2), want) // one more line doesn't fit
+	})
+
+	t.Run("headers only", func(t *testing.T) {
+		t.Parallel()
+
+		const want = fooHeader +
+			"@@ -1,9 +1,9 @@\n@@ -20,2 +20,2 @@\n... (truncated)\n" +
+			barHeader +
+			"@@ -1,9 +1,9 @@\n... (truncated)\n"
+
+		assertEqual(t, truncateDiff(multi, len(want)), want)
+	})
+
+	t.Run("single huge file", func(t *testing.T) {
+		t.Parallel()
+
+		var got = truncateDiff(huge, 1000)
+
+		if len(got) > 1000 || !strings.HasPrefix(got, fooHeader+"@@ -1,1000 +1,1000 @@\n+line\n") ||
+			!strings.HasSuffix(got, "+line\n... (truncated)\n") {
+			t.Errorf("unexpected result:\n%s", got)
+		}
+	})
+
+	t.Run("headers don't fit", func(t *testing.T) {
+		t.Parallel()
+
+		var got = truncateDiff(multi, 40)
+
+		if len(got) > 100 || !strings.Contains(got, "the diff is truncated") {
+			t.Errorf("unexpected result:\n%s", got)
+		}
+	})
+}
+
+func assertEqual[T comparable](t *testing.T, got, want T) {
+	t.Helper()
+
+	if got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
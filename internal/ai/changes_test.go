@@ -0,0 +1,258 @@
+package ai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_StripIndexLines(t *testing.T) {
+	t.Parallel()
+
+	const diff = `diff --git a/foo.go b/foo.go
+new file mode 100644
+index 0000000..d4e5f60
+--- /dev/null
++++ b/foo.go
+@@ -0,0 +1 @@
++index := 0
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+
+	for name, tc := range map[string]struct {
+		giveOpts    []ai.Option
+		wantContain []string
+		wantNot     []string
+	}{
+		"stripped by default": {
+			wantContain: []string{"diff --git a/foo.go b/foo.go", "--- /dev/null", "+++ b/foo.go", "+index := 0"},
+			wantNot:     []string{"index 0000000..d4e5f60", "new file mode", "old mode", "new mode"},
+		},
+		"kept": {
+			giveOpts:    []ai.Option{ai.WithKeepIndexLines(true)},
+			wantContain: []string{"index 0000000..d4e5f60", "new file mode 100644", "old mode 100644", "new mode 100755"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat: Add foo")
+
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), diff, "", tc.giveOpts...)
+			assertNoError(t, err)
+
+			var sent = openaiMessages(t, client.Requests()[0])[1]
+
+			for _, want := range tc.wantContain {
+				if !strings.Contains(sent, want) {
+					t.Errorf("expected %q to contain %q", sent, want)
+				}
+			}
+
+			for _, want := range tc.wantNot {
+				if strings.Contains(sent, want) {
+					t.Errorf("expected %q to not contain %q", sent, want)
+				}
+			}
+		})
+	}
+}
+
+func TestQuery_AdditionsOnly(t *testing.T) {
+	t.Parallel()
+
+	const diff = `diff --git a/foo.go b/foo.go
+--- a/foo.go
++++ b/foo.go
+@@ -1,4 +1,4 @@
+ package foo
+-// old comment
+--- decremented := 1
++// new comment
+ var x = 1
+diff --git a/bar.go b/bar.go
+deleted file mode 100644
+--- a/bar.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package bar
+`
+
+	var client = okClient("feat: Add foo")
+
+	_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+		Query(context.Background(), diff, "", ai.WithAdditionsOnly(true))
+	assertNoError(t, err)
+
+	var sent = openaiMessages(t, client.Requests()[0])[1]
+
+	for _, want := range []string{
+		"--- a/foo.go", "+++ b/foo.go", "@@ -1,4 +1,4 @@", " package foo", "+// new comment", " var x = 1",
+		"diff --git a/bar.go b/bar.go", "--- a/bar.go", "+++ /dev/null",
+	} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("expected %q to contain %q", sent, want)
+		}
+	}
+
+	for _, want := range []string{"-// old comment", "--- decremented", "-package bar"} {
+		if strings.Contains(sent, want) {
+			t.Errorf("expected %q to not contain %q", sent, want)
+		}
+	}
+}
+
+func TestQuery_MaxBytesPerFile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		small = "diff --git a/small.go b/small.go\n--- a/small.go\n+++ b/small.go\n@@ -1 +1 @@\n-a\n+b\n"
+		huge  = "diff --git a/huge.sql b/huge.sql\n--- a/huge.sql\n+++ b/huge.sql\n@@ -0,0 +1,5000 @@\n" +
+			strings.Repeat("+INSERT INTO t VALUES (1);\n", 5000)
+		last = "diff --git a/last.md b/last.md\n--- a/last.md\n+++ b/last.md\n@@ -1 +1 @@\n-# A\n+# B\n"
+	)
+
+	var client = okClient("feat: Add foo")
+
+	_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+		Query(context.Background(), small+huge+last, "", ai.WithMaxBytesPerFile(256))
+	assertNoError(t, err)
+
+	var sent = openaiMessages(t, client.Requests()[0])[1]
+
+	for _, want := range []string{small, last, "+++ b/huge.sql", "+INSERT INTO t VALUES (1);\n", "bytes omitted)"} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("expected %q to contain %q", sent, want)
+		}
+	}
+
+	if len(sent) > len(small)+len(last)+512 {
+		t.Errorf("the huge file is not truncated, the sent diff is %d bytes long", len(sent))
+	}
+
+	var _, hugeSection, _ = strings.Cut(sent, "diff --git a/huge.sql")
+
+	hugeSection, _, _ = strings.Cut(hugeSection, "... (the rest")
+
+	if !strings.HasSuffix(hugeSection, "\n") || strings.Count(hugeSection, "INSERT") == 0 {
+		t.Errorf("the huge file is not truncated at a line boundary: %q", hugeSection)
+	}
+}
+
+func TestQuery_MaxHunks(t *testing.T) {
+	t.Parallel()
+
+	const diff = "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n" +
+		"@@ -1 +1 @@\n-readme one\n+readme one, but much longer than the others\n" +
+		"@@ -10 +10 @@\n-readme two\n+readme two\n" +
+		"diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n" +
+		"@@ -1 +1 @@\n-small\n+go one\n" +
+		"@@ -10,2 +10,3 @@ func main() {\n-bigger\n+go two\n+go two, the largest hunk of the file\n" +
+		"@@ -20 +21 @@\n-medium\n+go three, a bit\n" +
+		"diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n"
+
+	for name, tc := range map[string]struct {
+		giveMax     int
+		wantOmitted int
+		wantContain []string
+		wantNot     []string
+	}{
+		"source and larger hunks first": {
+			giveMax:     2,
+			wantOmitted: 3,
+			wantContain: []string{
+				"diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n... (2 of the file hunks omitted)\n",
+				"+++ b/main.go\n@@ -10,2 +10,3 @@ func main() {\n-bigger\n+go two\n+go two, the largest hunk of the file\n" +
+					"@@ -20 +21 @@\n-medium\n+go three, a bit\n... (1 of the file hunks omitted)\n",
+				"diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n",
+			},
+			wantNot: []string{"go one", "readme"},
+		},
+		"non-source hunks fill the rest": {
+			giveMax:     4,
+			wantOmitted: 1,
+			wantContain: []string{"go one", "go two", "go three", "readme one", "... (1 of the file hunks omitted)"},
+			wantNot:     []string{"readme two"},
+		},
+		"under the limit": {
+			giveMax:     5,
+			wantContain: []string{diff},
+			wantNot:     []string{"omitted"},
+		},
+		"no limit": {
+			wantContain: []string{diff},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat: Add foo")
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), diff, "", ai.WithMaxHunks(tc.giveMax))
+			assertNoError(t, err)
+
+			assertEqual(t, resp.OmittedHunks, tc.wantOmitted)
+
+			var sent = openaiMessages(t, client.Requests()[0])[1]
+
+			for _, want := range tc.wantContain {
+				if !strings.Contains(sent, want) {
+					t.Errorf("expected %q to contain %q", sent, want)
+				}
+			}
+
+			for _, want := range tc.wantNot {
+				if strings.Contains(sent, want) {
+					t.Errorf("expected %q to not contain %q", sent, want)
+				}
+			}
+		})
+	}
+}
+
+func TestQuery_MaxLineLength(t *testing.T) {
+	t.Parallel()
+
+	var (
+		minified = "+" + strings.Repeat("var a=1;", 100_000) // ~800 KB on a single line
+		diff     = "diff --git a/app.min.js b/app.min.js\n--- a/app.min.js\n+++ b/app.min.js\n@@ -1 +1 @@\n" +
+			minified + "\n+short line\n"
+	)
+
+	for name, tc := range map[string]struct {
+		giveOpts      []ai.Option
+		wantTruncated bool
+		wantMaxSize   int
+	}{
+		"default":  {wantTruncated: true, wantMaxSize: 500},
+		"custom":   {giveOpts: []ai.Option{ai.WithMaxLineLength(100)}, wantTruncated: true, wantMaxSize: 100},
+		"disabled": {giveOpts: []ai.Option{ai.WithMaxLineLength(-1)}, wantMaxSize: len(minified)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("build: Update the bundle")
+
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), diff, "", tc.giveOpts...)
+			assertNoError(t, err)
+
+			var sent = openaiMessages(t, client.Requests()[0])[1]
+
+			assertEqual(t, strings.Contains(sent, "…(truncated)\n+short line\n"), tc.wantTruncated, "marker")
+			assertEqual(t, strings.Contains(sent, "+++ b/app.min.js\n@@ -1 +1 @@\n+var a=1;"), true, "headers")
+
+			for _, line := range strings.Split(sent, "\n") {
+				if line = strings.TrimSuffix(line, "…(truncated)"); len(line) > tc.wantMaxSize {
+					t.Fatalf("the line is too long: %d bytes", len(line))
+				}
+			}
+		})
+	}
+}
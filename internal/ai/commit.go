@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// CommitMode defines how the commit message is passed to `git commit`.
+type CommitMode uint8
+
+const (
+	// ModeSeparateMessages passes the subject and every body paragraph as separate `-m` arguments.
+	ModeSeparateMessages CommitMode = iota
+
+	// ModeSingleFile writes the whole message into a temporary file and passes it using the `-F` argument.
+	ModeSingleFile
+)
+
+// FormatForCommit converts the generated commit message into the `git commit` arguments (without the `commit`
+// itself). The returned cleanup function must be called once the command is finished (it removes the temporary
+// file in [ModeSingleFile] and does nothing otherwise).
+func FormatForCommit(resp *Response, mode CommitMode) ([]string, func() error, error) {
+	var noop = func() error { return nil }
+
+	if resp == nil || strings.TrimSpace(resp.Answer) == "" {
+		return nil, noop, errors.New("empty commit message")
+	}
+
+	var subject, body = splitMessage(resp.Answer)
+
+	switch mode {
+	case ModeSeparateMessages:
+		var args = make([]string, 0, 2+2*len(body)) //nolint:mnd // "-m <subject>" + "-m <paragraph>" pairs
+
+		args = append(args, "-m", subject)
+
+		for _, p := range body {
+			args = append(args, "-m", p)
+		}
+
+		return args, noop, nil
+
+	case ModeSingleFile:
+		f, err := os.CreateTemp("", "describe-commit-*.txt")
+		if err != nil {
+			return nil, noop, err
+		}
+
+		var cleanup = func() error { return os.Remove(f.Name()) }
+
+		if _, err = f.WriteString(strings.Join(append([]string{subject}, body...), "\n\n") + "\n"); err != nil {
+			_ = f.Close()
+			_ = cleanup()
+
+			return nil, noop, err
+		}
+
+		if err = f.Close(); err != nil {
+			_ = cleanup()
+
+			return nil, noop, err
+		}
+
+		return []string{"-F", f.Name()}, cleanup, nil
+	}
+
+	return nil, noop, errors.New("unknown commit mode")
+}
+
+// WriteToCommitMsgFile writes the generated commit message into the message file passed to the `prepare-commit-msg`
+// (or `commit-msg`) hook. The file is left untouched if it already contains a message (e.g. `git commit -m` or
+// `--amend` is used). The existing comment lines (starting with `#`, like the git's status summary) are preserved
+// below the message.
+func WriteToCommitMsgFile(path string, resp *Response) error {
+	if resp == nil || strings.TrimSpace(resp.Answer) == "" {
+		return errors.New("empty commit message")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var comments []string
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "#"):
+			comments = append(comments, line)
+		case strings.TrimSpace(line) != "":
+			return nil // the message is already provided
+		}
+	}
+
+	var b strings.Builder
+
+	b.WriteString(strings.TrimSpace(resp.Answer))
+	b.WriteRune('\n')
+
+	if len(comments) > 0 {
+		b.WriteRune('\n')
+		b.WriteString(strings.Join(comments, "\n"))
+		b.WriteRune('\n')
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0o644) //nolint:gosec,mnd // the same mode git uses
+}
+
+// splitMessage splits the commit message into the subject (the first line) and the body paragraphs.
+func splitMessage(message string) (string, []string) {
+	var subject, rest, _ = strings.Cut(strings.TrimLeft(strings.ReplaceAll(message, "\r\n", "\n"), "\n"), "\n")
+
+	return strings.TrimSpace(subject), splitParagraphs(rest)
+}
+
+// overflowBody trims the body of the commit message to the given number of lines. The trailers block (the last
+// paragraph consisting of the trailers only, e.g. `Signed-off-by: ...`) is always kept, and its lines are not
+// counted. The full body (without the trailers) is returned as the note if trimmed; otherwise, the message is
+// returned as is, and the note is empty.
+func overflowBody(message string, maxLines int) (trimmed, note string) {
+	var (
+		subject, paragraphs = splitMessage(message)
+		trailers            string
+	)
+
+	if n := len(paragraphs); n > 0 && isTrailers(strings.Split(paragraphs[n-1], "\n")) {
+		paragraphs, trailers = paragraphs[:n-1], paragraphs[n-1]
+	}
+
+	var (
+		body  = strings.Join(paragraphs, "\n\n")
+		lines = strings.Split(body, "\n")
+	)
+
+	if body == "" || len(lines) <= maxLines {
+		return message, ""
+	}
+
+	trimmed = subject + "\n\n" + strings.TrimSpace(strings.Join(lines[:maxLines], "\n"))
+
+	if trailers != "" {
+		trimmed += "\n\n" + trailers
+	}
+
+	return trimmed, body
+}
+
+// splitAnswer splits the commit message answer into the subject and the body (the paragraphs separated by the empty
+// line). The body is empty if only the short message is requested.
+func splitAnswer(answer string, o options) (subject, body string) {
+	subject, paragraphs := splitMessage(answer)
+
+	if o.ShortMessageOnly {
+		return subject, ""
+	}
+
+	return subject, strings.Join(paragraphs, "\n\n")
+}
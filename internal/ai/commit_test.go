@@ -0,0 +1,134 @@
+package ai_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestFormatForCommit_SeparateMessages(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		want       []string
+	}{
+		"subject only": {
+			giveAnswer: "feat: Add foo",
+			want:       []string{"-m", "feat: Add foo"},
+		},
+		"body without a blank line": {
+			giveAnswer: "fix: Bar\n- fix the bar",
+			want:       []string{"-m", "fix: Bar", "-m", "- fix the bar"},
+		},
+		"multi-paragraph body": {
+			giveAnswer: "feat(cli): Add baz\r\n\r\nThe baz is\nuseful.\n\n\n- one\n- two\n",
+			want:       []string{"-m", "feat(cli): Add baz", "-m", "The baz is\nuseful.", "-m", "- one\n- two"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			args, cleanup, err := ai.FormatForCommit(&ai.Response{Answer: tc.giveAnswer}, ai.ModeSeparateMessages)
+			assertNoError(t, err)
+			assertNoError(t, cleanup())
+
+			if !slices.Equal(args, tc.want) {
+				t.Errorf("want %q, got %q", tc.want, args)
+			}
+		})
+	}
+}
+
+func TestFormatForCommit_SingleFile(t *testing.T) {
+	t.Parallel()
+
+	args, cleanup, err := ai.FormatForCommit(
+		&ai.Response{Answer: "feat: Add foo\n\nFirst paragraph.\n\n\nSecond\nparagraph.\n"},
+		ai.ModeSingleFile,
+	)
+	assertNoError(t, err)
+
+	if len(args) != 2 || args[0] != "-F" {
+		t.Fatalf("unexpected args: %q", args)
+	}
+
+	content, err := os.ReadFile(args[1])
+	assertNoError(t, err)
+
+	assertEqual(t, string(content), "feat: Add foo\n\nFirst paragraph.\n\nSecond\nparagraph.\n")
+
+	assertNoError(t, cleanup())
+
+	if _, err = os.Stat(args[1]); !os.IsNotExist(err) {
+		t.Errorf("expected the file to be removed, got %v", err)
+	}
+}
+
+func TestFormatForCommit_Errors(t *testing.T) {
+	t.Parallel()
+
+	for name, resp := range map[string]*ai.Response{"nil": nil, "empty": {Answer: " \n"}} {
+		if _, _, err := ai.FormatForCommit(resp, ai.ModeSingleFile); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, _, err := ai.FormatForCommit(&ai.Response{Answer: "foo"}, ai.CommitMode(99)); err == nil {
+		t.Error("expected an error for the unknown mode")
+	}
+}
+
+func TestWriteToCommitMsgFile(t *testing.T) {
+	t.Parallel()
+
+	const answer = "feat: Add foo\n\nThe foo is useful.\n"
+
+	for name, tc := range map[string]struct {
+		giveContent *string // nil = no file
+		want        string
+	}{
+		"no file": {
+			want: "feat: Add foo\n\nThe foo is useful.\n",
+		},
+		"empty file": {
+			giveContent: ptr(""),
+			want:        "feat: Add foo\n\nThe foo is useful.\n",
+		},
+		"default template with comments": {
+			giveContent: ptr("\n# Please enter the commit message for your changes.\n#\n# On branch master\n"),
+			want: "feat: Add foo\n\nThe foo is useful.\n\n" +
+				"# Please enter the commit message for your changes.\n#\n# On branch master\n",
+		},
+		"pre-populated message": {
+			giveContent: ptr("fix: Keep me\n\n# On branch master\n"),
+			want:        "fix: Keep me\n\n# On branch master\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var path = filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+
+			if tc.giveContent != nil {
+				assertNoError(t, os.WriteFile(path, []byte(*tc.giveContent), 0o600))
+			}
+
+			assertNoError(t, ai.WriteToCommitMsgFile(path, &ai.Response{Answer: answer}))
+
+			got, err := os.ReadFile(path)
+			assertNoError(t, err)
+
+			assertEqual(t, string(got), tc.want)
+		})
+	}
+
+	if err := ai.WriteToCommitMsgFile(filepath.Join(t.TempDir(), "msg"), &ai.Response{}); err == nil {
+		t.Error("expected an error for the empty message")
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// errCommitlintNotFound is returned when the commitlint binary can't be found.
+var errCommitlintNotFound = errors.New("commitlint is not found")
+
+// lintCommitlint lints the message using the local commitlint. The project installation (in `node_modules/.bin`
+// of the repository) is preferred over the global one. The returned problem is nil if the message passes; only the
+// exit code 1 means the message is rejected, the other failures are returned as errors.
+func lintCommitlint(ctx context.Context, message string, o options) (problem, err error) {
+	var dir = cmp.Or(o.GitDir, ".")
+
+	bin, err := findCommitlint(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+
+	if o.CommitlintConfig != "" {
+		args = append(args, "--config", o.CommitlintConfig)
+	}
+
+	var (
+		cmd    = exec.CommandContext(ctx, bin, args...)
+		output bytes.Buffer
+	)
+
+	cmd.Dir, cmd.Stdin, cmd.Stdout, cmd.Stderr = dir, strings.NewReader(message), &output, &output
+
+	if err = cmd.Run(); err == nil {
+		return nil, nil
+	}
+
+	var exitErr *exec.ExitError
+
+	if !errors.As(err, &exitErr) || ctx.Err() != nil {
+		return nil, fmt.Errorf("failed to run commitlint: %w", err)
+	}
+
+	if exitErr.ExitCode() != 1 { // e.g. 9 for the missing config, not a problem of the message
+		return nil, fmt.Errorf("failed to run commitlint (exit code %d): %s",
+			exitErr.ExitCode(), commitlintProblems(output.String()),
+		)
+	}
+
+	return fmt.Errorf("commitlint: %s", commitlintProblems(output.String())), nil
+}
+
+// findCommitlint returns the path of the commitlint binary.
+func findCommitlint(dir string) (string, error) {
+	var local = filepath.Join(dir, "node_modules", ".bin", "commitlint")
+
+	if info, err := os.Stat(local); err == nil && !info.IsDir() {
+		return local, nil
+	}
+
+	if bin, err := exec.LookPath("commitlint"); err == nil {
+		return bin, nil
+	}
+
+	return "", errCommitlintNotFound
+}
+
+// commitlintProblems extracts the problems (the `✖ ...` lines, except the summary) from the commitlint output. The
+// whole output is returned if there are none.
+func commitlintProblems(output string) string {
+	var problems []string
+
+	for _, line := range strings.Split(output, "\n") {
+		if text, ok := strings.CutPrefix(strings.TrimSpace(line), "✖"); ok {
+			if text = strings.TrimSpace(text); !strings.HasPrefix(text, "found ") {
+				problems = append(problems, text)
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return strings.Join(strings.Fields(output), " ")
+	}
+
+	return strings.Join(problems, "; ")
+}
+
+// checkCommitlint lints the message with commitlint (see [WithCommitlintValidation]). If it fails, the message is
+// fixed once using an additional request with the linter errors; the remaining problems are reported as warnings.
+// The missing commitlint is reported as a warning as well.
+func checkCommitlint(ctx context.Context, c completer, instructions, changes string, r *Response, o options) error {
+	problem, err := lintCommitlint(ctx, r.Answer, o)
+	if err != nil {
+		if errors.Is(err, errCommitlintNotFound) {
+			r.Warnings = append(r.Warnings, "the message is not linted: "+err.Error())
+
+			return nil
+		}
+
+		return err
+	}
+
+	if problem == nil {
+		return nil
+	}
+
+	if err = fixAnswer(ctx, c, instructions, changes, r, problem, o); err != nil {
+		return fmt.Errorf("failed to fix the message rejected by commitlint: %w", err)
+	}
+
+	if problem, err = lintCommitlint(ctx, r.Answer, o); err != nil {
+		return err
+	}
+
+	if problem != nil {
+		r.Warnings = append(r.Warnings, problem.Error())
+	}
+
+	return nil
+}
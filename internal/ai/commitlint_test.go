@@ -0,0 +1,197 @@
+package ai_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+// fakeCommitlint installs the fake commitlint into the `node_modules` of a new directory, and returns the directory.
+// The fake accepts the `fix` commits only, and records its arguments into the `args` file.
+func fakeCommitlint(t *testing.T) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake commitlint is a shell script")
+	}
+
+	const script = `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+input="$(cat)"
+case "$*" in
+  *missing.yml*) echo "✖   Please add rules to your commitlint.config.js"; exit 9 ;;
+esac
+case "$input" in
+  fix:*) exit 0 ;;
+esac
+echo "⧗   input: feat: Add foo"
+echo "✖   type must be one of [fix] [type-enum]"
+echo
+echo "✖   found 1 problems, 0 warnings"
+exit 1
+`
+
+	var (
+		dir = t.TempDir()
+		bin = filepath.Join(dir, "node_modules", ".bin")
+	)
+
+	assertNoError(t, os.MkdirAll(bin, 0o755))
+	assertNoError(t, os.WriteFile(filepath.Join(bin, "commitlint"), []byte(script), 0o755)) //nolint:gosec
+
+	return dir
+}
+
+func TestWithCommitlintValidation(t *testing.T) {
+	t.Parallel()
+
+	var answersClient = func(answers ...string) *fakeHttpClient {
+		return &fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK,
+				`{"choices":[{"message":{"content":"`+answers[min(n, len(answers)-1)]+`"}}]}`,
+			), nil
+		}}
+	}
+
+	t.Run("passes", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			dir    = fakeCommitlint(t)
+			client = answersClient("fix: Fix foo")
+		)
+
+		resp, err := ai.NewOpenAI("key", "gpt-4o-mini", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithGitDir(dir), ai.WithCommitlintValidation("cl.yml"))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "fix: Fix foo")
+		assertEqual(t, resp.Fixed, false)
+		assertEqual(t, len(resp.Warnings), 0)
+		assertEqual(t, len(client.Requests()), 1)
+
+		args, _ := os.ReadFile(filepath.Join(dir, "node_modules", ".bin", "args"))
+
+		assertEqual(t, strings.TrimSpace(string(args)), "--config cl.yml")
+	})
+
+	t.Run("fixed", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			dir    = fakeCommitlint(t)
+			client = answersClient("feat: Add foo", "fix: Fix foo")
+		)
+
+		resp, err := ai.NewOpenAI("key", "gpt-4o-mini", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithGitDir(dir), ai.WithCommitlintValidation(""))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "fix: Fix foo")
+		assertEqual(t, resp.Fixed, true)
+		assertEqual(t, len(resp.Warnings), 0)
+
+		var requests = client.Requests()
+
+		assertEqual(t, len(requests), 2)
+
+		var fix = openaiMessages(t, requests[1])[0]
+
+		if !strings.Contains(fix, "commitlint: type must be one of [fix] [type-enum])") ||
+			!strings.Contains(fix, "feat: Add foo") || strings.Contains(fix, "found 1 problems") {
+			t.Errorf("the linter errors are not passed to the model:\n%s", fix)
+		}
+
+		args, _ := os.ReadFile(filepath.Join(dir, "node_modules", ".bin", "args"))
+
+		assertEqual(t, strings.TrimSpace(string(args)), "") // no config
+	})
+
+	t.Run("fixed with confidence", func(t *testing.T) {
+		t.Parallel()
+
+		var client = answersClient("feat: Add foo", `{\"message\":\"fix: Fix foo\",\"confidence\":0.9}`)
+
+		resp, err := ai.NewOpenAI("key", "gpt-4o-mini", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log",
+				ai.WithGitDir(fakeCommitlint(t)), ai.WithCommitlintValidation(""), ai.WithConfidence(true),
+			)
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "fix: Fix foo")
+		assertEqual(t, resp.Fixed, true)
+		assertEqual(t, resp.Confidence, 0.9)
+		assertEqual(t, len(resp.Warnings), 1, "the earlier warnings are kept")
+		assertEqual(t, resp.Warnings[0], "the model did not report the confidence")
+	})
+
+	t.Run("stale warnings dropped", func(t *testing.T) {
+		t.Parallel()
+
+		var client = answersClient("chore: Update foo", "fix: Fix foo")
+
+		resp, err := ai.NewOpenAI("key", "gpt-4o-mini", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff --git a/foo.go b/foo.go\n+foo", "log",
+				ai.WithGitDir(fakeCommitlint(t)), ai.WithCommitlintValidation(""), ai.WithDiscourageChore(true),
+			)
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "fix: Fix foo")
+		assertEqual(t, len(resp.Warnings), 0, "the chore warning is about the replaced message")
+	})
+
+	t.Run("still invalid", func(t *testing.T) {
+		t.Parallel()
+
+		var client = answersClient("feat: Add foo")
+
+		resp, err := ai.NewOpenAI("key", "gpt-4o-mini", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log",
+				ai.WithGitDir(fakeCommitlint(t)), ai.WithCommitlintValidation(""),
+			)
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+		assertEqual(t, len(client.Requests()), 2) // fixed only once
+		assertEqual(t, len(resp.Warnings), 1)
+		assertEqual(t, resp.Warnings[0], "commitlint: type must be one of [fix] [type-enum]")
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+
+		var client = answersClient("feat: Add foo")
+
+		_, err := ai.NewOpenAI("key", "gpt-4o-mini", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log",
+				ai.WithGitDir(fakeCommitlint(t)), ai.WithCommitlintValidation("missing.yml"),
+			)
+		if err == nil || !strings.Contains(err.Error(), "exit code 9") ||
+			!strings.Contains(err.Error(), "Please add rules to your commitlint.config.js") {
+			t.Fatalf("expected the commitlint failure, got %v", err)
+		}
+
+		assertEqual(t, len(client.Requests()), 1, "the message is not fixed")
+	})
+
+	t.Run("not installed", func(t *testing.T) {
+		t.Parallel()
+
+		var client = answersClient("feat: Add foo")
+
+		resp, err := ai.NewOpenAI("key", "gpt-4o-mini", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithGitDir(t.TempDir()), ai.WithCommitlintValidation(""))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+		assertEqual(t, len(client.Requests()), 1)
+		assertEqual(t, len(resp.Warnings), 1)
+		assertEqual(t, resp.Warnings[0], "the message is not linted: commitlint is not found")
+	})
+}
@@ -0,0 +1,40 @@
+package ai
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// assessment is the model's self-assessment of the answer (see [WithConfidence]).
+type assessment struct {
+	Confidence float64
+	Rationale  string
+}
+
+// parseJSONAnswer extracts the message and the assessment from the JSON object answer. The object may be wrapped
+// into a code block, despite being asked not to. Returns false if the answer is not a valid object.
+func parseJSONAnswer(answer string) (string, assessment, bool) {
+	var s = strings.TrimSpace(answer)
+
+	if start, end := strings.IndexByte(s, '{'), strings.LastIndexByte(s, '}'); start >= 0 && end > start {
+		s = s[start : end+1]
+	}
+
+	var obj struct {
+		Message    string   `json:"message"`
+		Confidence *float64 `json:"confidence"`
+		Rationale  string   `json:"rationale"`
+	}
+
+	if err := json.Unmarshal([]byte(s), &obj); err != nil || strings.TrimSpace(obj.Message) == "" {
+		return answer, assessment{}, false
+	}
+
+	var a = assessment{Rationale: strings.TrimSpace(obj.Rationale)}
+
+	if obj.Confidence != nil {
+		a.Confidence = min(max(*obj.Confidence, 0), 1)
+	}
+
+	return strings.Trim(obj.Message, "\n\t "), a, true
+}
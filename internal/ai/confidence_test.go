@@ -0,0 +1,89 @@
+package ai_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_Confidence(t *testing.T) {
+	t.Parallel()
+
+	t.Run("json answer", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient("```json\n" +
+			`{"message":"feat: Add foo","confidence":0.35,"rationale":"The diff is ambiguous"}` + "\n```")
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithConfidence(true))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+		assertEqual(t, resp.Confidence, 0.35)
+		assertEqual(t, resp.Rationale, "The diff is ambiguous")
+		assertEqual(t, len(resp.Warnings), 0)
+
+		var request = client.Requests()[0]
+
+		assertEqual(t, strings.Contains(request, `"response_format":{"type":"json_object"}`), true, "response format")
+		assertEqual(t, strings.Contains(openaiMessages(t, request)[0], "`confidence`"), true, "prompt")
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient(`{"message":"feat: Add foo","confidence":42}`)
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithConfidence(true))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Confidence, 1.0)
+	})
+
+	t.Run("not a json answer", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(okClient("feat: Add foo"))).
+			Query(context.Background(), "diff", "log", ai.WithConfidence(true))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+		assertEqual(t, resp.Confidence, 0.0)
+		assertEqual(t, len(resp.Warnings), 1)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient("feat: Add foo")
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log")
+		assertNoError(t, err)
+
+		assertEqual(t, len(resp.Warnings), 0)
+		assertEqual(t, strings.Contains(client.Requests()[0], `response_format`), false)
+	})
+
+	t.Run("gemini", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK, `{"candidates":[{"content":{"parts":[`+
+				`{"text":"{\"message\":\"feat: Add foo\",\"confidence\":0.9}"}]}}]}`), nil
+		}}
+
+		resp, err := ai.NewGemini("key", "model", ai.WithGeminiHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithConfidence(true))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+		assertEqual(t, resp.Confidence, 0.9)
+		assertEqual(t, strings.Contains(client.Requests()[0], `"responseMimeType":"application/json"`), true)
+	})
+}
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Header is the parsed first line (subject) of a conventional commit message.
+type Header struct {
+	Emoji       string // optional GitMoji prefix
+	Type        string // e.g. "feat", "fix"
+	Scope       string // optional
+	Breaking    bool   // the "!" mark after the type/scope
+	Description string
+}
+
+// headerRegex matches the `[<emoji> ]<type>[(<scope>)][!]: <description>` format.
+var headerRegex = regexp.MustCompile(`^(?:([^\sA-Za-z0-9]+)\s+)?([A-Za-z]+)(?:\(([^()]*)\))?(!)?:\s*(.+)$`)
+
+// ParseHeader parses the conventional commit header. False is returned if the line does not follow the format.
+func ParseHeader(line string) (Header, bool) {
+	var m = headerRegex.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Header{}, false
+	}
+
+	return Header{
+		Emoji:       m[1],
+		Type:        strings.ToLower(m[2]),
+		Scope:       strings.TrimSpace(m[3]),
+		Breaking:    m[4] != "",
+		Description: strings.TrimSpace(m[5]),
+	}, true
+}
+
+// String formats the header back to the `[<emoji> ]<type>[(<scope>)][!]: <description>` format.
+func (h Header) String() string {
+	var b strings.Builder
+
+	if h.Emoji != "" {
+		b.WriteString(h.Emoji)
+		b.WriteRune(' ')
+	}
+
+	b.WriteString(h.Type)
+
+	if h.Scope != "" {
+		b.WriteRune('(')
+		b.WriteString(h.Scope)
+		b.WriteRune(')')
+	}
+
+	if h.Breaking {
+		b.WriteRune('!')
+	}
+
+	b.WriteString(": ")
+	b.WriteString(h.Description)
+
+	return b.String()
+}
+
+// Header parses the conventional commit header of the answer.
+func (r *Response) Header() (Header, bool) {
+	subject, _, _ := strings.Cut(r.Answer, "\n")
+
+	return ParseHeader(subject)
+}
+
+// Semantic versioning impact of the commit.
+const (
+	SemverNone  = "none"
+	SemverPatch = "patch"
+	SemverMinor = "minor"
+	SemverMajor = "major"
+)
+
+// SemverImpact derives the semantic versioning bump from the commit type and the breaking change mark (the "!" in
+// the header, or the "BREAKING CHANGE" footer): breaking changes are major, features are minor, and fixes (and
+// performance improvements) are patches. [SemverNone] is returned for everything else, including non-conventional
+// messages.
+func (r *Response) SemverImpact() string {
+	header, ok := r.Header()
+	if !ok {
+		return SemverNone
+	}
+
+	if header.Breaking ||
+		strings.Contains(r.Answer, "\nBREAKING CHANGE:") ||
+		strings.Contains(r.Answer, "\nBREAKING-CHANGE:") {
+		return SemverMajor
+	}
+
+	switch header.Type {
+	case "feat":
+		return SemverMinor
+	case "fix", "perf":
+		return SemverPatch
+	}
+
+	return SemverNone
+}
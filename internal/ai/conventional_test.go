@@ -0,0 +1,70 @@
+package ai_test
+
+import (
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestParseHeader(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		give     string
+		want     ai.Header
+		wantOk   bool
+		wantBack string
+	}{
+		"type only": {
+			give:     "fix: Handle nil pointer",
+			want:     ai.Header{Type: "fix", Description: "Handle nil pointer"},
+			wantOk:   true,
+			wantBack: "fix: Handle nil pointer",
+		},
+		"full": {
+			give:     "✨ Feat(api)!: Drop the v1 endpoints",
+			want:     ai.Header{Emoji: "✨", Type: "feat", Scope: "api", Breaking: true, Description: "Drop the v1 endpoints"},
+			wantOk:   true,
+			wantBack: "✨ feat(api)!: Drop the v1 endpoints",
+		},
+		"not conventional": {give: "Update the README"},
+		"empty":            {give: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := ai.ParseHeader(tc.give)
+
+			assertEqual(t, ok, tc.wantOk)
+			assertEqual(t, got, tc.want)
+
+			if ok {
+				assertEqual(t, got.String(), tc.wantBack)
+			}
+		})
+	}
+}
+
+func TestResponse_SemverImpact(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]string{
+		"feat: Add the cache":                                   ai.SemverMinor,
+		"feat(api): Add the cache\n\nDetails":                   ai.SemverMinor,
+		"fix: Handle nil pointer":                               ai.SemverPatch,
+		"perf(db): Reuse prepared statements":                   ai.SemverPatch,
+		"feat!: Drop the v1 endpoints":                          ai.SemverMajor,
+		"fix(api)!: Reject empty tokens":                        ai.SemverMajor,
+		"refactor: Extract helpers\n\nBREAKING CHANGE: renamed": ai.SemverMajor,
+		"docs: Fix typos":                                       ai.SemverNone,
+		"chore: Bump deps":                                      ai.SemverNone,
+		"🐛 fix: Handle nil pointer":                             ai.SemverPatch,
+		"Update the README":                                     ai.SemverNone,
+	} {
+		t.Run(give, func(t *testing.T) {
+			t.Parallel()
+
+			assertEqual(t, (&ai.Response{Answer: give}).SemverImpact(), want)
+		})
+	}
+}
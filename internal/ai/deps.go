@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// lockFileNames are the base names of the dependency lock files.
+var lockFileNames = [...]string{ //nolint:gochecknoglobals
+	"go.sum", "package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "Cargo.lock",
+	"poetry.lock", "Pipfile.lock", "uv.lock",
+}
+
+// manifestVersionRegexes match the dependency declaration lines (the name and the version) of the supported
+// dependency manifests, keyed by the manifest base name.
+var manifestVersionRegexes = map[string][]*regexp.Regexp{ //nolint:gochecknoglobals
+	"go.mod": {
+		regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+)\s+(v\d\S*)(?:\s*//.*)?$`),
+	},
+	"package.json": {
+		regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*"([~^>=<\s]*\d[^"]*)"\s*,?\s*$`),
+	},
+	"requirements.txt": {
+		regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9_.\-\[\],]*)\s*((?:==|>=|<=|~=|!=|>|<)\s*[^\s;#]+)`),
+	},
+	"Cargo.toml": {
+		regexp.MustCompile(`^\s*([A-Za-z0-9_\-]+)\s*=\s*"([~^=<>]*\d[^"]*)"`),
+		regexp.MustCompile(`^\s*([A-Za-z0-9_\-]+)\s*=\s*\{.*\bversion\s*=\s*"([^"]+)"`),
+	},
+}
+
+// versionBump is the change of a single dependency.
+type versionBump struct {
+	Manifest, Name string
+	From, To       string // empty for the added (From) and removed (To) dependencies
+}
+
+// String formats the change as "<name> (<manifest>): <from> -> <to>".
+func (b versionBump) String() string {
+	switch {
+	case b.From == "":
+		return fmt.Sprintf("%s (%s): added %s", b.Name, b.Manifest, b.To)
+	case b.To == "":
+		return fmt.Sprintf("%s (%s): removed %s", b.Name, b.Manifest, b.From)
+	}
+
+	return fmt.Sprintf("%s (%s): %s -> %s", b.Name, b.Manifest, b.From, b.To)
+}
+
+// dependencyUpdate returns the dependency changes if the changes are confined to the dependency manifests and lock
+// files (nil otherwise, or if no versions were changed).
+func dependencyUpdate(changes string) []versionBump {
+	var (
+		files = git.SplitPatch(changes)
+		bumps []versionBump
+	)
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	for _, f := range files {
+		var base = path.Base(f.Path)
+
+		if slices.Contains(lockFileNames[:], base) {
+			continue
+		}
+
+		regexes, ok := manifestVersionRegexes[base]
+		if !ok {
+			return nil // not a dependency-only change
+		}
+
+		bumps = append(bumps, manifestBumps(base, f.Text, regexes)...)
+	}
+
+	return bumps
+}
+
+// manifestBumps parses the removed and added dependency declarations of the manifest diff and pairs them by name.
+func manifestBumps(manifest, diff string, regexes []*regexp.Regexp) []versionBump {
+	var (
+		bumps = make([]versionBump, 0)
+		index = make(map[string]int) // name to the index in bumps
+	)
+
+	for _, line := range strings.Split(diff, "\n") {
+		if len(line) == 0 || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") ||
+			(line[0] != '-' && line[0] != '+') {
+			continue
+		}
+
+		for _, re := range regexes {
+			var m = re.FindStringSubmatch(line[1:])
+			if m == nil || m[1] == "version" { // the version of the package itself (package.json, Cargo.toml)
+				continue
+			}
+
+			var name, version = m[1], strings.TrimSpace(m[2])
+
+			i, ok := index[name]
+			if !ok {
+				i, index[name] = len(bumps), len(bumps)
+				bumps = append(bumps, versionBump{Manifest: manifest, Name: name})
+			}
+
+			if line[0] == '-' {
+				bumps[i].From = version
+			} else {
+				bumps[i].To = version
+			}
+
+			break
+		}
+	}
+
+	return slices.DeleteFunc(bumps, func(b versionBump) bool { return b.From == b.To }) // e.g. reordered lines
+}
+
+// dependencyUpdateHint returns the instructions for the dependency-only changes.
+func dependencyUpdateHint(bumps []versionBump, o options) string {
+	var b strings.Builder
+
+	b.WriteString("The changes only update the dependencies (the manifests and the lock files). Use the ")
+	b.WriteString("`build(deps)` type (or `chore(deps)`, if the recent commits use it) and summarize the update in ")
+	b.WriteString("the subject, e.g. `build(deps): Bump <package> from <old> to <new>` for a single package, or ")
+	b.WriteString("`build(deps): Bump <n> dependencies` for several ones.")
+
+	if !o.ShortMessageOnly {
+		b.WriteString(" List every changed package with its versions in the body.")
+	}
+
+	b.WriteString(" The changed dependencies:\n")
+
+	for _, bump := range bumps {
+		b.WriteString("- ")
+		b.WriteString(bump.String())
+		b.WriteRune('\n')
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
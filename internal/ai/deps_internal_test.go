@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDependencyUpdate(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		give string
+		want []string
+	}{
+		"package.json": {
+			give: "diff --git a/web/package.json b/web/package.json\n--- a/web/package.json\n+++ b/web/package.json\n" +
+				"@@ -1,8 +1,8 @@\n" +
+				"-  \"version\": \"1.0.0\",\n" +
+				"+  \"version\": \"1.0.1\",\n" +
+				"   \"dependencies\": {\n" +
+				"-    \"lodash\": \"^4.17.20\",\n" +
+				"+    \"lodash\": \"^4.17.21\",\n" +
+				"-    \"left-pad\": \"1.3.0\"\n" +
+				"+    \"react\": \"~18.3.1\"\n" +
+				"diff --git a/web/yarn.lock b/web/yarn.lock\n--- a/web/yarn.lock\n+++ b/web/yarn.lock\n",
+			want: []string{
+				"lodash (package.json): ^4.17.20 -> ^4.17.21",
+				"left-pad (package.json): removed 1.3.0",
+				"react (package.json): added ~18.3.1",
+			},
+		},
+		"requirements.txt": {
+			give: "diff --git a/requirements.txt b/requirements.txt\n--- a/requirements.txt\n+++ b/requirements.txt\n" +
+				"@@ -1,2 +1,2 @@\n-requests==2.31.0\n+requests==2.32.3\n Django>=4.2\n",
+			want: []string{"requests (requirements.txt): ==2.31.0 -> ==2.32.3"},
+		},
+		"Cargo.toml": {
+			give: "diff --git a/Cargo.toml b/Cargo.toml\n--- a/Cargo.toml\n+++ b/Cargo.toml\n" +
+				"@@ -1,3 +1,3 @@\n" +
+				"-serde = \"1.0.200\"\n" +
+				"+serde = \"1.0.210\"\n" +
+				"-tokio = { version = \"1.38\", features = [\"full\"] }\n" +
+				"+tokio = { version = \"1.40\", features = [\"full\"] }\n",
+			want: []string{"serde (Cargo.toml): 1.0.200 -> 1.0.210", "tokio (Cargo.toml): 1.38 -> 1.40"},
+		},
+		"not a manifest": {
+			give: "diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		"empty": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+
+			for _, bump := range dependencyUpdate(tc.give) {
+				got = append(got, bump.String())
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
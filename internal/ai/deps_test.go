@@ -0,0 +1,86 @@
+package ai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_DepsAware(t *testing.T) {
+	t.Parallel()
+
+	const (
+		goModDiff = "diff --git a/go.mod b/go.mod\n" +
+			"index 1111111..2222222 100644\n" +
+			"--- a/go.mod\n" +
+			"+++ b/go.mod\n" +
+			"@@ -3,9 +3,9 @@ module example.com/app\n" +
+			" go 1.24\n" +
+			" \n" +
+			" require (\n" +
+			"-\tgithub.com/urfave/cli/v3 v3.0.0-beta1\n" +
+			"+\tgithub.com/urfave/cli/v3 v3.1.0\n" +
+			" \tgolang.org/x/sync v0.10.0\n" +
+			"-\tgolang.org/x/text v0.21.0 // indirect\n" +
+			"+\tgolang.org/x/text v0.22.0 // indirect\n" +
+			"+\tgithub.com/google/uuid v1.6.0\n" +
+			" )\n"
+		goSumDiff = "diff --git a/go.sum b/go.sum\n--- a/go.sum\n+++ b/go.sum\n@@ -1 +1 @@\n-a h1:x\n+a h1:y\n"
+		codeDiff  = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n"
+	)
+
+	for name, tc := range map[string]struct {
+		giveDiff string
+		giveOpts []ai.Option
+		wantHint bool
+	}{
+		"go.mod bump": {
+			giveDiff: goModDiff + goSumDiff,
+			giveOpts: []ai.Option{ai.WithDepsAware(true)},
+			wantHint: true,
+		},
+		"with code changes": {
+			giveDiff: goModDiff + codeDiff,
+			giveOpts: []ai.Option{ai.WithDepsAware(true)},
+		},
+		"lock file only": {
+			giveDiff: goSumDiff,
+			giveOpts: []ai.Option{ai.WithDepsAware(true)},
+		},
+		"disabled": {
+			giveDiff: goModDiff,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("build(deps): Bump dependencies")
+
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), tc.giveDiff, "", tc.giveOpts...)
+			assertNoError(t, err)
+
+			var prompt = openaiMessages(t, client.Requests()[0])[0]
+
+			assertEqual(t, strings.Contains(prompt, "`build(deps)`"), tc.wantHint, "hint")
+
+			if !tc.wantHint {
+				return
+			}
+
+			for _, want := range []string{
+				"- github.com/urfave/cli/v3 (go.mod): v3.0.0-beta1 -> v3.1.0\n",
+				"- golang.org/x/text (go.mod): v0.21.0 -> v0.22.0\n",
+				"- github.com/google/uuid (go.mod): added v1.6.0\n",
+			} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("expected %q to contain %q", prompt, want)
+				}
+			}
+
+			assertEqual(t, strings.Contains(prompt, "golang.org/x/sync"), false, "unchanged dependency")
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBadRequest is wrapped by the [APIError] when the provider rejects the request (e.g. unknown model or too
+	// long input).
+	ErrBadRequest = errors.New("bad request")
+
+	// ErrUnauthorized is wrapped by the [APIError] when the API key is missing, invalid, or has no access.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrRateLimited is wrapped by the [APIError] when the rate limit or the quota is exceeded.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrProviderUnavailable is wrapped by the [APIError] when the provider fails on its side (5xx).
+	ErrProviderUnavailable = errors.New("provider unavailable")
+
+	// ErrContextTooLong is wrapped by the [APIError] when the request exceeds the context window of the model.
+	ErrContextTooLong = errors.New("context too long")
+
+	// ErrMalformedResponse is returned when the provider responds with the successful status code, but the body
+	// can't be decoded (e.g. truncated by a flaky gateway).
+	ErrMalformedResponse = errors.New("malformed response")
+
+	// ErrCostLimitExceeded is returned when even a single candidate exceeds the cost limit (see
+	// [WithMaxCandidateCost]).
+	ErrCostLimitExceeded = errors.New("cost limit exceeded")
+
+	// ErrBudgetExceeded is returned when the query does not fit into the time budget (see [WithTotalBudget]).
+	ErrBudgetExceeded = errors.New("total time budget exceeded")
+
+	// ErrTimeout is wrapped by the [TimeoutError] when the request to the provider API times out on the client side.
+	ErrTimeout = errors.New("request timed out")
+
+	// ErrInvalidMessage is returned by [Response.Validate] when the message does not follow the convention.
+	ErrInvalidMessage = errors.New("invalid commit message")
+)
+
+// APIError is returned when the provider API responds with an unexpected status code. Use [errors.Is] with the
+// sentinel errors (e.g. [ErrRateLimited]) to check the kind of the error.
+type APIError struct {
+	Provider   string // the provider name (e.g. "OpenAI")
+	StatusCode int    // the HTTP status code
+	Code       string // provider-specific error code (optional)
+	Message    string // error message from the response body (optional)
+	InStream   bool   // the error was sent in the middle of the stream (the status code is derived from the code)
+
+	// the delay before the next attempt requested by the provider (the `Retry-After` header), if any
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.InStream {
+		return fmt.Sprintf("%s API stream error: %s (code: %s)", e.Provider, e.Message, e.Code)
+	}
+
+	if e.Message != "" {
+		return fmt.Sprintf("%s API error: %s (status code: %d)", e.Provider, e.Message, e.StatusCode)
+	}
+
+	return fmt.Sprintf(
+		"unexpected %s API response status code: %d (%s)",
+		e.Provider, e.StatusCode, http.StatusText(e.StatusCode),
+	)
+}
+
+// Unwrap returns the sentinel errors matching the status code and the error details.
+func (e *APIError) Unwrap() []error {
+	var errs = make([]error, 0, 2) //nolint:mnd
+
+	switch {
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden:
+		errs = append(errs, ErrUnauthorized)
+	case e.StatusCode == http.StatusTooManyRequests:
+		errs = append(errs, ErrRateLimited)
+	case e.StatusCode >= http.StatusInternalServerError:
+		errs = append(errs, ErrProviderUnavailable)
+	case e.StatusCode >= http.StatusBadRequest:
+		errs = append(errs, ErrBadRequest)
+	}
+
+	if e.isContextTooLong() {
+		errs = append(errs, ErrContextTooLong)
+	}
+
+	return errs
+}
+
+// TimeoutError is returned when the request to the provider API exceeds the HTTP client timeout. The cancellation
+// (or the deadline) of the query context is not a timeout and is returned as is.
+type TimeoutError struct {
+	Provider string        // the provider name (e.g. "OpenAI")
+	Timeout  time.Duration // the HTTP client timeout (zero, if unknown - e.g. for the custom clients)
+	Err      error         // the original error
+}
+
+// Error implements the error interface.
+func (e *TimeoutError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("%s request timed out after %s", e.Provider, e.Timeout)
+	}
+
+	return e.Provider + " request timed out"
+}
+
+// Unwrap returns the [ErrTimeout] and the original error.
+func (e *TimeoutError) Unwrap() []error { return []error{ErrTimeout, e.Err} }
+
+// doRequest sends the request using the client. The client timeout errors are wrapped into the [TimeoutError], while
+// the errors caused by the request context (canceled or past the deadline) are returned as is.
+func doRequest(provider string, client httpClient, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err == nil {
+		return resp, nil
+	}
+
+	if req.Context().Err() != nil || !isTimeout(err) {
+		return nil, err
+	}
+
+	var timeoutErr = TimeoutError{Provider: provider, Err: err}
+
+	if c, ok := client.(*http.Client); ok {
+		timeoutErr.Timeout = c.Timeout
+	}
+
+	return nil, &timeoutErr
+}
+
+// isTimeout reports whether the error is a timeout one.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// contextTooLongMarkers are the (lowercase) error codes and message parts the providers use to report that the
+// request exceeds the context window.
+var contextTooLongMarkers = [...]string{ //nolint:gochecknoglobals
+	"context_length_exceeded", "maximum context length", "context window", "context length",
+	"exceeds the maximum number of tokens", "too many tokens", "prompt is too long", "input is too long",
+}
+
+// isContextTooLong reports whether the error means that the request exceeds the context window.
+func (e *APIError) isContextTooLong() bool {
+	if e.StatusCode != http.StatusBadRequest && e.StatusCode != http.StatusRequestEntityTooLarge {
+		return false
+	}
+
+	var s = strings.ToLower(e.Code + " " + e.Message)
+
+	for _, marker := range contextTooLongMarkers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apiErrorDetails is the error object of the `{"error": {"message": "...", "code": ...}}` body, used by the most
+// providers. The code may be either a string or a number.
+type apiErrorDetails struct {
+	Message string          `json:"message"`
+	Code    json.RawMessage `json:"code"`
+}
+
+// code returns the error code as a string (empty if not set).
+func (d *apiErrorDetails) code() string {
+	if code := strings.Trim(string(d.Code), `"`); code != "null" {
+		return code
+	}
+
+	return ""
+}
+
+// newAPIError creates the [APIError] from the response. The error body (see [apiErrorDetails]) is decoded if present.
+func newAPIError(provider string, resp *http.Response) *APIError {
+	var (
+		apiErr = APIError{
+			Provider:   provider,
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+		body struct {
+			Error apiErrorDetails `json:"error"`
+		}
+	)
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		apiErr.Message, apiErr.Code = body.Error.Message, body.Error.code()
+	}
+
+	return &apiErr
+}
+
+// newStreamError creates the [APIError] from the error object sent in the middle of the stream. The numeric code
+// is used as the status code, if it looks like the HTTP one; otherwise, the error is considered to be on the
+// provider side.
+func newStreamError(provider string, details *apiErrorDetails) *APIError {
+	var apiErr = APIError{
+		Provider:   provider,
+		StatusCode: http.StatusInternalServerError,
+		Code:       details.code(),
+		Message:    details.Message,
+		InStream:   true,
+	}
+
+	if code, err := strconv.Atoi(apiErr.Code); err == nil && code >= http.StatusBadRequest && http.StatusText(code) != "" {
+		apiErr.StatusCode = code
+	}
+
+	return &apiErr
+}
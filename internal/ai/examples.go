@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// maxContributorExamples limits the number of the top contributor's commits used as the style examples.
+const maxContributorExamples = 15
+
+// withCommitExamples reformats the recent commits (newline-separated subjects) into the style examples, if enabled
+// (see [WithCommitsAsExamples]). The examples are added to the options, and the raw commits are dropped. If none of
+// the commits follows the Conventional Commits format, the commits are returned unchanged.
+func withCommitExamples(commits string, opts []Option) (string, []Option) {
+	if !(options{}).Apply(opts...).CommitExamples {
+		return commits, opts
+	}
+
+	var examples = commitExamples(commits)
+	if examples == "" {
+		return commits, opts
+	}
+
+	return "", append(opts[:len(opts):len(opts)], withContext(
+		"Commit message examples from the repository history (follow their style, the types, and the scopes)",
+		examples,
+	))
+}
+
+// commitExamples formats every conventional commit subject as a style example, pairing it with its type and scope.
+// Malformed (non-conventional) lines are skipped.
+func commitExamples(commits string) string {
+	var b strings.Builder
+
+	for _, line := range strings.Split(commits, "\n") {
+		var subject = strings.TrimSpace(line)
+
+		header, ok := ParseHeader(subject)
+		if !ok {
+			continue
+		}
+
+		if header.Scope != "" {
+			_, _ = fmt.Fprintf(&b, "- type `%s`, scope `%s`: %s\n", header.Type, header.Scope, subject)
+		} else {
+			_, _ = fmt.Fprintf(&b, "- type `%s`, no scope: %s\n", header.Type, subject)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// topContributorExamples returns the name of the top contributor of the repository and their recent commit
+// subjects formatted as a list (see [WithMimicTopContributor]). Empty strings are returned for the repository
+// without commits.
+func topContributorExamples(ctx context.Context, dirPath string) (author, examples string, _ error) {
+	author, err := git.TopContributor(ctx, dirPath)
+	if err != nil || author == "" {
+		return "", "", err
+	}
+
+	examples, err = authorExamples(ctx, dirPath, author)
+	if err != nil {
+		return "", "", err
+	}
+
+	return author, examples, nil
+}
+
+// authorExamples returns the recent commit subjects of the author formatted as a list. An empty string is returned
+// if the author has no commits in the repository.
+func authorExamples(ctx context.Context, dirPath, author string) (string, error) {
+	log, err := git.AuthorLog(ctx, dirPath, author, maxContributorExamples)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	for _, line := range strings.Split(log, "\n") {
+		if subject := strings.TrimSpace(line); subject != "" {
+			_, _ = fmt.Fprintf(&b, "- %s\n", subject)
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
@@ -0,0 +1,182 @@
+package ai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_CommitsAsExamples(t *testing.T) {
+	t.Parallel()
+
+	const commits = "feat(api): Add rate-limiting\n" +
+		"Merge branch 'main' into dev\n" +
+		"\n" +
+		"  fix: Handle the empty input  \n" +
+		"WIP\n" +
+		"✨ feat(ui)!: Redesign the settings page"
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient("feat: Add foo")
+
+		_, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", commits, ai.WithCommitsAsExamples(true))
+		assertNoError(t, err)
+
+		var messages = openaiMessages(t, client.Requests()[0])
+
+		assertEqual(t, strings.Contains(messages[0], "- type `feat`, scope `api`: feat(api): Add rate-limiting\n"+
+			"- type `fix`, no scope: fix: Handle the empty input\n"+
+			"- type `feat`, scope `ui`: ✨ feat(ui)!: Redesign the settings page\n",
+		), true, "examples")
+		assertEqual(t, strings.Contains(messages[0], "Merge branch"), false, "malformed line")
+		assertEqual(t, strings.Contains(messages[2], "Merge branch"), false, "raw commits")
+	})
+
+	t.Run("no conventional commits", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient("feat: Add foo")
+
+		_, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "Initial commit\nWIP", ai.WithCommitsAsExamples(true))
+		assertNoError(t, err)
+
+		var messages = openaiMessages(t, client.Requests()[0])
+
+		assertEqual(t, strings.Contains(messages[0], "Commit message examples"), false, "examples")
+		assertEqual(t, strings.Contains(messages[2], "Initial commit\nWIP"), true, "raw commits")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient("feat: Add foo")
+
+		_, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", commits)
+		assertNoError(t, err)
+
+		assertEqual(t, strings.Contains(openaiMessages(t, client.Requests()[0])[2], commits), true)
+	})
+}
+
+func TestQuery_MimicTopContributor(t *testing.T) {
+	t.Parallel()
+
+	var dir = newGitRepo(t, nil)
+
+	for i, c := range []struct{ author, subject string }{
+		{"Jane Doe <jane@example.com>", "feat(api): Add the endpoint"},
+		{"John Smith <john@example.com>", "Fixed stuff"},
+		{"Jane Doe <jane@example.com>", "fix(api): Handle the timeout"},
+	} {
+		writeFiles(t, dir, map[string]string{"file.txt": strings.Repeat("a", i+1)})
+		runGit(t, dir, "commit", "--quiet", "--author", c.author, "-m", c.subject)
+	}
+
+	var client = okClient("feat: Add foo")
+
+	_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+		Query(context.Background(), "diff", "", ai.WithMimicTopContributor(dir))
+	assertNoError(t, err)
+
+	var prompt = openaiMessages(t, client.Requests()[0])[0]
+
+	for _, want := range []string{
+		"Commit messages of Jane Doe, the top contributor of the repository",
+		"- fix(api): Handle the timeout\n- feat(api): Add the endpoint",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected %q to contain %q", prompt, want)
+		}
+	}
+
+	assertEqual(t, strings.Contains(prompt, "Fixed stuff"), false, "other authors")
+
+	t.Run("no commits", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient("feat: Add foo")
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "", ai.WithMimicTopContributor(newGitRepo(t, nil)))
+		assertNoError(t, err)
+
+		assertEqual(t, strings.Contains(openaiMessages(t, client.Requests()[0])[0], "top contributor"), false)
+	})
+}
+
+func TestQuery_DescribeAsAuthor(t *testing.T) {
+	t.Parallel()
+
+	var dir = newGitRepo(t, nil)
+
+	for i, c := range []struct{ author, subject string }{
+		{"Jane Doe <jane@example.com>", "feat(api): Add the endpoint"},
+		{"John Smith <john@example.com>", "Fixed stuff"},
+		{"John Smith <john@example.com>", "Tweaked things"},
+	} {
+		writeFiles(t, dir, map[string]string{"file.txt": strings.Repeat("a", i+1)})
+		runGit(t, dir, "commit", "--quiet", "--author", c.author, "-m", c.subject)
+	}
+
+	var client = okClient("feat: Add foo")
+
+	_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+		Query(context.Background(), "diff", "", ai.WithGitDir(dir), ai.WithDescribeAsAuthor("John Smith"))
+	assertNoError(t, err)
+
+	var prompt = openaiMessages(t, client.Requests()[0])[0]
+
+	for _, want := range []string{
+		"- Write the message as John Smith would",
+		"Recent commit messages of John Smith (write in their style)",
+		"- Tweaked things\n- Fixed stuff",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected %q to contain %q", prompt, want)
+		}
+	}
+
+	assertEqual(t, strings.Contains(prompt, "Add the endpoint"), false, "other authors")
+}
+
+func TestQuery_RecentCommits(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir    = newGitRepo(t, nil)
+		client = okClient("feat: Add foo")
+		p      = ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client))
+	)
+
+	// no commits yet
+	resp, err := p.Query(context.Background(), "diff", "", ai.WithGitDir(dir), ai.WithRecentCommits(2))
+	assertNoError(t, err)
+
+	assertEqual(t, strings.Contains(resp.Prompt, "Recent commit messages"), false, "empty repository")
+
+	for i, message := range []string{
+		"chore: Init",
+		"feat(api): Add the endpoint\n\n- Add the handler",
+		"fix(api): Handle the timeout\n\nThe context deadline is respected.",
+	} {
+		writeFiles(t, dir, map[string]string{"file.txt": strings.Repeat("a", i+1)})
+		runGit(t, dir, "commit", "--quiet", "-m", message)
+	}
+
+	resp, err = p.Query(context.Background(), "diff", "", ai.WithGitDir(dir), ai.WithRecentCommits(2))
+	assertNoError(t, err)
+
+	if want := "fix(api): Handle the timeout\n\nThe context deadline is respected.\n\n" +
+		"feat(api): Add the endpoint\n\n- Add the handler"; !strings.Contains(resp.Prompt, want) {
+		t.Errorf("expected %q to contain %q", resp.Prompt, want)
+	}
+
+	assertEqual(t, strings.Contains(resp.Prompt, "chore: Init"), false, "limited")
+}
@@ -0,0 +1,28 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// fixupPrefix is the prefix of the message the `git rebase --autosquash` squashes into the target commit.
+const fixupPrefix = "fixup! "
+
+// fixupResponse builds the response with the `fixup!` message for the target commit (see [WithFixupTarget]).
+func fixupResponse(ctx context.Context, o options) (*Response, error) {
+	if o.GitDir == "" {
+		return nil, errors.New("the fixup target requires the git directory (see WithGitDir)")
+	}
+
+	subject, err := git.CommitSubject(ctx, o.GitDir, o.FixupTarget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the fixup target: %w", err)
+	}
+
+	var answer = fixupPrefix + subject
+
+	return &Response{Answer: answer, Answers: []string{answer}, Subject: answer}, nil
+}
@@ -0,0 +1,56 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestQuery_FixupTarget(t *testing.T) {
+	t.Parallel()
+
+	var dir = newGitRepo(t, map[string]string{"main.go": "package main\n"})
+
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "feat(api): Add the endpoint\n\nThe body.")
+
+	var hash = strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+
+	t.Run("fixup message", func(t *testing.T) {
+		t.Parallel()
+
+		var client = okClient("feat: Add foo")
+
+		resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).Query(context.Background(), "diff", "log",
+			ai.WithGitDir(dir), ai.WithFixupTarget(hash[:10]),
+		)
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "fixup! feat(api): Add the endpoint")
+		assertEqual(t, len(client.Requests()), 0, "the provider is not called")
+	})
+
+	t.Run("unknown commit", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient("feat: Add foo"))).
+			Query(context.Background(), "diff", "log", ai.WithGitDir(dir), ai.WithFixupTarget("deadbeef"))
+		if !errors.Is(err, git.ErrUnknownRevision) {
+			t.Fatalf("expected the unknown revision error, got %v", err)
+		}
+	})
+
+	t.Run("no git dir", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient("feat: Add foo"))).
+			Query(context.Background(), "diff", "log", ai.WithFixupTarget(hash))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
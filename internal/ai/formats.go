@@ -0,0 +1,161 @@
+package ai
+
+import "strings"
+
+// OutputFormat is the format of the generated text.
+type OutputFormat uint8
+
+const (
+	// FormatCommitMessage is the (default) format: a Git commit message.
+	FormatCommitMessage OutputFormat = iota
+
+	// FormatGitNote is a longer, explanatory note about the changes, suitable for `git notes add -F -`. Unlike the
+	// commit message, it focuses on the rationale (the "why") rather than the summary.
+	FormatGitNote
+
+	// FormatReviewComment is a list of code review annotations in the "conventional comments" style (`praise:`,
+	// `nitpick:`, `issue:`, etc.), attributed to the changed files and lines. Use [ParseReviewComments] to parse it.
+	FormatReviewComment
+)
+
+// BodyStyle is the style of the commit message body.
+type BodyStyle uint8
+
+const (
+	// BulletList is the (default) body style: a short description followed by the bullet list of the key points.
+	BulletList BodyStyle = iota
+
+	// Paragraph is the prose body style: a single paragraph, without bullet points.
+	Paragraph
+)
+
+// generateFormatPrompt generates the system prompt for the non-default output formats.
+func generateFormatPrompt(opt options) string {
+	if opt.OutputFormat == FormatReviewComment {
+		return generateReviewPrompt(opt)
+	}
+
+	return generateNotePrompt(opt)
+}
+
+// generateNotePrompt generates the system prompt for the [FormatGitNote].
+func generateNotePrompt(opt options) string {
+	var b strings.Builder
+
+	b.Grow(2048) //nolint:mnd // pre-allocate memory for the string builder
+
+	{ // role
+		b.WriteString("## Role\n")
+		b.WriteString("You are an AI assistant specializing in documenting the changes in Git repositories.\n")
+
+		b.WriteRune('\n')
+	}
+
+	{ // task
+		b.WriteString("## Task\n")
+		b.WriteString("Generate a detailed, explanatory **SINGLE** Git note (for `git notes add -F -`) ")
+		b.WriteString("based on the provided input. The note is attached to the commit in addition to its ")
+		b.WriteString("commit message, so it must not repeat the commit subject.\n")
+		writeLocale(&b, opt, "note")
+		writeContextLabels(&b, opt)
+
+		b.WriteRune('\n')
+	}
+
+	writeInputSection(&b)
+	writeContextSection(&b, opt)
+
+	{ // output
+		b.WriteString("## Output\n")
+		b.WriteString("Your output should be the plain text of the note only. Do not wrap it in code blocks ")
+		b.WriteString("and do not include any additional text, headings, or explanations.\n")
+
+		b.WriteRune('\n')
+	}
+
+	{ // note guidelines
+		b.WriteString("## Note Guidelines\n")
+		b.WriteString("- Explain **why** the changes were made: the motivation, the problem being solved, ")
+		b.WriteString("and the reasoning behind the chosen approach.\n")
+		b.WriteString("- Mention the alternatives that were considered (if evident from the changes), ")
+		b.WriteString("the trade-offs, and the known limitations.\n")
+		b.WriteString("- Describe the impact on users, the API, or other parts of the codebase, if any.\n")
+		b.WriteString("- Split the note into short paragraphs separated by blank lines; bullet points are allowed.\n")
+		b.WriteString("- Wrap lines at 72 characters.\n")
+		b.WriteString("- Do not use Markdown formatting, except for inline code in backticks.\n")
+
+		if opt.EnableEmoji {
+			b.WriteString("- Emojis are allowed, but use them sparingly.\n")
+		} else {
+			b.WriteString("- Do not use emojis.\n")
+		}
+
+		b.WriteRune('\n')
+	}
+
+	writeSecuritySection(&b, "note")
+
+	{ // instructions
+		b.WriteString("## Instructions for the AI\n")
+		b.WriteString("- Analyze the provided `git diff` to understand the current changes.\n")
+		b.WriteString("- Analyze the provided `git log` output to better understand the codebase and the ")
+		b.WriteString("reasons behind the changes, but do not describe the previous commits in the note.\n")
+	}
+
+	return b.String()
+}
+
+// writeLocale writes the instruction on the formatting of the dates and numbers in the given kind of output (e.g.
+// "note"), if the locale is set.
+func writeLocale(b *strings.Builder, opt options, what string) {
+	if locale := strings.TrimSpace(opt.Locale); locale != "" {
+		b.WriteString("Format the dates, numbers, and versions mentioned in the " + what + " according to the `")
+		b.WriteString(locale + "` locale conventions (e.g., the date order and the decimal separator).\n")
+	}
+}
+
+// writeSecuritySection writes the security guidelines for the given kind of output (e.g. "note").
+func writeSecuritySection(b *strings.Builder, what string) {
+	b.WriteString("## Security\n")
+	b.WriteString("- Exclude sensitive data (passwords, API keys, personal information, etc.) ")
+	b.WriteString("or code snippets from the " + what + ".\n")
+	b.WriteRune('\n')
+}
+
+// NoteToArgs converts the generated Git note into the `git` command arguments that attach it to the current commit
+// (`HEAD`). Every paragraph is passed as a separate `-m` argument (git joins them with blank lines). Returns nil if
+// the response is nil or empty.
+func NoteToArgs(resp *Response) []string {
+	if resp == nil {
+		return nil
+	}
+
+	var paragraphs = splitParagraphs(resp.Answer)
+
+	if len(paragraphs) == 0 {
+		return nil
+	}
+
+	var args = make([]string, 0, 2+2*len(paragraphs)) //nolint:mnd // "notes add" + "-m <paragraph>" pairs
+
+	args = append(args, "notes", "add")
+
+	for _, p := range paragraphs {
+		args = append(args, "-m", p)
+	}
+
+	return args
+}
+
+// splitParagraphs splits the text into the paragraphs (separated by blank lines). Empty paragraphs are skipped.
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.Trim(p, "\n"); strings.TrimSpace(p) != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+
+	return paragraphs
+}
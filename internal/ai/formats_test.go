@@ -0,0 +1,36 @@
+package ai_test
+
+import (
+	"slices"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestNoteToArgs(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveResp *ai.Response
+		want     []string
+	}{
+		"nil":   {giveResp: nil, want: nil},
+		"empty": {giveResp: &ai.Response{Answer: " \n\n \n"}, want: nil},
+		"single paragraph": {
+			giveResp: &ai.Response{Answer: "The cache was\nunbounded."},
+			want:     []string{"notes", "add", "-m", "The cache was\nunbounded."},
+		},
+		"multiple paragraphs": {
+			giveResp: &ai.Response{Answer: "First.\r\n\r\nSecond:\n- one\n- two\n\n\n\nThird.\n"},
+			want:     []string{"notes", "add", "-m", "First.", "-m", "Second:\n- one\n- two", "-m", "Third."},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ai.NoteToArgs(tc.giveResp); !slices.Equal(got, tc.want) {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
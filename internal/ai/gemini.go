@@ -22,6 +22,7 @@ var _ Provider = (*Gemini)(nil) // ensure the interface is implemented
 type (
 	geminiOptions struct {
 		HttpClient httpClient
+		Timeout    time.Duration
 	}
 
 	// GeminiOption allows to customize the Gemini provider.
@@ -33,9 +34,15 @@ func WithGeminiHttpClient(c httpClient) GeminiOption {
 	return func(o *geminiOptions) { o.HttpClient = c }
 }
 
+// WithGeminiTimeout sets the timeout of the default HTTP client (60 seconds by default). It has no effect if the
+// custom HTTP client is set (see [WithGeminiHttpClient]).
+func WithGeminiTimeout(d time.Duration) GeminiOption {
+	return func(o *geminiOptions) { o.Timeout = d }
+}
+
 // NewGemini creates a new Gemini provider.
 func NewGemini(apiKey, model string, opt ...GeminiOption) *Gemini {
-	var opts geminiOptions
+	var opts = geminiOptions{Timeout: defaultTimeout}
 
 	for _, o := range opt {
 		o(&opts)
@@ -49,7 +56,7 @@ func NewGemini(apiKey, model string, opt ...GeminiOption) *Gemini {
 
 	if p.httpClient == nil { // set default HTTP client
 		p.httpClient = &http.Client{
-			Timeout:   60 * time.Second,                         //nolint:mnd
+			Timeout:   opts.Timeout,
 			Transport: &http.Transport{ForceAttemptHTTP2: true}, // use HTTP/2 (why not?)
 		}
 	}
@@ -57,53 +64,44 @@ func NewGemini(apiKey, model string, opt ...GeminiOption) *Gemini {
 	return &p
 }
 
-func (p *Gemini) Query( //nolint:dupl
-	ctx context.Context,
-	changes, commits string,
-	opts ...Option,
-) (*Response, error) {
-	var (
-		opt          = options{}.Apply(opts...)
-		instructions = GeneratePrompt(opts...)
-	)
+// Query implements the [Provider] interface.
+func (p *Gemini) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
 
-	if opt.MaxOutputTokens == 0 {
-		opt.MaxOutputTokens = defaultMaxOutputTokens // set default value
-	}
+// Name implements the [Provider] interface.
+func (*Gemini) Name() string { return ProviderGemini }
+
+// model returns the model name.
+func (p *Gemini) model() string { return p.modelName }
 
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (*Gemini) batchesCandidates() bool { return true }
+
+// complete performs a single request to the Gemini API.
+func (p *Gemini) complete(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
 	// https://ai.google.dev/gemini-api/docs/text-generation?lang=rest
 	req, rErr := p.newRequest(ctx, instructions, changes, commits, opt)
 	if rErr != nil {
 		return nil, rErr
 	}
 
-	resp, rErr := p.httpClient.Do(req)
+	resp, rErr := doRequest("Gemini", p.httpClient, req)
 	if rErr != nil {
 		return nil, rErr
 	}
 
-	defer func() { _ = resp.Body.Close() }()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, p.responseToError(resp)
 	}
 
-	answer, aErr := p.parseResponse(resp)
-	if aErr != nil {
-		return nil, aErr
-	}
-
-	if opt.ShortMessageOnly {
-		var parts = strings.Split(answer, "\n")
-
-		if len(parts) == 0 {
-			return nil, errors.New("no response from the Gemini API")
-		}
-
-		return &Response{Prompt: instructions, Answer: parts[0]}, nil
-	}
-
-	return &Response{Prompt: instructions, Answer: answer}, nil
+	return p.parseResponse(resp)
 }
 
 // newRequest creates a new HTTP request for the Gemini API.
@@ -118,6 +116,7 @@ func (p *Gemini) newRequest( //nolint:funlen
 			MaxOutputTokens int64   `json:"maxOutputTokens"`
 			TopP            float64 `json:"topP"`
 			CandidateCount  int     `json:"candidateCount"`
+			MimeType        string  `json:"responseMimeType,omitempty"`
 		}
 
 		safetySetting struct {
@@ -146,10 +145,10 @@ func (p *Gemini) newRequest( //nolint:funlen
 		Contents       []content       `json:"contents"`
 	}{
 		GenerationConfig: generationConfig{
-			Temperature:     0.1, //nolint:mnd
+			Temperature:     o.temperature(),
 			MaxOutputTokens: o.MaxOutputTokens,
-			TopP:            0.1, //nolint:mnd
-			CandidateCount:  1,
+			TopP:            o.topP(),
+			CandidateCount:  o.Candidates,
 		},
 		SafetySettings: []safetySetting{
 			{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_LOW_AND_ABOVE"},
@@ -157,14 +156,19 @@ func (p *Gemini) newRequest( //nolint:funlen
 			{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_LOW_AND_ABOVE"},
 			{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_LOW_AND_ABOVE"},
 		},
-		Contents: []content{{Parts: []contentPart{
-			{Text: wrapChanges(changes)},
-			{Text: wrapCommits(commits)},
-		}}},
+		Contents: []content{{}},
 	}
 
 	data.SystemInstruction.Parts.Text = instructions
 
+	for _, turn := range userTurns(changes, commits, o) {
+		data.Contents[0].Parts = append(data.Contents[0].Parts, contentPart{Text: turn})
+	}
+
+	if o.jsonOutput() {
+		data.GenerationConfig.MimeType = "application/json"
+	}
+
 	j, jErr := json.Marshal(data)
 	if jErr != nil {
 		return nil, jErr
@@ -173,13 +177,14 @@ func (p *Gemini) newRequest( //nolint:funlen
 	// https://ai.google.dev/gemini-api/docs/text-generation?lang=rest
 	req, rErr := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(
 		"https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent",
-		p.modelName,
+		o.modelOr(p.modelName),
 	), bytes.NewReader(j))
 	if rErr != nil {
 		return nil, rErr
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.userAgent())
 
 	// https://cloud.google.com/docs/authentication/api-keys-use#using-with-rest
 	req.Header.Set("x-goog-api-key", p.apiKey)
@@ -188,28 +193,10 @@ func (p *Gemini) newRequest( //nolint:funlen
 }
 
 // responseToError converts the response from the Gemini API to an error.
-func (p *Gemini) responseToError(resp *http.Response) error {
-	var response struct {
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err == nil && response.Error.Message != "" {
-		return fmt.Errorf(
-			"gemini API error: %s (status code: %d)",
-			response.Error.Message, resp.StatusCode,
-		)
-	}
-
-	return fmt.Errorf(
-		"unexpected Gemini API response status code: %d (%s)",
-		resp.StatusCode, http.StatusText(resp.StatusCode),
-	)
-}
+func (p *Gemini) responseToError(resp *http.Response) error { return newAPIError("Gemini", resp) }
 
-// parseResponse parses the response from the Gemini API.
-func (p *Gemini) parseResponse(resp *http.Response) (string, error) {
+// parseResponse parses the response from the Gemini API. Each candidate is returned as a separate answer.
+func (p *Gemini) parseResponse(resp *http.Response) (*completion, error) {
 	var answer struct {
 		Candidates []struct {
 			Content struct {
@@ -217,26 +204,50 @@ func (p *Gemini) parseResponse(resp *http.Response) (string, error) {
 					Text string `json:"text"`
 				} `json:"parts"`
 			} `json:"content"`
+			FinishReason string `json:"finishReason"`
 		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+			TotalTokenCount      int `json:"totalTokenCount"`
+		} `json:"usageMetadata"`
 	}
 
 	if dErr := json.NewDecoder(resp.Body).Decode(&answer); dErr != nil {
-		return "", dErr
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, dErr)
 	}
 
 	if len(answer.Candidates) == 0 || len(answer.Candidates[0].Content.Parts) == 0 {
-		return "", errors.New("no content found")
+		return nil, errors.New("no content found")
 	}
 
-	var texts = make([]string, 0, len(answer.Candidates[0].Content.Parts))
+	var texts = make([]string, 0, len(answer.Candidates))
 
 	for _, candidate := range answer.Candidates {
+		var parts = make([]string, 0, len(candidate.Content.Parts))
+
 		for _, part := range candidate.Content.Parts {
 			if part.Text != "" {
-				texts = append(texts, part.Text)
+				parts = append(parts, part.Text)
 			}
 		}
+
+		if text := finalizeAnswer(strings.Join(parts, "\n")); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	if len(texts) == 0 {
+		return nil, errors.New("no content found")
 	}
 
-	return strings.Trim(strings.Join(texts, "\n"), "\n\t "), nil
+	return &completion{
+		Answers: texts,
+		Usage: Usage{
+			PromptTokens:     answer.UsageMetadata.PromptTokenCount,
+			CompletionTokens: answer.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      answer.UsageMetadata.TotalTokenCount,
+		},
+		Truncated: answer.Candidates[0].FinishReason == "MAX_TOKENS",
+	}, nil
 }
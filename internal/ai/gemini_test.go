@@ -0,0 +1,139 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestGemini_Query(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+		assertEqual(t, req.URL.String(),
+			"https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent",
+		)
+		assertEqual(t, req.Header.Get("x-goog-api-key"), "gemini-key")
+
+		return newHttpResponse(http.StatusOK, `{
+			"candidates":[{"content":{"parts":[{"text":"feat: Add foo\n"},{"text":"- Bar"}],"role":"model"}}],
+			"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}
+		}`), nil
+	}}
+
+	resp, err := ai.NewGemini("gemini-key", "gemini-2.0-flash", ai.WithGeminiHttpClient(&client)).
+		Query(context.Background(), "diff", "log", ai.WithTemperature(0.5), ai.WithTopP(0.9))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo\n\n- Bar")
+	assertEqual(t, resp.Usage, ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+
+	var req struct {
+		SystemInstruction struct {
+			Parts struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"system_instruction"`
+		Contents []struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"contents"`
+		GenerationConfig struct {
+			Temperature     float64 `json:"temperature"`
+			TopP            float64 `json:"topP"`
+			MaxOutputTokens int     `json:"maxOutputTokens"`
+		} `json:"generationConfig"`
+	}
+
+	assertNoError(t, json.Unmarshal([]byte(client.Requests()[0]), &req))
+
+	assertEqual(t, req.SystemInstruction.Parts.Text, resp.Prompt)
+	assertEqual(t, len(req.Contents), 1)
+	assertEqual(t, len(req.Contents[0].Parts), 2)
+	assertEqual(t, req.GenerationConfig.Temperature, 0.5)
+	assertEqual(t, req.GenerationConfig.TopP, 0.9)
+	assertEqual(t, req.GenerationConfig.MaxOutputTokens, 500)
+}
+
+func TestGemini_Candidates(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusOK, `{"candidates":[
+			{"content":{"parts":[{"text":"feat: Add foo"}]}},
+			{"content":{"parts":[{"text":"fix: Fix foo"}]}}
+		]}`), nil
+	}}
+
+	resp, err := ai.NewGemini("", "gemini-2.0-flash", ai.WithGeminiHttpClient(&client)).
+		Query(context.Background(), "diff", "log", ai.WithCandidates(2))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, len(resp.Alternatives), 1)
+	assertEqual(t, resp.Alternatives[0], "fix: Fix foo")
+	assertEqual(t, len(client.Requests()), 1) // all the candidates are generated at once
+}
+
+func TestGemini_Error(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveCode    int
+		giveBody    string
+		wantMessage string
+		wantErr     error
+	}{
+		"invalid key": {
+			giveCode: http.StatusBadRequest,
+			giveBody: `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.",` +
+				`"status":"INVALID_ARGUMENT"}}`,
+			wantMessage: "API key not valid. Please pass a valid API key.",
+			wantErr:     ai.ErrBadRequest,
+		},
+		"too long": {
+			giveCode: http.StatusBadRequest,
+			giveBody: `{"error":{"code":400,"message":"The input token count (1200000) exceeds the maximum number ` +
+				`of tokens allowed (1048576).","status":"INVALID_ARGUMENT"}}`,
+			wantMessage: "The input token count (1200000) exceeds the maximum number of tokens allowed (1048576).",
+			wantErr:     ai.ErrContextTooLong,
+		},
+		"no content": {
+			giveCode: http.StatusOK,
+			giveBody: `{"candidates":[{"finishReason":"SAFETY"}]}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return newHttpResponse(tc.giveCode, tc.giveBody), nil
+			}}
+
+			_, err := ai.NewGemini("", "gemini-2.0-flash", ai.WithGeminiHttpClient(&client)).
+				Query(context.Background(), "diff", "log")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if tc.wantErr == nil {
+				return
+			}
+
+			var apiErr *ai.APIError
+
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected the API error, got %v", err)
+			}
+
+			assertEqual(t, apiErr.Provider, "Gemini")
+			assertEqual(t, apiErr.Message, tc.wantMessage)
+			assertEqual(t, errors.Is(err, tc.wantErr), true, tc.wantErr.Error())
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package ai
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// conventionConfigNames are the base names (or their prefixes, if ending with "*") of the files that configure the
+// commit conventions or the commit message linting.
+var conventionConfigNames = [...]string{ //nolint:gochecknoglobals
+	"commitlint.config.*", ".commitlintrc*", ".gitlint", ".czrc", ".cz.*", "cz.json", ".gitmessage*",
+	".versionrc*", ".releaserc*", "release.config.*", ".cz-config.*",
+}
+
+// fileTypes maps the file extensions (or the base names of the files without extension) to the file type names.
+var fileTypes = map[string]string{ //nolint:gochecknoglobals
+	".go": "Go", ".mod": "Go module", ".sum": "Go module",
+	".py": "Python", ".rb": "Ruby", ".php": "PHP", ".java": "Java", ".kt": "Kotlin", ".scala": "Scala",
+	".rs": "Rust", ".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".hpp": "C++", ".cs": "C#",
+	".swift": "Swift", ".dart": "Dart", ".ex": "Elixir", ".exs": "Elixir", ".lua": "Lua",
+	".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".ts": "TypeScript", ".tsx": "TypeScript",
+	".vue": "Vue", ".svelte": "Svelte", ".html": "HTML", ".css": "CSS", ".scss": "SCSS",
+	".sh": "shell", ".bash": "shell", ".ps1": "PowerShell", ".sql": "SQL", ".proto": "Protobuf",
+	".yml": "YAML", ".yaml": "YAML", ".json": "JSON", ".toml": "TOML", ".xml": "XML", ".ini": "INI",
+	".env": "env", ".md": "Markdown", ".rst": "reStructuredText", ".txt": "text",
+	".png": "image", ".jpg": "image", ".jpeg": "image", ".gif": "image", ".svg": "image", ".webp": "image",
+	".ico": "image", "Dockerfile": "Dockerfile", "Makefile": "Makefile",
+}
+
+// withChangesContext adds the hints derived from the changes (no repository access is required) to the options.
+func withChangesContext(changes string, opts []Option) []Option {
+	var o = options{}.Apply(opts...)
+
+	if o.DepsAware {
+		if bumps := dependencyUpdate(changes); len(bumps) > 0 {
+			opts = append(opts[:len(opts):len(opts)], withContext("Dependency update", dependencyUpdateHint(bumps, o)))
+		}
+	}
+
+	if o.FileTypeSummary {
+		if summary := fileTypeSummary(git.ChangedFiles(changes)); summary != "" {
+			opts = append(opts[:len(opts):len(opts)], withContext("Changed file types", summary))
+		}
+	}
+
+	if o.inlineFileNotes() {
+		if files := git.ChangedFiles(changes); len(files) > 0 {
+			opts = append(opts[:len(opts):len(opts)], withContext("Changed files", "- "+strings.Join(files, "\n- ")))
+		}
+	}
+
+	if files := conventionConfigFiles(git.ChangedFiles(changes)); len(files) > 0 {
+		opts = append(opts[:len(opts):len(opts)], withContext("Commit conventions configuration",
+			"The following files configure the commit conventions or the commit message linting of the repository: `"+
+				strings.Join(files, "`, `")+"`. Changing them is a configuration change, not a feature or a fix: "+
+				"unless other changes dominate, use the `build` or `chore(config)` type for them.",
+		))
+	}
+
+	return opts
+}
+
+// conventionConfigFiles returns the files configuring the commit conventions (see [conventionConfigNames]).
+func conventionConfigFiles(files []string) []string {
+	var found []string
+
+	for _, f := range files {
+		var base = path.Base(f)
+
+		for _, name := range conventionConfigNames {
+			if prefix, ok := strings.CutSuffix(name, "*"); (ok && strings.HasPrefix(base, prefix)) || base == name {
+				found = append(found, f)
+
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+// fileType returns the type name of the file (see [fileTypes]), or an empty string if the type is unknown.
+func fileType(file string) string {
+	if name, ok := fileTypes[strings.ToLower(path.Ext(file))]; ok {
+		return name
+	}
+
+	return fileTypes[path.Base(file)]
+}
+
+// fileTypeSummary returns the summary of the file types (e.g. "3 Go files, 1 YAML file"), the most common first.
+// The files of unknown types are counted as "other".
+func fileTypeSummary(files []string) string {
+	type typeCount struct {
+		name  string
+		count int
+	}
+
+	var counts []typeCount
+
+	for _, f := range files {
+		var name = fileType(f)
+		if name == "" {
+			name = "other"
+		}
+
+		if i := slices.IndexFunc(counts, func(c typeCount) bool { return c.name == name }); i >= 0 {
+			counts[i].count++
+		} else {
+			counts = append(counts, typeCount{name: name, count: 1})
+		}
+	}
+
+	slices.SortStableFunc(counts, func(a, b typeCount) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), cmp.Compare(a.name, b.name))
+	})
+
+	var parts = make([]string, 0, len(counts))
+
+	for _, c := range counts {
+		if c.count == 1 {
+			parts = append(parts, fmt.Sprintf("1 %s file", c.name))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %s files", c.count, c.name))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// maxScopeHints limits the number of the scopes suggested to the model (see [WithScopeHints]).
+const maxScopeHints = 5
+
+// scopeContainerDirs are the top-level directories grouping the modules, so the scope is the module inside them
+// (e.g. "internal/ai" rather than "internal").
+var scopeContainerDirs = [...]string{ //nolint:gochecknoglobals
+	"internal", "pkg", "cmd", "src", "lib", "libs", "apps", "packages", "services", "modules",
+}
+
+// likelyScopes returns the likely scopes of the changed files: their top-level directories (or the second-level
+// ones for the container directories, see [scopeContainerDirs]), the most frequent first. The files in the root are
+// skipped.
+func likelyScopes(paths []string) []string {
+	type scope struct {
+		name  string
+		count int
+	}
+
+	var scopes []scope
+
+	for _, p := range paths {
+		var parts = strings.Split(strings.Trim(p, "/"), "/")
+		if len(parts) < 2 { //nolint:mnd
+			continue // the file is in the root
+		}
+
+		var name = parts[0]
+
+		if len(parts) > 2 && slices.Contains(scopeContainerDirs[:], parts[0]) { //nolint:mnd
+			name = parts[0] + "/" + parts[1]
+		}
+
+		if i := slices.IndexFunc(scopes, func(s scope) bool { return s.name == name }); i >= 0 {
+			scopes[i].count++
+		} else {
+			scopes = append(scopes, scope{name: name, count: 1})
+		}
+	}
+
+	slices.SortStableFunc(scopes, func(a, b scope) int { return cmp.Compare(b.count, a.count) })
+
+	var names = make([]string, 0, min(len(scopes), maxScopeHints))
+
+	for _, s := range scopes[:min(len(scopes), maxScopeHints)] {
+		names = append(names, s.name)
+	}
+
+	return names
+}
+
+// repoName returns the name of the repository (e.g. "github.com/org/payments-service") from its remote URL (see
+// [git.RemoteURL]): the host and the path, without the scheme, the port, and the ".git" suffix. An empty string is
+// returned for the local paths.
+func repoName(remote string) string {
+	var host, repoPath string
+
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		host, repoPath = u.Hostname(), u.Path
+	} else if h, p, ok := strings.Cut(remote, ":"); ok && len(h) > 1 && !strings.ContainsAny(h, `/\`) &&
+		!strings.HasPrefix(p, "//") {
+		host, repoPath = h[strings.LastIndex(h, "@")+1:], p // scp-like syntax ([user@]host:path), not C:\path
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+
+	if host == "" || repoPath == "" {
+		return ""
+	}
+
+	return host + "/" + repoPath
+}
+
+// testFileRegex matches the paths of the test files (including the test fixtures) of the common languages and
+// frameworks.
+var testFileRegex = regexp.MustCompile(
+	`(^|/)(tests?|__tests__|spec|testdata|fixtures)/|` + // the test directories
+		`(_test\.go|_test\.py|_spec\.rb|Tests?\.(java|kt|cs|swift)|\.(test|spec)\.[cm]?[jt]sx?)$|` + // the suffixes
+		`(^|/)test_[^/]+\.py$`, // the pytest prefix
+)
+
+// isTestOnly reports whether all the changed files are the test files (see [testFileRegex]).
+func isTestOnly(files []string) bool {
+	if len(files) == 0 {
+		return false
+	}
+
+	for _, file := range files {
+		if !testFileRegex.MatchString(file) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileTypeSummary(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		give []string
+		want string
+	}{
+		"mixed": {
+			give: []string{"main.go", "internal/ai/query.go", ".github/workflows/ci.yml", "README.md", "cmd/app.go"},
+			want: "3 Go files, 1 Markdown file, 1 YAML file",
+		},
+		"case insensitive": {give: []string{"LOGO.PNG", "docs/icon.svg"}, want: "2 image files"},
+		"by base name":     {give: []string{"Dockerfile", "build/Makefile"}, want: "1 Dockerfile file, 1 Makefile file"},
+		"unknown":          {give: []string{"LICENSE", "data.bin", "go.sum"}, want: "2 other files, 1 Go module file"},
+		"empty":            {give: nil, want: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := fileTypeSummary(tc.give); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLikelyScopes(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		give []string
+		want string
+	}{
+		"by count": {
+			give: []string{"api/handler.go", "internal/ai/query.go", "internal/ai/prompt.go", "api/router.go", "docs/x.md"},
+			want: "api, internal/ai, docs",
+		},
+		"container dirs": {give: []string{"pkg/log/log.go", "pkg/README.md"}, want: "pkg/log, pkg"},
+		"root files":     {give: []string{"go.mod", "README.md"}, want: ""},
+		"limited": {
+			give: []string{"a/1", "b/1", "c/1", "d/1", "e/1", "f/1"},
+			want: "a, b, c, d, e",
+		},
+		"empty": {give: nil, want: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := strings.Join(likelyScopes(tc.give), ", "); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsTestOnly(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		give []string
+		want bool
+	}{
+		"go":         {give: []string{"api/server_test.go", "api/testdata/req.json"}, want: true},
+		"js":         {give: []string{"web/app.spec.ts", "web/__tests__/util.js", "web/util.test.mjs"}, want: true},
+		"python":     {give: []string{"tests/conftest.py", "pkg/test_util.py", "pkg/util_test.py"}, want: true},
+		"java":       {give: []string{"src/main/FooTest.java", "src/main/FooTests.kt"}, want: true},
+		"mixed":      {give: []string{"api/server_test.go", "api/server.go"}, want: false},
+		"look-alike": {give: []string{"api/contest.go", "latest/main.go"}, want: false},
+		"empty":      {give: nil, want: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isTestOnly(tc.give); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRepoName(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]string{
+		"https://github.com/org/payments-service.git": "github.com/org/payments-service",
+		"https://gitlab.com:8443/group/sub/repo/":     "gitlab.com/group/sub/repo",
+		"ssh://github.com:22/org/repo.git":            "github.com/org/repo",
+		"git@github.com:org/payments-service.git":     "github.com/org/payments-service",
+		"github.com:org/repo":                         "github.com/org/repo",
+		"file:///srv/git/repo.git":                    "",
+		"/srv/git/repo.git":                           "",
+		`C:\repos\repo.git`:                           "",
+		"":                                            "",
+	} {
+		if got := repoName(give); got != want {
+			t.Errorf("repoName(%q) = %q, want %q", give, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_CommitConventionsHint(t *testing.T) {
+	t.Parallel()
+
+	const hint = "configure the commit conventions"
+
+	for name, tc := range map[string]struct {
+		giveDiff    string
+		wantContain []string
+		wantHint    bool
+	}{
+		"commitlint config": {
+			giveDiff: "diff --git a/commitlint.config.js b/commitlint.config.js\n" +
+				"--- a/commitlint.config.js\n+++ b/commitlint.config.js\n@@ -1 +1 @@\n-a\n+b\n" +
+				"diff --git a/tools/.gitlint b/tools/.gitlint\n--- a/tools/.gitlint\n+++ b/tools/.gitlint\n",
+			wantContain: []string{"`commitlint.config.js`, `tools/.gitlint`", "`build` or `chore(config)`"},
+			wantHint:    true,
+		},
+		"commitlintrc": {
+			giveDiff: "diff --git a/.commitlintrc.yml b/.commitlintrc.yml\n" +
+				"--- a/.commitlintrc.yml\n+++ b/.commitlintrc.yml\n",
+			wantContain: []string{"`.commitlintrc.yml`"},
+			wantHint:    true,
+		},
+		"regular source": {
+			giveDiff: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("build: Update commitlint rules")
+
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), tc.giveDiff, "")
+			assertNoError(t, err)
+
+			var prompt = openaiMessages(t, client.Requests()[0])[0]
+
+			assertEqual(t, strings.Contains(prompt, hint), tc.wantHint)
+
+			for _, want := range tc.wantContain {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("expected %q to contain %q", prompt, want)
+				}
+			}
+		})
+	}
+}
+
+func TestQuery_FileTypeSummary(t *testing.T) {
+	t.Parallel()
+
+	const diff = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+		"diff --git a/docs/index.md b/docs/index.md\n--- a/docs/index.md\n+++ b/docs/index.md\n@@ -1 +1 @@\n-a\n+b\n"
+
+	for name, tc := range map[string]struct {
+		giveOpts []ai.Option
+		want     bool
+	}{
+		"enabled":  {giveOpts: []ai.Option{ai.WithFileTypeSummary(true)}, want: true},
+		"disabled": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("docs: Update the index page")
+
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), diff, "", tc.giveOpts...)
+			assertNoError(t, err)
+
+			var prompt = openaiMessages(t, client.Requests()[0])[0]
+
+			assertEqual(t, strings.Contains(prompt, "Changed file types"), tc.want, "title")
+			assertEqual(t, strings.Contains(prompt, "1 Go file, 1 Markdown file"), tc.want, "summary")
+		})
+	}
+}
+
+func TestQuery_InlineFileNotes(t *testing.T) {
+	t.Parallel()
+
+	const diff = "diff --git a/auth.go b/auth.go\n--- a/auth.go\n+++ b/auth.go\n@@ -1 +1 @@\n-a\n+b\n" +
+		"diff --git a/api/server.go b/api/server.go\n--- a/api/server.go\n+++ b/api/server.go\n@@ -1 +1 @@\n-a\n+b\n"
+
+	for name, tc := range map[string]struct {
+		giveOpts []ai.Option
+		want     bool
+	}{
+		"enabled":  {giveOpts: []ai.Option{ai.WithInlineFileNotes(true)}, want: true},
+		"disabled": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat(auth): Add the token refresh\n\n- auth.go: add token refresh")
+
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), diff, "", tc.giveOpts...)
+			assertNoError(t, err)
+
+			var prompt = openaiMessages(t, client.Requests()[0])[0]
+
+			assertEqual(t, strings.Contains(prompt, "Changed files"), tc.want, "title")
+			assertEqual(t, strings.Contains(prompt, "- auth.go\n- api/server.go"), tc.want, "files")
+		})
+	}
+}
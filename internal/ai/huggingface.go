@@ -0,0 +1,267 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HuggingFace is a provider for the Hugging Face Inference Providers (the OpenAI-compatible router).
+type HuggingFace struct {
+	httpClient       httpClient
+	token, modelName string
+	waitForModel     bool
+}
+
+var _ Provider = (*HuggingFace)(nil) // ensure the interface is implemented
+
+type (
+	huggingFaceOptions struct {
+		HttpClient   httpClient
+		WaitForModel bool
+		Timeout      time.Duration
+	}
+
+	// HuggingFaceOption allows to customize the Hugging Face provider.
+	HuggingFaceOption func(*huggingFaceOptions)
+)
+
+// WithHuggingFaceHttpClient sets the HTTP client for the Hugging Face provider.
+func WithHuggingFaceHttpClient(c httpClient) HuggingFaceOption {
+	return func(o *huggingFaceOptions) { o.HttpClient = c }
+}
+
+// WithHuggingFaceTimeout sets the timeout of the default HTTP client (60 seconds by default). It has no effect if the
+// custom HTTP client is set (see [WithHuggingFaceHttpClient]).
+func WithHuggingFaceTimeout(d time.Duration) HuggingFaceOption {
+	return func(o *huggingFaceOptions) { o.Timeout = d }
+}
+
+// WithWaitForModel enables waiting for the model to load (the cold start) instead of returning an error. The wait
+// time is taken from the response (the `estimated_time` field), and the request is retried a few times at most.
+func WithWaitForModel(on bool) HuggingFaceOption {
+	return func(o *huggingFaceOptions) { o.WaitForModel = on }
+}
+
+const (
+	hfMaxLoadingRetries  = 3                // how many times to retry the request while the model is loading
+	hfDefaultLoadingWait = 10 * time.Second // used when the estimated time is not provided
+	hfMaxLoadingWait     = 2 * time.Minute  // the max time to wait before a single retry
+)
+
+// NewHuggingFace creates a new Hugging Face provider.
+func NewHuggingFace(token, model string, opt ...HuggingFaceOption) *HuggingFace {
+	var opts = huggingFaceOptions{Timeout: defaultTimeout}
+
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	var p = HuggingFace{
+		httpClient:   opts.HttpClient,
+		token:        token,
+		modelName:    model,
+		waitForModel: opts.WaitForModel,
+	}
+
+	if p.httpClient == nil { // set default HTTP client
+		p.httpClient = &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: &http.Transport{ForceAttemptHTTP2: true}, // use HTTP/2 (why not?)
+		}
+	}
+
+	return &p
+}
+
+// Query implements the [Provider] interface.
+func (p *HuggingFace) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
+
+// Name implements the [Provider] interface.
+func (*HuggingFace) Name() string { return ProviderHuggingFace }
+
+// model returns the model name.
+func (p *HuggingFace) model() string { return p.modelName }
+
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (*HuggingFace) batchesCandidates() bool { return true }
+
+// complete performs a single request to the Hugging Face API (retrying while the model is loading, if enabled).
+func (p *HuggingFace) complete(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
+	for attempt := 0; ; attempt++ {
+		req, rErr := p.newRequest(ctx, instructions, changes, commits, opt)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		resp, rErr := doRequest("HuggingFace", p.httpClient, req)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			defer drainAndClose(resp.Body)
+
+			return p.parseResponse(resp)
+		}
+
+		var apiErr, wait = p.responseToError(resp)
+
+		drainAndClose(resp.Body)
+
+		if wait == 0 || !p.waitForModel || attempt >= hfMaxLoadingRetries {
+			return nil, apiErr
+		}
+
+		var timer = time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// newRequest creates a new HTTP request for the Hugging Face API.
+func (p *HuggingFace) newRequest(
+	ctx context.Context,
+	instructions, changes, commits string,
+	o options,
+) (*http.Request, error) {
+	// https://huggingface.co/docs/inference-providers/tasks/chat-completion
+	j, jErr := json.Marshal(struct {
+		Model          string          `json:"model"`
+		Messages       []chatMessage   `json:"messages"`
+		Temperature    float64         `json:"temperature"`
+		TopP           float64         `json:"top_p"`
+		HowMany        int             `json:"n"` // How many chat completion choices to generate for each input message
+		MaxTokens      int64           `json:"max_tokens"`
+		ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	}{
+		Model:          o.modelOr(p.modelName),
+		Temperature:    o.temperature(),
+		TopP:           o.topP(),
+		HowMany:        o.Candidates,
+		MaxTokens:      o.MaxOutputTokens,
+		ResponseFormat: jsonResponseFormat(o),
+		Messages:       chatMessages(instructions, changes, commits, o),
+	})
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	req, rErr := http.NewRequestWithContext(ctx,
+		http.MethodPost,
+		"https://router.huggingface.co/v1/chat/completions",
+		bytes.NewReader(j),
+	)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.userAgent())
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.token))
+
+	return req, nil
+}
+
+// responseToError converts the response from the Hugging Face API to an error. If the model is loading (the cold
+// start), the estimated time to wait is returned too (zero otherwise).
+func (p *HuggingFace) responseToError(resp *http.Response) (*APIError, time.Duration) {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxDrainBytes))
+
+	var apiErr = newAPIError("HuggingFace", &http.Response{
+		StatusCode: resp.StatusCode,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	})
+
+	// the legacy errors are strings, e.g. `{"error": "Model <name> is currently loading", "estimated_time": 20.5}`
+	var legacy struct {
+		Error         json.RawMessage `json:"error"`
+		EstimatedTime float64         `json:"estimated_time"`
+	}
+
+	if err := json.Unmarshal(body, &legacy); err == nil && apiErr.Message == "" {
+		_ = json.Unmarshal(legacy.Error, &apiErr.Message)
+	}
+
+	if resp.StatusCode != http.StatusServiceUnavailable ||
+		!strings.Contains(strings.ToLower(apiErr.Message), "loading") {
+		return apiErr, 0
+	}
+
+	var wait = min(time.Duration(legacy.EstimatedTime*float64(time.Second)), hfMaxLoadingWait)
+	if wait <= 0 {
+		wait = hfDefaultLoadingWait
+	}
+
+	apiErr.Message = fmt.Sprintf(
+		"the model is loading (estimated time: %s), try again later or enable waiting for the model",
+		wait.Round(time.Second),
+	)
+
+	return apiErr, wait
+}
+
+// parseResponse parses the response from the Hugging Face API. Each choice is returned as a separate answer.
+func (p *HuggingFace) parseResponse(resp *http.Response) (*completion, error) {
+	var answer struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+
+	if dErr := json.NewDecoder(resp.Body).Decode(&answer); dErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, dErr)
+	}
+
+	if len(answer.Choices) == 0 || len(answer.Choices[0].Message.Content) == 0 {
+		return nil, errors.New("no content found")
+	}
+
+	var texts = make([]string, 0, len(answer.Choices))
+
+	for _, choice := range answer.Choices {
+		if text := finalizeAnswer(choice.Message.Content); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	if len(texts) == 0 {
+		return nil, errors.New("no content found")
+	}
+
+	return &completion{
+		Answers: texts,
+		Usage: Usage{
+			PromptTokens:     answer.Usage.PromptTokens,
+			CompletionTokens: answer.Usage.CompletionTokens,
+			TotalTokens:      answer.Usage.TotalTokens,
+		},
+		Truncated: answer.Choices[0].FinishReason == finishReasonLength,
+	}, nil
+}
@@ -0,0 +1,119 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestHuggingFace_Query(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+		assertEqual(t, req.URL.String(), "https://router.huggingface.co/v1/chat/completions")
+		assertEqual(t, req.Header.Get("Authorization"), "Bearer hf_token")
+
+		return newHttpResponse(http.StatusOK, `{
+			"choices":[{"message":{"content":"\n feat: Add foo\n"}},{"message":{"content":"fix: Fix bar"}}],
+			"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}
+		}`), nil
+	}}
+
+	resp, err := ai.NewHuggingFace("hf_token", "meta-llama/Llama-3.1-8B-Instruct",
+		ai.WithHuggingFaceHttpClient(&client),
+	).Query(context.Background(), "diff", "log", ai.WithCandidates(2))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, len(resp.Alternatives), 1)
+	assertEqual(t, resp.Alternatives[0], "fix: Fix bar")
+	assertEqual(t, resp.Usage, ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+
+	var body = client.Requests()[0]
+
+	for _, want := range []string{`"model":"meta-llama/Llama-3.1-8B-Instruct"`, `"n":2`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q to contain %q", body, want)
+		}
+	}
+}
+
+func TestHuggingFace_ColdStart(t *testing.T) {
+	t.Parallel()
+
+	const loading = `{"error":"Model meta-llama/Llama-3.1-8B-Instruct is currently loading","estimated_time":0.01}`
+
+	var newClient = func(failures int) *fakeHttpClient {
+		return &fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+			if n < failures {
+				return newHttpResponse(http.StatusServiceUnavailable, loading), nil
+			}
+
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+		}}
+	}
+
+	t.Run("clear error by default", func(t *testing.T) {
+		t.Parallel()
+
+		var client = newClient(1)
+
+		_, err := ai.NewHuggingFace("", "", ai.WithHuggingFaceHttpClient(client)).
+			Query(context.Background(), "diff", "log")
+
+		if !errors.Is(err, ai.ErrProviderUnavailable) || !strings.Contains(err.Error(), "the model is loading") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		assertEqual(t, len(client.Requests()), 1)
+	})
+
+	t.Run("wait for the model", func(t *testing.T) {
+		t.Parallel()
+
+		var client = newClient(2)
+
+		resp, err := ai.NewHuggingFace("", "", ai.WithHuggingFaceHttpClient(client), ai.WithWaitForModel(true)).
+			Query(context.Background(), "diff", "log")
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+		assertEqual(t, len(client.Requests()), 3)
+	})
+
+	t.Run("waiting is limited", func(t *testing.T) {
+		t.Parallel()
+
+		var client = newClient(100)
+
+		_, err := ai.NewHuggingFace("", "", ai.WithHuggingFaceHttpClient(client), ai.WithWaitForModel(true)).
+			Query(context.Background(), "diff", "log")
+
+		if !errors.Is(err, ai.ErrProviderUnavailable) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		assertEqual(t, len(client.Requests()), 4)
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusUnauthorized, `{"error":"Invalid credentials in Authorization header"}`), nil
+		}}
+
+		_, err := ai.NewHuggingFace("", "", ai.WithHuggingFaceHttpClient(client), ai.WithWaitForModel(true)).
+			Query(context.Background(), "diff", "log")
+
+		if !errors.Is(err, ai.ErrUnauthorized) || !strings.Contains(err.Error(), "Invalid credentials") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		assertEqual(t, len(client.Requests()), 1)
+	})
+}
@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"context"
+	"slices"
+)
+
+// QueryInput is the input of the [Provider.Query] call. It's attached to the context of the query, so the
+// decorators (caching, logging, metrics, etc.) wrapping the provider or its HTTP client can inspect the inputs
+// without passing them separately (see [QueryInputFromContext]).
+type QueryInput struct {
+	Changes, Commits string
+	Opts             []Option
+}
+
+// Key returns the hash of the changes, the commits, and the options affecting the output (see [GenerationKey]),
+// suitable for the cache keys. False is returned if the options can't be compared (e.g. [WithPostProcess] or [WithStream] is used).
+func (in QueryInput) Key() (string, bool) { return queryKey(in.Changes, in.Commits, in.Opts...) }
+
+// queryInputKey is the context key of the [QueryInput].
+type queryInputKey struct{}
+
+// ContextWithQueryInput returns the copy of the context carrying the query input.
+func ContextWithQueryInput(ctx context.Context, in QueryInput) context.Context {
+	in.Opts = slices.Clone(in.Opts) // the caller may reuse the slice
+
+	return context.WithValue(ctx, queryInputKey{}, in)
+}
+
+// QueryInputFromContext returns the query input attached to the context. The input is attached by [QueryWithInput]
+// and by every provider, so it's available in the context of the HTTP requests too.
+func QueryInputFromContext(ctx context.Context) (QueryInput, bool) {
+	in, ok := ctx.Value(queryInputKey{}).(QueryInput)
+
+	return in, ok
+}
+
+// QueryWithInput queries the provider (or the chain of decorators) using the input attached to the context.
+func QueryWithInput(ctx context.Context, p Provider, in QueryInput) (*Response, error) {
+	return p.Query(ContextWithQueryInput(ctx, in), in.Changes, in.Commits, in.Opts...)
+}
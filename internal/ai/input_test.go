@@ -0,0 +1,131 @@
+package ai_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+// cachingProvider is a decorator caching the responses by the query input taken from the context.
+type cachingProvider struct {
+	p ai.Provider
+
+	mu    sync.Mutex
+	cache map[string]*ai.Response
+}
+
+func (c *cachingProvider) Name() string { return c.p.Name() }
+
+func (c *cachingProvider) Query(ctx context.Context, changes, commits string, opts ...ai.Option) (*ai.Response, error) {
+	in, ok := ai.QueryInputFromContext(ctx)
+	if !ok {
+		return c.p.Query(ctx, changes, commits, opts...)
+	}
+
+	key, ok := in.Key()
+	if !ok {
+		return c.p.Query(ctx, changes, commits, opts...)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if resp, hit := c.cache[key]; hit {
+		return resp, nil
+	}
+
+	resp, err := c.p.Query(ctx, changes, commits, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache[key] = resp
+
+	return resp, nil
+}
+
+func TestQueryWithInput_CachingDecorator(t *testing.T) {
+	t.Parallel()
+
+	var (
+		client   = okClient("feat: Add foo")
+		provider = &cachingProvider{
+			p:     ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)),
+			cache: make(map[string]*ai.Response),
+		}
+		ctx = context.Background()
+	)
+
+	for _, in := range []ai.QueryInput{
+		{Changes: "diff", Commits: "log"},
+		{Changes: "diff", Commits: "log"},                                        // cached
+		{Changes: "diff", Commits: "log", Opts: []ai.Option{ai.WithEmoji(true)}}, // the options differ
+		{Changes: "diff", Commits: "log", Opts: []ai.Option{ai.WithEmoji(true)}}, // cached
+		{Changes: "other diff", Commits: "log"},
+		{ // can't be cached
+			Changes: "diff",
+			Commits: "log",
+			Opts:    []ai.Option{ai.WithPostProcess(func(s string) string { return s })},
+		},
+	} {
+		resp, err := ai.QueryWithInput(ctx, provider, in)
+		assertNoError(t, err)
+		assertEqual(t, resp.Answer, "feat: Add foo")
+	}
+
+	assertEqual(t, len(client.Requests()), 4)
+}
+
+func TestQueryInputFromContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty context", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok := ai.QueryInputFromContext(context.Background())
+		assertEqual(t, ok, false)
+	})
+
+	t.Run("attached by the provider", func(t *testing.T) {
+		t.Parallel()
+
+		var got ai.QueryInput
+
+		var client = &fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+			got, _ = ai.QueryInputFromContext(req.Context()) // e.g. for the HTTP-level metrics
+
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+		}}
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithEmoji(true))
+		assertNoError(t, err)
+
+		assertEqual(t, got.Changes, "diff")
+		assertEqual(t, got.Commits, "log")
+		assertEqual(t, len(got.Opts), 1)
+	})
+
+	t.Run("attached by the caller", func(t *testing.T) {
+		t.Parallel()
+
+		var got ai.QueryInput
+
+		var client = &fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+			got, _ = ai.QueryInputFromContext(req.Context())
+
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+		}}
+
+		_, err := ai.QueryWithInput(context.Background(), ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)),
+			ai.QueryInput{Changes: "diff", Commits: "log"},
+		)
+		assertNoError(t, err)
+
+		assertEqual(t, got.Changes, "diff")
+		assertEqual(t, len(got.Opts), 0)
+	})
+}
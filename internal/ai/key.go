@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// generationKey is the comparable set of the options affecting the output, on top of the ones affecting the prompt.
+// Every option changing the output must be added here (or to the [promptKey]), while the ones affecting the delivery
+// only (the retries, the time budget, the User-Agent, the dry run, etc.) must not.
+type generationKey struct {
+	Prompt promptKey
+
+	MaxOutputTokens                             int64
+	Candidates, MaxBytesPerFile, MaxDiffBytes   int
+	MaxLineLength, MaxHunks, MaxBodyLines       int
+	RecentCommits                               int
+	UniqueCandidates, BlameContext, KeepIndex   bool
+	PlanThenWrite, ForceDirScope, AdditionsOnly bool
+	CommitExamples, FileTypeSummary, DepsAware  bool
+	ModelTrailer, LaxSubject, BinaryMetadata    bool
+	AvoidReverted, TestOnlyType, RepoName       bool
+	Commitlint, FixInvalidStream                bool
+
+	OverflowModel, TrailerFormat, MimicRepoDir, FixupTarget string
+	Temperature, TopP                                       string // formatted, so the NaN is supported
+	Tokenizer                                               string // the type of the custom tokenizer
+	StopSequences, ExtraContext                             string // joined with NUL
+}
+
+// newGenerationKey returns the generation key of the options (the defaults are resolved).
+func newGenerationKey(o options) generationKey {
+	var extra = make([]string, 0, len(o.extraContext)*2) //nolint:mnd
+
+	for _, block := range o.extraContext {
+		extra = append(extra, block.Title, block.Text)
+	}
+
+	return generationKey{
+		Prompt:           newPromptKey(o),
+		MaxOutputTokens:  cmp.Or(o.MaxOutputTokens, defaultMaxOutputTokens),
+		Candidates:       max(o.Candidates, 1),
+		MaxBytesPerFile:  o.MaxBytesPerFile,
+		MaxDiffBytes:     o.MaxDiffBytes,
+		MaxLineLength:    o.MaxLineLength,
+		MaxHunks:         o.MaxHunks,
+		MaxBodyLines:     o.MaxBodyLines,
+		RecentCommits:    o.RecentCommits,
+		UniqueCandidates: o.UniqueCandidates,
+		BlameContext:     o.BlameContext,
+		KeepIndex:        o.KeepIndexLines,
+		PlanThenWrite:    o.PlanThenWrite,
+		ForceDirScope:    o.ForceDirScope,
+		AdditionsOnly:    o.AdditionsOnly,
+		CommitExamples:   o.CommitExamples,
+		FileTypeSummary:  o.FileTypeSummary,
+		DepsAware:        o.DepsAware,
+		ModelTrailer:     o.ModelTrailer,
+		LaxSubject:       o.LaxSubject,
+		BinaryMetadata:   o.BinaryMetadata,
+		AvoidReverted:    o.AvoidReverted,
+		TestOnlyType:     o.TestOnlyType,
+		RepoName:         o.RepoNameContext,
+		Commitlint:       o.Commitlint,
+		FixInvalidStream: o.FixInvalidStream,
+		OverflowModel:    o.OverflowModel,
+		TrailerFormat:    o.TrailerFormat,
+		MimicRepoDir:     o.MimicRepoDir,
+		FixupTarget:      o.FixupTarget,
+		Temperature:      strconv.FormatFloat(o.temperature(), 'g', -1, 64),
+		TopP:             strconv.FormatFloat(o.topP(), 'g', -1, 64),
+		Tokenizer:        fmt.Sprintf("%T", o.Tokenizer),
+		StopSequences:    strings.Join(o.StopSequences, "\x00"),
+		ExtraContext:     strings.Join(extra, "\x00"),
+	}
+}
+
+// GenerationKey returns the stable key (the hex SHA-256) of the generation: the model, the changes, the commits,
+// and the options affecting the output (the emoji, the message length, the convention, the language, the sampling
+// parameters, etc.). The options affecting the delivery only (the retries, the time budget, the User-Agent, the
+// dry run, etc.) are not included, and the line endings of the inputs are normalized. Use it for the cache and
+// idempotency keys.
+//
+// The functions (like [WithPostProcess] or [WithStream]) can't be compared, so they are not included either.
+func GenerationKey(model, changes, commits string, opts ...Option) string {
+	var (
+		o = options{}.Apply(opts...)
+		h = sha256.New()
+	)
+
+	for _, part := range []string{
+		o.modelOr(model),
+		normalizeNewlines(changes),
+		normalizeNewlines(commits),
+		fmt.Sprintf("%#v", newGenerationKey(o)), // the key consists of the strings, numbers, and booleans only
+	} {
+		_, _ = fmt.Fprintf(h, "%d:%s;", len(part), part) // the length prefix avoids ambiguity between the parts
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeNewlines replaces the Windows (CRLF) line endings with the Unix (LF) ones.
+func normalizeNewlines(s string) string { return strings.ReplaceAll(s, "\r\n", "\n") }
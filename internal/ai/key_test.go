@@ -0,0 +1,76 @@
+package ai_test
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestGenerationKey(t *testing.T) {
+	t.Parallel()
+
+	var (
+		base = []ai.Option{ai.WithEmoji(true), ai.WithLanguage("de")}
+		with = func(opts ...ai.Option) []ai.Option { return append(slices.Clone(base), opts...) }
+		key  = ai.GenerationKey("model", "diff", "log", base...)
+	)
+
+	assertEqual(t, regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString(key), true, "hex SHA-256")
+
+	t.Run("stable", func(t *testing.T) {
+		t.Parallel()
+
+		for name, opts := range map[string][]ai.Option{
+			"same":               base,
+			"options order":      {ai.WithLanguage("de"), ai.WithEmoji(true)},
+			"overridden option":  {ai.WithEmoji(false), ai.WithEmoji(true), ai.WithLanguage("de")},
+			"retries":            with(ai.WithRetries(3, time.Second)),
+			"budget":             with(ai.WithTotalBudget(time.Minute)),
+			"user agent":         with(ai.WithUserAgent("my-tool/1.0")),
+			"audit log":          with(ai.WithAuditLog(&bytes.Buffer{}), ai.WithAuditLogBody(true)),
+			"default sampling":   with(ai.WithTemperature(0.1), ai.WithTopP(0.1)),
+			"default max tokens": with(ai.WithMaxOutputTokens(500)),
+			"dry run":            with(ai.WithDryRun()),
+			"git dir":            with(ai.WithGitDir("/path/to/repo")),
+			"infinite cost":      with(ai.WithMaxCandidateCost(math.Inf(1))),
+			"NaN cost":           with(ai.WithMaxCandidateCost(math.NaN())),
+		} {
+			assertEqual(t, ai.GenerationKey("model", "diff", "log", opts...), key, name)
+		}
+
+		assertEqual(t, ai.GenerationKey("model", "a\r\nb", "c\r\n"), ai.GenerationKey("model", "a\nb", "c\n"), "CRLF")
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		t.Parallel()
+
+		var seen = map[string]string{key: "base"}
+
+		for name, got := range map[string]string{
+			"model":       ai.GenerationKey("other", "diff", "log", base...),
+			"changes":     ai.GenerationKey("model", "other", "log", base...),
+			"commits":     ai.GenerationKey("model", "diff", "other", base...),
+			"parts":       ai.GenerationKey("model", "difflog", "", base...),
+			"emoji":       ai.GenerationKey("model", "diff", "log", ai.WithLanguage("de")),
+			"language":    ai.GenerationKey("model", "diff", "log", ai.WithEmoji(true), ai.WithLanguage("fr")),
+			"short":       ai.GenerationKey("model", "diff", "log", with(ai.WithShortMessageOnly(true))...),
+			"format":      ai.GenerationKey("model", "diff", "log", with(ai.WithOutputFormat(ai.FormatGitNote))...),
+			"temperature": ai.GenerationKey("model", "diff", "log", with(ai.WithTemperature(0.7))...),
+			"top_p":       ai.GenerationKey("model", "diff", "log", with(ai.WithTopP(0.5))...),
+			"max tokens":  ai.GenerationKey("model", "diff", "log", with(ai.WithMaxOutputTokens(100))...),
+			"tokenizer":   ai.GenerationKey("model", "diff", "log", with(ai.WithTokenizer(&countingTokenizer{}))...),
+			"NaN top_p":   ai.GenerationKey("model", "diff", "log", with(ai.WithTopP(math.NaN()))...),
+		} {
+			if prev, dup := seen[got]; dup {
+				t.Errorf("%s: the key is the same as for %s", name, prev)
+			}
+
+			seen[got] = name
+		}
+	})
+}
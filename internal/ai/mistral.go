@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Mistral is a provider for the Mistral AI API (https://docs.mistral.ai/api/).
+type Mistral struct {
+	httpClient        httpClient
+	apiKey, modelName string
+}
+
+var _ StreamingProvider = (*Mistral)(nil) // ensure the interface is implemented
+
+type (
+	mistralOptions struct {
+		HttpClient httpClient
+		Timeout    time.Duration
+	}
+
+	// MistralOption allows to customize the Mistral provider.
+	MistralOption func(*mistralOptions)
+)
+
+// MistralDefaultModel is the model used by the Mistral provider if no model is set. It's the fast and cheap one,
+// which is good enough for the commit messages.
+const MistralDefaultModel = "mistral-small-latest"
+
+// WithMistralHttpClient sets the HTTP client for the Mistral provider.
+func WithMistralHttpClient(c httpClient) MistralOption {
+	return func(o *mistralOptions) { o.HttpClient = c }
+}
+
+// WithMistralTimeout sets the timeout of the default HTTP client (60 seconds by default). It has no effect if the
+// custom HTTP client is set (see [WithMistralHttpClient]).
+func WithMistralTimeout(d time.Duration) MistralOption {
+	return func(o *mistralOptions) { o.Timeout = d }
+}
+
+// NewMistral creates a new Mistral provider. If the model is empty, [MistralDefaultModel] is used.
+func NewMistral(apiKey, model string, opt ...MistralOption) *Mistral {
+	var opts = mistralOptions{Timeout: defaultTimeout}
+
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	if model == "" {
+		model = MistralDefaultModel
+	}
+
+	var p = Mistral{
+		httpClient: opts.HttpClient,
+		apiKey:     apiKey,
+		modelName:  model,
+	}
+
+	if p.httpClient == nil { // set default HTTP client
+		p.httpClient = &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: &http.Transport{ForceAttemptHTTP2: true}, // use HTTP/2 (why not?)
+		}
+	}
+
+	return &p
+}
+
+// Query implements the [Provider] interface.
+func (p *Mistral) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
+
+// QueryStream implements the [StreamingProvider] interface.
+func (p *Mistral) QueryStream(
+	ctx context.Context,
+	changes, commits string,
+	opts ...Option,
+) (<-chan string, <-chan error) {
+	return queryStream(ctx, p, changes, commits, opts...)
+}
+
+// Name implements the [Provider] interface.
+func (*Mistral) Name() string { return ProviderMistral }
+
+// model returns the model name.
+func (p *Mistral) model() string { return p.modelName }
+
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (*Mistral) batchesCandidates() bool { return true }
+
+// complete performs a single request to the Mistral API.
+func (p *Mistral) complete(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
+	req, rErr := p.newRequest(ctx, instructions, changes, commits, opt)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	resp, rErr := doRequest("Mistral", p.httpClient, req)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.responseToError(resp)
+	}
+
+	if opt.streaming() {
+		return readChatStream("Mistral", resp.Body, opt.Stream)
+	}
+
+	return p.parseResponse(resp)
+}
+
+// newRequest creates a new HTTP request for the Mistral API.
+func (p *Mistral) newRequest(
+	ctx context.Context,
+	instructions, changes, commits string,
+	o options,
+) (*http.Request, error) {
+	// https://docs.mistral.ai/api/#tag/chat/operation/chat_completion_v1_chat_completions_post
+	j, jErr := json.Marshal(struct {
+		Model          string          `json:"model"`
+		Messages       []chatMessage   `json:"messages"`
+		Temperature    float64         `json:"temperature"`
+		TopP           float64         `json:"top_p"`
+		HowMany        int             `json:"n"` // How many chat completion choices to generate for each input message
+		MaxTokens      int64           `json:"max_tokens"`
+		ResponseFormat *responseFormat `json:"response_format,omitempty"`
+		Stream         bool            `json:"stream,omitempty"` // the usage is sent with the last chunk
+	}{
+		Model:          o.modelOr(p.modelName),
+		Temperature:    o.temperature(),
+		TopP:           o.topP(),
+		HowMany:        o.Candidates,
+		MaxTokens:      o.MaxOutputTokens,
+		ResponseFormat: jsonResponseFormat(o),
+		Stream:         o.streaming(),
+		Messages:       chatMessages(instructions, changes, commits, o),
+	})
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	req, rErr := http.NewRequestWithContext(ctx,
+		http.MethodPost,
+		"https://api.mistral.ai/v1/chat/completions",
+		bytes.NewReader(j),
+	)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.userAgent())
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
+
+	return req, nil
+}
+
+// responseToError converts the response from the Mistral API to an error. Unlike the other providers, Mistral
+// responds with the top-level message (e.g. `{"object": "error", "message": "...", "code": "3051"}`), which may be
+// an object with the validation details.
+func (p *Mistral) responseToError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxDrainBytes))
+
+	var apiErr = newAPIError("Mistral", &http.Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	})
+
+	var top struct {
+		Message json.RawMessage `json:"message"`
+		Code    json.RawMessage `json:"code"`
+	}
+
+	if err := json.Unmarshal(body, &top); err == nil && apiErr.Message == "" && len(top.Message) > 0 {
+		if uErr := json.Unmarshal(top.Message, &apiErr.Message); uErr != nil {
+			apiErr.Message = string(top.Message) // the validation details object
+		}
+
+		apiErr.Code = (&apiErrorDetails{Code: top.Code}).code()
+	}
+
+	return apiErr
+}
+
+// parseResponse parses the response from the Mistral API. Each choice is returned as a separate answer.
+func (p *Mistral) parseResponse(resp *http.Response) (*completion, error) {
+	var answer struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+
+	if dErr := json.NewDecoder(resp.Body).Decode(&answer); dErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, dErr)
+	}
+
+	var texts = make([]string, 0, len(answer.Choices))
+
+	for _, choice := range answer.Choices {
+		if text := finalizeAnswer(choice.Message.Content); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	if len(texts) == 0 {
+		return nil, errors.New("no response from the Mistral API")
+	}
+
+	return &completion{
+		Answers: texts,
+		Usage: Usage{
+			PromptTokens:     answer.Usage.PromptTokens,
+			CompletionTokens: answer.Usage.CompletionTokens,
+			TotalTokens:      answer.Usage.TotalTokens,
+		},
+		Truncated: answer.Choices[0].FinishReason == finishReasonLength,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestMistral_Query(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+		assertEqual(t, req.URL.String(), "https://api.mistral.ai/v1/chat/completions")
+		assertEqual(t, req.Header.Get("Authorization"), "Bearer mistral-key")
+
+		return newHttpResponse(http.StatusOK, `{
+			"id":"cmpl-1","object":"chat.completion","model":"mistral-small-latest",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"feat: Add foo\n\n- Bar"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}
+		}`), nil
+	}}
+
+	resp, err := ai.NewMistral("mistral-key", "", ai.WithMistralHttpClient(&client)).
+		Query(context.Background(), "diff", "log", ai.WithTemperature(0.5), ai.WithTopP(0.9))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo\n\n- Bar")
+	assertEqual(t, resp.Provider, ai.ProviderMistral)
+	assertEqual(t, resp.Model, ai.MistralDefaultModel)
+	assertEqual(t, resp.Usage, ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+
+	var req struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		Temperature float64 `json:"temperature"`
+		TopP        float64 `json:"top_p"`
+		MaxTokens   int     `json:"max_tokens"`
+	}
+
+	assertNoError(t, json.Unmarshal([]byte(client.Requests()[0]), &req))
+
+	assertEqual(t, req.Model, "mistral-small-latest")
+	assertEqual(t, req.Messages[0].Role, "system")
+	assertEqual(t, req.Messages[0].Content, resp.Prompt)
+	assertEqual(t, req.Messages[1].Role, "user")
+	assertEqual(t, req.Temperature, 0.5)
+	assertEqual(t, req.TopP, 0.9)
+	assertEqual(t, req.MaxTokens, 500)
+}
+
+func TestMistral_ShortMessageOnly(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo\n\n- Bar"}}]}`), nil
+	}}
+
+	resp, err := ai.NewMistral("", "mistral-large-latest", ai.WithMistralHttpClient(&client)).
+		Query(context.Background(), "diff", "log", ai.WithShortMessageOnly(true))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, strings.Contains(client.Requests()[0], `"model":"mistral-large-latest"`), true, "model")
+}
+
+func TestMistral_Stream(t *testing.T) {
+	t.Parallel()
+
+	var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusOK, sseBody("feat: ", "Add foo")), nil
+	}}
+
+	var deltas []string
+
+	resp, err := ai.NewMistral("", "", ai.WithMistralHttpClient(client)).Query(context.Background(), "diff", "log",
+		ai.WithStream(func(delta string) { deltas = append(deltas, delta) }),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, strings.Join(deltas, "|"), "feat: |Add foo")
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, strings.Contains(client.Requests()[0], `"stream":true`), true, "stream")
+	assertEqual(t, strings.Contains(client.Requests()[0], `stream_options`), false, "stream options")
+}
+
+func TestMistral_Error(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveCode    int
+		giveBody    string
+		wantMessage string
+		wantErr     error
+	}{
+		"unauthorized": {
+			giveCode:    http.StatusUnauthorized,
+			giveBody:    `{"message":"Unauthorized","request_id":"0c2f8f5e"}`,
+			wantMessage: "Unauthorized",
+			wantErr:     ai.ErrUnauthorized,
+		},
+		"too long": {
+			giveCode: http.StatusBadRequest,
+			giveBody: `{"object":"error","message":"Prompt contains 40000 tokens, too large for model with 32768 ` +
+				`maximum context length","type":"invalid_request_error","param":null,"code":"3051"}`,
+			wantMessage: "Prompt contains 40000 tokens, too large for model with 32768 maximum context length",
+			wantErr:     ai.ErrContextTooLong,
+		},
+		"validation": {
+			giveCode: http.StatusUnprocessableEntity,
+			giveBody: `{"object":"error","message":{"detail":[{"msg":"Input should be a valid number"}]},` +
+				`"type":"invalid_request_message_error"}`,
+			wantMessage: `{"detail":[{"msg":"Input should be a valid number"}]}`,
+			wantErr:     ai.ErrBadRequest,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return newHttpResponse(tc.giveCode, tc.giveBody), nil
+			}}
+
+			_, err := ai.NewMistral("", "", ai.WithMistralHttpClient(&client)).
+				Query(context.Background(), "diff", "log")
+
+			var apiErr *ai.APIError
+
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected the API error, got %v", err)
+			}
+
+			assertEqual(t, apiErr.Provider, "Mistral")
+			assertEqual(t, apiErr.Message, tc.wantMessage)
+			assertEqual(t, errors.Is(err, tc.wantErr), true, tc.wantErr.Error())
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Ollama is a provider for the local models served by Ollama (https://ollama.com), so the changes never leave the
+// machine (or the network).
+type Ollama struct {
+	httpClient         httpClient
+	baseURL, modelName string
+}
+
+var _ Provider = (*Ollama)(nil) // ensure the interface is implemented
+
+type (
+	ollamaOptions struct {
+		HttpClient httpClient
+		Timeout    time.Duration
+	}
+
+	// OllamaOption allows to customize the Ollama provider.
+	OllamaOption func(*ollamaOptions)
+)
+
+const (
+	// OllamaDefaultBaseURL is the address the Ollama server listens on by default.
+	OllamaDefaultBaseURL = "http://localhost:11434"
+
+	// ollamaDefaultTimeout is much higher than the one of the remote providers, since the local models can be slow
+	// (especially on the first request, when the model is loaded into the memory).
+	ollamaDefaultTimeout = 5 * time.Minute
+)
+
+// WithOllamaHttpClient sets the HTTP client for the Ollama provider.
+func WithOllamaHttpClient(c httpClient) OllamaOption {
+	return func(o *ollamaOptions) { o.HttpClient = c }
+}
+
+// WithOllamaTimeout sets the timeout of the default HTTP client (5 minutes by default). It has no effect if the
+// custom HTTP client is set.
+func WithOllamaTimeout(d time.Duration) OllamaOption {
+	return func(o *ollamaOptions) { o.Timeout = d }
+}
+
+// NewOllama creates a new Ollama provider. If the base URL is empty, [OllamaDefaultBaseURL] is used.
+func NewOllama(baseURL, model string, opt ...OllamaOption) *Ollama {
+	var opts = ollamaOptions{Timeout: ollamaDefaultTimeout}
+
+	for _, o := range opt {
+		o(&opts)
+	}
+
+	if baseURL == "" {
+		baseURL = OllamaDefaultBaseURL
+	}
+
+	var p = Ollama{
+		httpClient: opts.HttpClient,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		modelName:  model,
+	}
+
+	if p.httpClient == nil { // set default HTTP client
+		p.httpClient = &http.Client{Timeout: opts.Timeout}
+	}
+
+	return &p
+}
+
+// Query implements the [Provider] interface.
+func (p *Ollama) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
+
+// Name implements the [Provider] interface.
+func (*Ollama) Name() string { return ProviderOllama }
+
+// model returns the model name.
+func (p *Ollama) model() string { return p.modelName }
+
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (*Ollama) batchesCandidates() bool { return false }
+
+// complete performs the requests to the Ollama API. The chat API generates a single answer, so a separate request
+// is made for every candidate.
+func (p *Ollama) complete(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
+	var result completion
+
+	for range max(opt.Candidates, 1) {
+		res, err := p.completeOne(ctx, instructions, changes, commits, opt)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(result.Answers) == 0 {
+			result.Truncated = res.Truncated // the first answer comes from the first request
+		}
+
+		result.Answers = append(result.Answers, res.Answers...)
+		result.Usage = result.Usage.add(res.Usage)
+	}
+
+	return &result, nil
+}
+
+// completeOne performs a single request to the Ollama API.
+func (p *Ollama) completeOne(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
+	req, rErr := p.newRequest(ctx, instructions, changes, commits, opt)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	resp, rErr := doRequest("Ollama", p.httpClient, req)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	defer drainAndClose(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, p.responseToError(resp)
+	}
+
+	return p.parseResponse(resp)
+}
+
+// newRequest creates a new HTTP request for the Ollama API.
+func (p *Ollama) newRequest(
+	ctx context.Context,
+	instructions, changes, commits string,
+	o options,
+) (*http.Request, error) {
+	type modelOptions struct { // https://github.com/ollama/ollama/blob/main/docs/modelfile.md#valid-parameters-and-values
+		Temperature float64 `json:"temperature"`
+		TopP        float64 `json:"top_p"`
+		NumPredict  int64   `json:"num_predict"` // the max number of the output tokens
+	}
+
+	var format string
+
+	if o.jsonOutput() {
+		format = "json"
+	}
+
+	// https://github.com/ollama/ollama/blob/main/docs/api.md#generate-a-chat-completion
+	j, jErr := json.Marshal(struct {
+		Model    string        `json:"model"`
+		Messages []chatMessage `json:"messages"`
+		Stream   bool          `json:"stream"`
+		Format   string        `json:"format,omitempty"`
+		Options  modelOptions  `json:"options"`
+	}{
+		Model:    o.modelOr(p.modelName),
+		Messages: chatMessages(instructions, changes, commits, o),
+		Format:   format,
+		Options: modelOptions{
+			Temperature: o.temperature(),
+			TopP:        o.topP(),
+			NumPredict:  o.MaxOutputTokens,
+		},
+	})
+	if jErr != nil {
+		return nil, jErr
+	}
+
+	req, rErr := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(j))
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.userAgent())
+
+	return req, nil
+}
+
+// responseToError converts the response from the Ollama API to an error. Unlike the other providers, Ollama
+// responds with the error string (e.g. `{"error": "model \"foo\" not found, try pulling it first"}`).
+func (p *Ollama) responseToError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxDrainBytes))
+
+	var apiErr = newAPIError("Ollama", &http.Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	})
+
+	var legacy struct {
+		Error string `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &legacy); err == nil && apiErr.Message == "" {
+		apiErr.Message = legacy.Error
+	}
+
+	return apiErr
+}
+
+// parseResponse parses the response from the Ollama API.
+func (p *Ollama) parseResponse(resp *http.Response) (*completion, error) {
+	var answer struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		DoneReason      string `json:"done_reason"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+	}
+
+	if dErr := json.NewDecoder(resp.Body).Decode(&answer); dErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, dErr)
+	}
+
+	var text = finalizeAnswer(answer.Message.Content)
+	if text == "" {
+		return nil, errors.New("no content found")
+	}
+
+	return &completion{
+		Answers: []string{text},
+		Usage: Usage{
+			PromptTokens:     answer.PromptEvalCount,
+			CompletionTokens: answer.EvalCount,
+			TotalTokens:      answer.PromptEvalCount + answer.EvalCount,
+		},
+		Truncated: answer.DoneReason == finishReasonLength,
+	}, nil
+}
@@ -0,0 +1,90 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestOllama_Query(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+		assertEqual(t, req.URL.String(), "http://localhost:11434/api/chat")
+
+		return newHttpResponse(http.StatusOK, `{
+			"model":"llama3.2",
+			"message":{"role":"assistant","content":"feat: Add foo\n\n- Add bar\n"},
+			"done":true,
+			"prompt_eval_count":10,
+			"eval_count":5
+		}`), nil
+	}}
+
+	resp, err := ai.NewOllama("", "llama3.2", ai.WithOllamaHttpClient(&client)).
+		Query(context.Background(), "diff", "log", ai.WithMaxOutputTokens(321))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo\n\n- Add bar")
+	assertEqual(t, resp.Usage, ai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15})
+
+	var req struct {
+		Model    string `json:"model"`
+		Stream   *bool  `json:"stream"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		Options struct {
+			NumPredict int `json:"num_predict"`
+		} `json:"options"`
+	}
+
+	assertNoError(t, json.Unmarshal([]byte(client.Requests()[0]), &req))
+
+	assertEqual(t, req.Model, "llama3.2")
+	assertEqual(t, req.Stream != nil && !*req.Stream, true, "stream disabled")
+	assertEqual(t, req.Options.NumPredict, 321)
+	assertEqual(t, req.Messages[0].Role, "system")
+	assertEqual(t, req.Messages[0].Content, resp.Prompt)
+	assertEqual(t, len(req.Messages), 3)
+}
+
+func TestOllama_BaseURLAndShortMessage(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+		assertEqual(t, req.URL.String(), "http://gpu-box:8080/ollama/api/chat")
+
+		return newHttpResponse(http.StatusOK, `{"message":{"content":"fix: Fix foo\n\n- Fix bar"}}`), nil
+	}}
+
+	resp, err := ai.NewOllama("http://gpu-box:8080/ollama/", "", ai.WithOllamaHttpClient(&client)).
+		Query(context.Background(), "diff", "log", ai.WithShortMessageOnly(true))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "fix: Fix foo")
+}
+
+func TestOllama_Error(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusNotFound, `{"error":"model \"foo\" not found, try pulling it first"}`), nil
+	}}
+
+	_, err := ai.NewOllama("", "foo", ai.WithOllamaHttpClient(&client)).Query(context.Background(), "diff", "log")
+
+	var apiErr *ai.APIError
+
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+
+	assertEqual(t, apiErr.Message, `model "foo" not found, try pulling it first`)
+	assertEqual(t, errors.Is(err, ai.ErrBadRequest), true, "bad request")
+}
@@ -12,15 +12,21 @@ import (
 )
 
 type OpenAI struct {
-	httpClient        httpClient
-	apiKey, modelName string
+	httpClient                       httpClient
+	apiKey, modelName                string
+	promptCacheKey, safetyIdentifier string
+	baseURL                          string
 }
 
-var _ Provider = (*OpenAI)(nil)
+var _ StreamingProvider = (*OpenAI)(nil)
 
 type (
 	openaiOptions struct {
-		HttpClient httpClient
+		HttpClient       httpClient
+		PromptCacheKey   string
+		SafetyIdentifier string
+		BaseURL          string
+		Timeout          time.Duration
 	}
 
 	// OpenAIOption allows to customize the OpenAI provider.
@@ -32,23 +38,57 @@ func WithOpenAIHttpClient(c httpClient) OpenAIOption {
 	return func(o *openaiOptions) { o.HttpClient = c }
 }
 
+// WithOpenAITimeout sets the timeout of the default HTTP client (60 seconds by default). It has no effect if the
+// custom HTTP client is set (see [WithOpenAIHttpClient]).
+func WithOpenAITimeout(d time.Duration) OpenAIOption {
+	return func(o *openaiOptions) { o.Timeout = d }
+}
+
+// WithOpenAIPromptCacheKey sets the key used by OpenAI to route the requests with the same (stable) prompt to the
+// same cache, improving latency and cost.
+func WithOpenAIPromptCacheKey(key string) OpenAIOption {
+	return func(o *openaiOptions) { o.PromptCacheKey = key }
+}
+
+// WithOpenAISafetyIdentifier sets the stable identifier of the end user, used by OpenAI to detect policy violations.
+func WithOpenAISafetyIdentifier(id string) OpenAIOption {
+	return func(o *openaiOptions) { o.SafetyIdentifier = id }
+}
+
+// OpenAIDefaultBaseURL is the base URL of the public OpenAI API.
+const OpenAIDefaultBaseURL = "https://api.openai.com/v1"
+
+// WithOpenAIBaseURL sets the base URL of the API (e.g. `https://llm-gateway.example.com/v1`), so the provider can be
+// used with any OpenAI-compatible gateway or server (LiteLLM, vLLM, Azure, etc.). The `/chat/completions` path is
+// appended to it. [OpenAIDefaultBaseURL] is used by default.
+func WithOpenAIBaseURL(u string) OpenAIOption {
+	return func(o *openaiOptions) { o.BaseURL = u }
+}
+
 // NewOpenAI creates a new OpenAI provider.
 func NewOpenAI(apiKey, model string, opt ...OpenAIOption) *OpenAI {
-	var opts openaiOptions
+	var opts = openaiOptions{Timeout: defaultTimeout}
 
 	for _, o := range opt {
 		o(&opts)
 	}
 
 	var p = OpenAI{
-		httpClient: opts.HttpClient,
-		apiKey:     apiKey,
-		modelName:  model,
+		httpClient:       opts.HttpClient,
+		apiKey:           apiKey,
+		modelName:        model,
+		promptCacheKey:   opts.PromptCacheKey,
+		safetyIdentifier: opts.SafetyIdentifier,
+		baseURL:          strings.TrimRight(opts.BaseURL, "/"),
+	}
+
+	if p.baseURL == "" {
+		p.baseURL = OpenAIDefaultBaseURL
 	}
 
 	if p.httpClient == nil { // set default HTTP client
 		p.httpClient = &http.Client{
-			Timeout:   60 * time.Second,                         //nolint:mnd
+			Timeout:   opts.Timeout,
 			Transport: &http.Transport{ForceAttemptHTTP2: true}, // use HTTP/2 (why not?)
 		}
 	}
@@ -56,52 +96,56 @@ func NewOpenAI(apiKey, model string, opt ...OpenAIOption) *OpenAI {
 	return &p
 }
 
-func (p *OpenAI) Query( //nolint:dupl
+// Query implements the [Provider] interface.
+func (p *OpenAI) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
+
+// QueryStream implements the [StreamingProvider] interface.
+func (p *OpenAI) QueryStream(
 	ctx context.Context,
 	changes, commits string,
 	opts ...Option,
-) (*Response, error) {
-	var (
-		opt          = options{}.Apply(opts...)
-		instructions = GeneratePrompt(opts...)
-	)
+) (<-chan string, <-chan error) {
+	return queryStream(ctx, p, changes, commits, opts...)
+}
 
-	if opt.MaxOutputTokens == 0 {
-		opt.MaxOutputTokens = defaultMaxOutputTokens // set default value
-	}
+// Name implements the [Provider] interface.
+func (*OpenAI) Name() string { return ProviderOpenAI }
+
+// model returns the model name.
+func (p *OpenAI) model() string { return p.modelName }
 
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (*OpenAI) batchesCandidates() bool { return true }
+
+// complete performs a single request to the OpenAI API.
+func (p *OpenAI) complete(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
 	req, rErr := p.newRequest(ctx, instructions, changes, commits, opt)
 	if rErr != nil {
 		return nil, rErr
 	}
 
-	resp, rErr := p.httpClient.Do(req)
+	resp, rErr := doRequest("OpenAI", p.httpClient, req)
 	if rErr != nil {
 		return nil, rErr
 	}
 
-	defer func() { _ = resp.Body.Close() }()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, p.responseToError(resp)
 	}
 
-	answer, aErr := p.parseResponse(resp)
-	if aErr != nil {
-		return nil, aErr
+	if opt.streaming() {
+		return readChatStream("OpenAI", resp.Body, opt.Stream)
 	}
 
-	if opt.ShortMessageOnly {
-		var parts = strings.Split(answer, "\n")
-
-		if len(parts) == 0 {
-			return nil, errors.New("no response from the OpenAI API")
-		}
-
-		return &Response{Prompt: instructions, Answer: parts[0]}, nil
-	}
-
-	return &Response{Prompt: instructions, Answer: answer}, nil
+	return p.parseResponse(resp)
 }
 
 // newRequest creates a new HTTP request for the OpenAI API.
@@ -110,32 +154,35 @@ func (p *OpenAI) newRequest(
 	instructions, changes, commits string,
 	o options,
 ) (*http.Request, error) {
-	type message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	}
-
 	// https://platform.openai.com/docs/api-reference/chat
 	j, jErr := json.Marshal(struct {
-		Model               string    `json:"model"`
-		Messages            []message `json:"messages"`
-		Store               bool      `json:"store"`
-		Temperature         float64   `json:"temperature"`
-		TopP                float64   `json:"top_p"`
-		HowMany             int       `json:"n"` // How many chat completion choices to generate for each input message
-		MaxCompletionTokens int64     `json:"max_completion_tokens"`
+		Model               string          `json:"model"`
+		Messages            []chatMessage   `json:"messages"`
+		Store               bool            `json:"store"`
+		Temperature         float64         `json:"temperature"`
+		TopP                float64         `json:"top_p"`
+		HowMany             int             `json:"n"` // How many chat completion choices to generate for each input message
+		MaxCompletionTokens int64           `json:"max_completion_tokens"`
+		PromptCacheKey      string          `json:"prompt_cache_key,omitempty"`
+		SafetyIdentifier    string          `json:"safety_identifier,omitempty"`
+		ResponseFormat      *responseFormat `json:"response_format,omitempty"`
+		Stop                []string        `json:"stop,omitempty"`
+		Stream              bool            `json:"stream,omitempty"`
+		StreamOptions       *streamOptions  `json:"stream_options,omitempty"`
 	}{
-		Model:               p.modelName,
+		Model:               o.modelOr(p.modelName),
 		Store:               false,
-		Temperature:         0.1, //nolint:mnd
-		TopP:                0.1, //nolint:mnd
-		HowMany:             1,
+		Temperature:         o.temperature(),
+		TopP:                o.topP(),
+		HowMany:             o.Candidates,
 		MaxCompletionTokens: o.MaxOutputTokens,
-		Messages: []message{
-			{Role: "system", Content: instructions},
-			{Role: "user", Content: wrapChanges(changes)},
-			{Role: "user", Content: wrapCommits(commits)},
-		},
+		PromptCacheKey:      p.promptCacheKey,
+		SafetyIdentifier:    p.safetyIdentifier,
+		ResponseFormat:      jsonResponseFormat(o),
+		Stop:                o.StopSequences,
+		Stream:              o.streaming(),
+		StreamOptions:       chatStreamOptions(o),
+		Messages:            chatMessages(instructions, changes, commits, o),
 	})
 	if jErr != nil {
 		return nil, jErr
@@ -144,7 +191,7 @@ func (p *OpenAI) newRequest(
 	// https://ai.google.dev/gemini-api/docs/text-generation?lang=rest
 	req, rErr := http.NewRequestWithContext(ctx,
 		http.MethodPost,
-		"https://api.openai.com/v1/chat/completions",
+		p.baseURL+"/chat/completions",
 		bytes.NewReader(j),
 	)
 	if rErr != nil {
@@ -152,57 +199,58 @@ func (p *OpenAI) newRequest(
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.userAgent())
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
 
 	return req, nil
 }
 
 // responseToError converts the response from the OpenAI API to an error.
-func (p *OpenAI) responseToError(resp *http.Response) error {
-	var response struct {
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err == nil && response.Error.Message != "" {
-		return fmt.Errorf(
-			"OpenAI API error: %s (status code: %d)",
-			response.Error.Message, resp.StatusCode,
-		)
-	}
+func (p *OpenAI) responseToError(resp *http.Response) error { return newAPIError("OpenAI", resp) }
 
-	return fmt.Errorf(
-		"unexpected OpenAI API response status code: %d (%s)",
-		resp.StatusCode, http.StatusText(resp.StatusCode),
-	)
-}
-
-// parseResponse parses the response from the OpenAI API.
-func (p *OpenAI) parseResponse(resp *http.Response) (string, error) {
+// parseResponse parses the response from the OpenAI API. Each choice is returned as a separate answer.
+func (p *OpenAI) parseResponse(resp *http.Response) (*completion, error) {
 	var answer struct {
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
 	}
 
 	if dErr := json.NewDecoder(resp.Body).Decode(&answer); dErr != nil {
-		return "", dErr
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, dErr)
 	}
 
 	if len(answer.Choices) == 0 {
-		return "", errors.New("no response from the OpenAI API")
+		return nil, errors.New("no response from the OpenAI API")
 	}
 
 	var texts = make([]string, 0, len(answer.Choices))
 
 	for _, choice := range answer.Choices {
-		if choice.Message.Content != "" {
-			texts = append(texts, choice.Message.Content)
+		if text := finalizeAnswer(choice.Message.Content); text != "" {
+			texts = append(texts, text)
 		}
 	}
 
-	return strings.Trim(strings.Join(texts, "\n"), "\n\t "), nil
+	if len(texts) == 0 {
+		return nil, errors.New("no response from the OpenAI API")
+	}
+
+	return &completion{
+		Answers: texts,
+		Usage: Usage{
+			PromptTokens:     answer.Usage.PromptTokens,
+			CompletionTokens: answer.Usage.CompletionTokens,
+			TotalTokens:      answer.Usage.TotalTokens,
+		},
+		Truncated: answer.Choices[0].FinishReason == finishReasonLength,
+	}, nil
 }
@@ -0,0 +1,248 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestOpenAI_PromptCacheKeyAndSafetyIdentifier(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveOpts    []ai.OpenAIOption
+		wantContain []string
+		wantNot     []string
+	}{
+		"omitted by default": {
+			wantNot: []string{"prompt_cache_key", "safety_identifier"},
+		},
+		"configured": {
+			giveOpts: []ai.OpenAIOption{
+				ai.WithOpenAIPromptCacheKey("describe-commit-v1"),
+				ai.WithOpenAISafetyIdentifier("user-hash"),
+			},
+			wantContain: []string{`"prompt_cache_key":"describe-commit-v1"`, `"safety_identifier":"user-hash"`},
+		},
+		"empty values": {
+			giveOpts: []ai.OpenAIOption{ai.WithOpenAIPromptCacheKey(""), ai.WithOpenAISafetyIdentifier("")},
+			wantNot:  []string{"prompt_cache_key", "safety_identifier"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat: Add foo")
+
+			_, err := ai.NewOpenAI("", "", append(tc.giveOpts, ai.WithOpenAIHttpClient(client))...).
+				Query(context.Background(), "diff", "log")
+			assertNoError(t, err)
+
+			var body = client.Requests()[0]
+
+			for _, want := range tc.wantContain {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected %q to contain %q", body, want)
+				}
+			}
+
+			for _, want := range tc.wantNot {
+				if strings.Contains(body, want) {
+					t.Errorf("expected %q to not contain %q", body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAI_BaseURL(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveOpts []ai.OpenAIOption
+		wantURL  string
+	}{
+		"default": {
+			wantURL: "https://api.openai.com/v1/chat/completions",
+		},
+		"empty": {
+			giveOpts: []ai.OpenAIOption{ai.WithOpenAIBaseURL("")},
+			wantURL:  "https://api.openai.com/v1/chat/completions",
+		},
+		"custom": {
+			giveOpts: []ai.OpenAIOption{ai.WithOpenAIBaseURL("http://gateway:4000/v1")},
+			wantURL:  "http://gateway:4000/v1/chat/completions",
+		},
+		"trailing slashes": {
+			giveOpts: []ai.OpenAIOption{ai.WithOpenAIBaseURL("https://llm.example.com/openai/v1//")},
+			wantURL:  "https://llm.example.com/openai/v1/chat/completions",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotURL string
+
+			var client = &fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+				gotURL = req.URL.String()
+
+				return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+			}}
+
+			_, err := ai.NewOpenAI("", "", append(tc.giveOpts, ai.WithOpenAIHttpClient(client))...).
+				Query(context.Background(), "diff", "log")
+			assertNoError(t, err)
+			assertEqual(t, gotURL, tc.wantURL)
+		})
+	}
+}
+
+func TestOpenAI_Truncated(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveBody string
+		giveOpts []ai.Option
+		want     bool
+	}{
+		"length": {
+			giveBody: `{"choices":[{"message":{"content":"feat: Add foo\n\nThe foo is"},"finish_reason":"length"}]}`,
+			want:     true,
+		},
+		"stop": {
+			giveBody: `{"choices":[{"message":{"content":"feat: Add foo"},"finish_reason":"stop"}]}`,
+		},
+		"streamed length": {
+			giveBody: `data: {"choices":[{"delta":{"content":"feat: Add foo\n\nThe foo is"}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{},"finish_reason":"length"}]}` + "\n\n" + "data: [DONE]\n\n",
+			giveOpts: []ai.Option{ai.WithStream(func(string) {})},
+			want:     true,
+		},
+		"streamed stop": {
+			giveBody: `data: {"choices":[{"delta":{"content":"feat: Add foo"},"finish_reason":"stop"}]}` + "\n\n" +
+				"data: [DONE]\n\n",
+			giveOpts: []ai.Option{ai.WithStream(func(string) {})},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return newHttpResponse(http.StatusOK, tc.giveBody), nil
+			}}
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(&client)).
+				Query(context.Background(), "diff", "log", tc.giveOpts...)
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Truncated, tc.want)
+		})
+	}
+}
+
+// roundTripperFunc is a [http.RoundTripper] implemented by the function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// timeoutError is a [net.Error] reporting the timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return false }
+
+func TestOpenAI_Timeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("client timeout", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &http.Client{
+			Timeout: 50 * time.Millisecond,
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				<-req.Context().Done() // hang until the client gives up
+
+				return nil, req.Context().Err()
+			}),
+		}
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).Query(context.Background(), "diff", "log")
+
+		var timeoutErr *ai.TimeoutError
+
+		if !errors.As(err, &timeoutErr) || !errors.Is(err, ai.ErrTimeout) {
+			t.Fatalf("expected the timeout error, got %v", err)
+		}
+
+		assertEqual(t, err.Error(), "OpenAI request timed out after 50ms")
+		assertEqual(t, timeoutErr.Timeout, 50*time.Millisecond)
+	})
+
+	t.Run("custom client timeout", func(t *testing.T) {
+		t.Parallel()
+
+		var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+			return nil, &url.Error{Op: "Post", URL: req.URL.String(), Err: timeoutError{}}
+		}}
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(&client)).Query(context.Background(), "diff", "log")
+
+		if !errors.Is(err, ai.ErrTimeout) {
+			t.Fatalf("expected the timeout error, got %v", err)
+		}
+
+		assertEqual(t, err.Error(), "OpenAI request timed out") // the timeout of the custom client is unknown
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+			cancel()
+
+			return nil, &url.Error{Op: "Post", URL: req.URL.String(), Err: req.Context().Err()}
+		}}
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(&client)).Query(ctx, "diff", "log")
+
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the cancellation error, got %v", err)
+		}
+
+		if errors.Is(err, ai.ErrTimeout) {
+			t.Errorf("the cancellation must not be reported as the timeout: %v", err)
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+			<-req.Context().Done()
+
+			return nil, &url.Error{Op: "Post", URL: req.URL.String(), Err: req.Context().Err()}
+		}}
+
+		_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(&client)).Query(ctx, "diff", "log")
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the deadline error, got %v", err)
+		}
+
+		if errors.Is(err, ai.ErrTimeout) {
+			t.Errorf("the query deadline must not be reported as the client timeout: %v", err)
+		}
+	})
+}
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -17,11 +16,12 @@ type OpenRouter struct {
 	apiKey, modelName string
 }
 
-var _ Provider = (*OpenRouter)(nil) // ensure the interface is implemented
+var _ StreamingProvider = (*OpenRouter)(nil) // ensure the interface is implemented
 
 type (
 	openRouterOptions struct {
 		HttpClient httpClient
+		Timeout    time.Duration
 	}
 
 	// OpenRouterOption allows to customize the OpenRouter provider.
@@ -33,9 +33,15 @@ func WithOpenRouterHttpClient(c httpClient) OpenRouterOption {
 	return func(o *openRouterOptions) { o.HttpClient = c }
 }
 
+// WithOpenRouterTimeout sets the timeout of the default HTTP client (60 seconds by default). It has no effect if the
+// custom HTTP client is set (see [WithOpenRouterHttpClient]).
+func WithOpenRouterTimeout(d time.Duration) OpenRouterOption {
+	return func(o *openRouterOptions) { o.Timeout = d }
+}
+
 // NewOpenRouter creates a new OpenRouter provider.
 func NewOpenRouter(apiKey, model string, opt ...OpenRouterOption) *OpenRouter {
-	var opts openRouterOptions
+	var opts = openRouterOptions{Timeout: defaultTimeout}
 
 	for _, o := range opt {
 		o(&opts)
@@ -49,7 +55,7 @@ func NewOpenRouter(apiKey, model string, opt ...OpenRouterOption) *OpenRouter {
 
 	if p.httpClient == nil { // set default HTTP client
 		p.httpClient = &http.Client{
-			Timeout:   60 * time.Second,                         //nolint:mnd
+			Timeout:   opts.Timeout,
 			Transport: &http.Transport{ForceAttemptHTTP2: true}, // use HTTP/2 (why not?)
 		}
 	}
@@ -57,52 +63,56 @@ func NewOpenRouter(apiKey, model string, opt ...OpenRouterOption) *OpenRouter {
 	return &p
 }
 
-func (p *OpenRouter) Query( //nolint:dupl
+// Query implements the [Provider] interface.
+func (p *OpenRouter) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
+
+// QueryStream implements the [StreamingProvider] interface.
+func (p *OpenRouter) QueryStream(
 	ctx context.Context,
 	changes, commits string,
 	opts ...Option,
-) (*Response, error) {
-	var (
-		opt          = options{}.Apply(opts...)
-		instructions = GeneratePrompt(opts...)
-	)
+) (<-chan string, <-chan error) {
+	return queryStream(ctx, p, changes, commits, opts...)
+}
 
-	if opt.MaxOutputTokens == 0 {
-		opt.MaxOutputTokens = defaultMaxOutputTokens // set default value
-	}
+// Name implements the [Provider] interface.
+func (*OpenRouter) Name() string { return ProviderOpenRouter }
 
+// model returns the model name.
+func (p *OpenRouter) model() string { return p.modelName }
+
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (*OpenRouter) batchesCandidates() bool { return true }
+
+// complete performs a single request to the OpenRouter API.
+func (p *OpenRouter) complete(
+	ctx context.Context,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, error) {
 	req, rErr := p.newRequest(ctx, instructions, changes, commits, opt)
 	if rErr != nil {
 		return nil, rErr
 	}
 
-	resp, rErr := p.httpClient.Do(req)
+	resp, rErr := doRequest("OpenRouter", p.httpClient, req)
 	if rErr != nil {
 		return nil, rErr
 	}
 
-	defer func() { _ = resp.Body.Close() }()
+	defer drainAndClose(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, p.responseToError(resp)
 	}
 
-	answer, aErr := p.parseResponse(resp)
-	if aErr != nil {
-		return nil, aErr
+	if opt.streaming() {
+		return readChatStream("OpenRouter", resp.Body, opt.Stream)
 	}
 
-	if opt.ShortMessageOnly {
-		var parts = strings.Split(answer, "\n")
-
-		if len(parts) == 0 {
-			return nil, errors.New("no response from the OpenRouter API")
-		}
-
-		return &Response{Prompt: instructions, Answer: parts[0]}, nil
-	}
-
-	return &Response{Prompt: instructions, Answer: answer}, nil
+	return p.parseResponse(resp)
 }
 
 // newRequest creates a new HTTP request for the OpenRouter API.
@@ -111,30 +121,29 @@ func (p *OpenRouter) newRequest(
 	instructions, changes, commits string,
 	o options,
 ) (*http.Request, error) {
-	type message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	}
-
 	// https://openrouter.ai/docs/api-reference/parameters
 	j, jErr := json.Marshal(struct {
-		Model       string    `json:"model"`
-		Messages    []message `json:"messages"`
-		Temperature float64   `json:"temperature"`
-		TopP        float64   `json:"top_p"`
-		HowMany     int       `json:"n"` // How many chat completion choices to generate for each input message
-		MaxTokens   int64     `json:"max_tokens"`
+		Model          string          `json:"model"`
+		Messages       []chatMessage   `json:"messages"`
+		Temperature    float64         `json:"temperature"`
+		TopP           float64         `json:"top_p"`
+		HowMany        int             `json:"n"` // How many chat completion choices to generate for each input message
+		MaxTokens      int64           `json:"max_tokens"`
+		ResponseFormat *responseFormat `json:"response_format,omitempty"`
+		Stop           []string        `json:"stop,omitempty"`
+		Stream         bool            `json:"stream,omitempty"`
+		StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
 	}{
-		Model:       p.modelName,
-		Temperature: 0.1, //nolint:mnd
-		TopP:        0.1, //nolint:mnd
-		HowMany:     1,
-		MaxTokens:   o.MaxOutputTokens,
-		Messages: []message{
-			{Role: "system", Content: instructions},
-			{Role: "user", Content: wrapChanges(changes)},
-			{Role: "user", Content: wrapCommits(commits)},
-		},
+		Model:          o.modelOr(p.modelName),
+		Temperature:    o.temperature(),
+		TopP:           o.topP(),
+		HowMany:        o.Candidates,
+		MaxTokens:      o.MaxOutputTokens,
+		ResponseFormat: jsonResponseFormat(o),
+		Stop:           o.StopSequences,
+		Stream:         o.streaming(),
+		StreamOptions:  chatStreamOptions(o),
+		Messages:       chatMessages(instructions, changes, commits, o),
 	})
 	if jErr != nil {
 		return nil, jErr
@@ -150,6 +159,7 @@ func (p *OpenRouter) newRequest(
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", o.userAgent())
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", p.apiKey))
 
 	return req, nil
@@ -157,50 +167,52 @@ func (p *OpenRouter) newRequest(
 
 // responseToError converts the response from the OpenRouter API to an error.
 func (p *OpenRouter) responseToError(resp *http.Response) error {
-	var response struct {
-		Error struct {
-			Message string `json:"message"`
-		} `json:"error"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err == nil && response.Error.Message != "" {
-		return fmt.Errorf(
-			"OpenRouter API error: %s (status code: %d)",
-			response.Error.Message, resp.StatusCode,
-		)
-	}
-
-	return fmt.Errorf(
-		"unexpected OpenRouter API response status code: %d (%s)",
-		resp.StatusCode, http.StatusText(resp.StatusCode),
-	)
+	return newAPIError("OpenRouter", resp)
 }
 
-// parseResponse parses the response from the OpenRouter API.
-func (p *OpenRouter) parseResponse(resp *http.Response) (string, error) {
+// parseResponse parses the response from the OpenRouter API. Each choice is returned as a separate answer.
+func (p *OpenRouter) parseResponse(resp *http.Response) (*completion, error) {
 	var answer struct {
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
 	}
 
 	if dErr := json.NewDecoder(resp.Body).Decode(&answer); dErr != nil {
-		return "", dErr
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, dErr)
 	}
 
 	if len(answer.Choices) == 0 || len(answer.Choices[0].Message.Content) == 0 {
-		return "", errors.New("no content found")
+		return nil, errors.New("no content found")
 	}
 
 	var texts = make([]string, 0, len(answer.Choices))
 
 	for _, choice := range answer.Choices {
-		if text := choice.Message.Content; text != "" {
+		if text := finalizeAnswer(choice.Message.Content); text != "" {
 			texts = append(texts, text)
 		}
 	}
 
-	return strings.Trim(strings.Join(texts, "\n"), "\n\t "), nil
+	if len(texts) == 0 {
+		return nil, errors.New("no content found")
+	}
+
+	return &completion{
+		Answers: texts,
+		Usage: Usage{
+			PromptTokens:     answer.Usage.PromptTokens,
+			CompletionTokens: answer.Usage.CompletionTokens,
+			TotalTokens:      answer.Usage.TotalTokens,
+		},
+		Truncated: answer.Choices[0].FinishReason == finishReasonLength,
+	}, nil
 }
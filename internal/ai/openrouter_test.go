@@ -0,0 +1,77 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestOpenRouter_ResponseToError(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveCode   int
+		giveBody   string
+		wantErr    error
+		wantString string
+		wantCode   string
+	}{
+		"unauthorized": {
+			giveCode:   http.StatusUnauthorized,
+			giveBody:   `{"error":{"message":"No auth credentials found","code":401}}`,
+			wantErr:    ai.ErrUnauthorized,
+			wantString: "OpenRouter API error: No auth credentials found (status code: 401)",
+			wantCode:   "401",
+		},
+		"rate limited": {
+			giveCode:   http.StatusTooManyRequests,
+			giveBody:   `{"error":{"message":"Rate limit exceeded: free-models-per-day","code":429}}`,
+			wantErr:    ai.ErrRateLimited,
+			wantString: "OpenRouter API error: Rate limit exceeded: free-models-per-day (status code: 429)",
+			wantCode:   "429",
+		},
+		"bad request with a string code": {
+			giveCode:   http.StatusBadRequest,
+			giveBody:   `{"error":{"message":"foo/bar is not a valid model ID","code":"invalid_model"}}`,
+			wantErr:    ai.ErrBadRequest,
+			wantString: "OpenRouter API error: foo/bar is not a valid model ID (status code: 400)",
+			wantCode:   "invalid_model",
+		},
+		"not a json": {
+			giveCode:   http.StatusBadGateway,
+			giveBody:   `<html>Bad Gateway</html>`,
+			wantErr:    ai.ErrProviderUnavailable,
+			wantString: "unexpected OpenRouter API response status code: 502 (Bad Gateway)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return newHttpResponse(tc.giveCode, tc.giveBody), nil
+			}}
+
+			_, err := ai.NewOpenRouter("", "", ai.WithOpenRouterHttpClient(client)).
+				Query(context.Background(), "diff", "log")
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+
+			assertEqual(t, err.Error(), tc.wantString)
+
+			var apiErr *ai.APIError
+
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected an *ai.APIError, got %T", err)
+			}
+
+			assertEqual(t, apiErr.Provider, "OpenRouter")
+			assertEqual(t, apiErr.StatusCode, tc.giveCode)
+			assertEqual(t, apiErr.Code, tc.wantCode)
+		})
+	}
+}
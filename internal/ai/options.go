@@ -1,27 +1,230 @@
 package ai
 
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gh.tarampamp.am/describe-commit/internal/version"
+)
+
 type (
 	// options is a set of options that can be applied to the AI provider.
 	options struct {
 		ShortMessageOnly bool
 		EnableEmoji      bool
 		MaxOutputTokens  int64
+		Candidates       int       // how many candidates (alternative messages) to generate
+		UniqueCandidates bool      // drop candidates with the same subject
+		AuditLog         io.Writer // where to write the audit records (nil = disabled)
+		AuditLogBody     bool      // include the commit message body into the audit records
+		PostProcess      func(string) string
+		SemverHint       bool // ask the model to note the intended version bump in a footer
+		GitDir           string
+		BlameContext     bool
+		KeepIndexLines   bool // do not strip the `index` and file mode lines from the diff
+		PlanThenWrite    bool // ask for the list of changes first, then write the message based on it
+		ForceDirScope    bool // use the common top-level directory of the changed files as the scope
+		OutputFormat     OutputFormat
+		SkipDecodeRetry  bool     // do not retry the request when the response can't be decoded
+		ContextLabels    []string // brief labels of the changes area (e.g. "backend")
+		DiscourageChore  bool     // prefer the more specific types over "chore"
+		UserAgent        string   // the User-Agent header value (empty = default)
+		AdditionsOnly    bool     // strip the deleted lines from the diff
+		SmartBody        bool     // add the body only for the non-trivial changes
+		MaxBytesPerFile  int      // truncate every file section of the diff to this size (0 = no limit)
+		MaxDiffBytes     int      // truncate the hunks of the whole diff to fit this size (0 = no limit)
+		MaxLineLength    int      // truncate the longer diff lines (0 = default, negative = no limit)
+		PlainText        bool     // avoid the Markdown formatting in the message
+		OverflowModel    string   // the larger-context model to retry with when the context is too long
+		Confidence       bool     // ask for the confidence and the rationale (the JSON output is used)
+		CommitExamples   bool     // pass the recent commits as the style examples instead of the raw log
+		FileTypeSummary  bool     // add the summary of the changed file types (e.g. "3 Go files") to the prompt
+		BodyStyle        BodyStyle
+		SeedMessage      string  // the partial message written by the user, to build on
+		MaxCandidateCost float64 // the estimated cost limit (USD) of all the candidates (0 = no limit)
+		DepsAware        bool    // detect the dependency-only changes and ask for the tailored message
+		MaxHunks         int     // the max number of the diff hunks sent to the model (0 = no limit)
+		ModelTrailer     bool    // append the trailer with the model name to the message
+		TrailerFormat    string  // the format of the model trailer (empty = default)
+		MimicRepoDir     string  // the repository to take the top contributor's commits from (empty = disabled)
+		LaxSubject       bool    // do not normalize the subject format
+		TranslateTo      string  // translate the message passed as the changes to this language (see [Translate])
+		InlineFileNotes  bool    // prefix the body bullets with the files they relate to
+		Language         string  // the natural language of the message (an ISO 639-1 code or a name; empty = default)
+		FixupTarget      string  // the commit to emit the `fixup!` message for, instead of generating one
+		BinaryMetadata   bool    // describe the changed binary files using their metadata (size, type, dimensions)
+		MaxSubjectWords  int     // the max number of words in the subject description (0 = no limit)
+		PromptTemplate   string  // the custom template of the instructions (empty = the built-in prompt)
+		AvoidReverted    bool    // pass the subjects of the reverted commits from the log as the patterns to avoid
+		AuthorName       string  // the author to personalize the message style for (empty = disabled)
+		TestOnlyType     bool    // force the `test` type when only the test files are changed
+		RepoNameContext  bool    // add the repository name (from the `origin` remote URL) to the prompt
+		DryRun           bool    // return the assembled prompt without calling the API
+		ASCIIOnly        bool    // use the ASCII characters only in the message (disables the emoji)
+		Locale           string  // the locale of the dates and numbers in the long-form outputs (empty = default)
+		MaxBodyLines     int     // move the longer body to the overflow note, keeping this many lines (0 = disabled)
+		RecentCommits    int     // the number of the recent full commit messages used as the examples (0 = disabled)
+		SecurityReview   bool    // ask the model to flag the security-relevant changes in a footer
+
+		// the commit messages (or phrasings) the model must not repeat (e.g. the reverted ones), and the changed
+		// paths to suggest the scopes from
+		AvoidPatterns []string
+		ScopeHints    []string
+
+		// counts the tokens for the token budgets (nil = the estimation, see [EstimateTokens])
+		Tokenizer Tokenizer
+
+		// the sampling parameters (nil = the default ones)
+		Temperature, TopP *float64
+
+		// the sequences the generation stops at (OpenAI and OpenRouter only, empty = none)
+		StopSequences []string
+
+		// the time limit of the whole query, including all the attempts (0 = no limit)
+		TotalBudget time.Duration
+
+		// the max number of the retries of the transient failures (0 = disabled), and the base delay between them
+		Retries   int
+		RetryBase time.Duration
+
+		// lint the message with commitlint, and the path of its config file (empty = the commitlint's default lookup)
+		Commitlint       bool
+		CommitlintConfig string
+
+		// the streaming mode: the function receives the message deltas as they are generated, the invalid
+		// streamed message can be fixed with an additional request, and the stalled stream can be replaced with
+		// the non-streaming request (after the grace period, 0 = default)
+		Stream           func(delta string)
+		FixInvalidStream bool
+		StreamFallback   bool
+		StreamGrace      time.Duration
+
+		// the model to use instead of the configured one (set internally, e.g. on the context overflow)
+		modelOverride string
+
+		// additional context for the model, added by the features that inspect the repository or the changes
+		extraContext []contextBlock
 	}
 
+	// contextBlock is a titled piece of additional context included into the prompt.
+	contextBlock struct{ Title, Text string }
+
 	// Option is a function that modifies the options.
 	Option func(*options)
 )
 
+// defaultUserAgent returns the default User-Agent header value (`describe-commit/<version>`).
+func defaultUserAgent() string {
+	var v, _, _ = strings.Cut(version.Version(), "@") // drop the build metadata (e.g. "0.0.0@undefined")
+
+	return "describe-commit/" + v
+}
+
+// userAgent returns the User-Agent header value to use.
+func (o options) userAgent() string {
+	if o.UserAgent != "" {
+		return o.UserAgent
+	}
+
+	return defaultUserAgent()
+}
+
+// modelOr returns the model to use: the overridden one, or the given default.
+func (o options) modelOr(model string) string {
+	if o.modelOverride != "" {
+		return o.modelOverride
+	}
+
+	return model
+}
+
+// tokenizer returns the tokenizer to use for the model.
+func (o options) tokenizer(model string) Tokenizer {
+	if o.Tokenizer != nil {
+		return o.Tokenizer
+	}
+
+	return estimator{model: model}
+}
+
+// defaultSampling is the default value of both the temperature and the top_p sampling parameters. The low values
+// make the output focused and near-deterministic.
+const defaultSampling = 0.1
+
+// temperature returns the sampling temperature to use.
+func (o options) temperature() float64 {
+	if o.Temperature != nil {
+		return *o.Temperature
+	}
+
+	return defaultSampling
+}
+
+// topP returns the nucleus sampling (top_p) value to use.
+func (o options) topP() float64 {
+	if o.TopP != nil {
+		return *o.TopP
+	}
+
+	return defaultSampling
+}
+
+// validate checks the options that can't be silently corrected.
+func (o options) validate() error {
+	if t := o.temperature(); t < 0 || t > 2 {
+		return fmt.Errorf("invalid temperature %g: must be within [0, 2]", t)
+	}
+
+	if p := o.topP(); p <= 0 || p > 1 {
+		return fmt.Errorf("invalid top_p %g: must be within (0, 1]", p)
+	}
+
+	if o.PromptTemplate != "" {
+		if _, err := renderPromptTemplate(o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inlineFileNotes reports whether the body bullets should be prefixed with the files.
+func (o options) inlineFileNotes() bool {
+	return o.InlineFileNotes && !o.ShortMessageOnly && o.BodyStyle != Paragraph
+}
+
+// jsonOutput reports whether the model is asked to respond with a JSON object instead of the plain text.
+func (o options) jsonOutput() bool { return o.Confidence && o.OutputFormat == FormatCommitMessage }
+
+// streaming reports whether the answer should be streamed (a single plain text candidate only).
+func (o options) streaming() bool { return o.Stream != nil && o.Candidates <= 1 && !o.jsonOutput() }
+
+// seed returns the partial message written by the user (commit messages only).
+func (o options) seed() string {
+	if o.OutputFormat != FormatCommitMessage {
+		return ""
+	}
+
+	return strings.TrimSpace(o.SeedMessage)
+}
+
 // Apply applies the given options.
 func (o options) Apply(opts ...Option) options {
 	for _, opt := range opts {
 		opt(&o)
 	}
 
+	if o.ASCIIOnly {
+		o.EnableEmoji = false // regardless of the options order
+	}
+
 	return o
 }
 
-// WithShortMessageOnly forces the provider to return only the short commit message (usually the first line).
+// WithShortMessageOnly forces the provider to return only the short commit message (usually the first line). With
+// several candidates (see [WithCandidates]), it applies to every one of them.
 func WithShortMessageOnly(on bool) Option { return func(o *options) { o.ShortMessageOnly = on } }
 
 // WithEmoji enables or disables emoji in the commit message.
@@ -29,3 +232,354 @@ func WithEmoji(on bool) Option { return func(o *options) { o.EnableEmoji = on }
 
 // WithMaxOutputTokens sets the maximum number of tokens in the output.
 func WithMaxOutputTokens(max int64) Option { return func(o *options) { o.MaxOutputTokens = max } }
+
+// WithMaxSubjectWords asks the model to keep the subject description (after the type and scope) to at most n words.
+// Unlike the characters limit, the violations are flagged by [Response.Validate] for the response of the query
+// made with this option. Zero (the default) means no limit.
+func WithMaxSubjectWords(n int) Option { return func(o *options) { o.MaxSubjectWords = n } }
+
+// WithPromptTemplate replaces the built-in instructions for the model with the custom ones, rendered using the
+// `text/template` package. The `{{.ShortMessageOnly}}` and `{{.EnableEmoji}}` fields are available to the template
+// (e.g. `{{if .EnableEmoji}}Start with the GitMoji.{{end}}`). The query fails if the template can't be parsed or
+// rendered. The template is not used for the other output formats (see [WithOutputFormat]) and the translation.
+func WithPromptTemplate(tmpl string) Option { return func(o *options) { o.PromptTemplate = tmpl } }
+
+// WithAvoidPatterns passes the commit messages (or phrasings) the model must not repeat, e.g. the ones that were
+// reverted or rejected in the review, as the negative examples.
+func WithAvoidPatterns(patterns []string) Option {
+	return func(o *options) { o.AvoidPatterns = patterns }
+}
+
+// WithAvoidReverted scans the commits log for the revert commits (`revert: <subject>` or `Revert "<subject>"`), and
+// passes the subjects of the reverted commits to the model as the patterns to avoid (see [WithAvoidPatterns]).
+func WithAvoidReverted(on bool) Option { return func(o *options) { o.AvoidReverted = on } }
+
+// WithScopeHints passes the paths of the changed files (e.g. from [git.ChangedPaths]), so the likely scopes (the
+// top-level directories, or the modules inside the directories like `internal` and `pkg`) are suggested to the
+// model instead of letting it guess. Disabled by default.
+func WithScopeHints(paths []string) Option { return func(o *options) { o.ScopeHints = paths } }
+
+// WithRepoNameContext adds the name of the repository (e.g. "github.com/org/payments-service", taken from the URL
+// of the `origin` remote of the [WithGitDir] repository, with the credentials stripped) to the prompt, so the model
+// can infer the default scope or the project area from it. Nothing is added if the repository has no remote.
+func WithRepoNameContext(on bool) Option { return func(o *options) { o.RepoNameContext = on } }
+
+// WithDryRun makes the query skip the API calls: the returned [Response] has no answer, and its prompt is the fully
+// assembled system prompt followed by the wrapped changes and commits, exactly as they would be sent to the model
+// (the planning pass of [WithPlanThenWrite] is skipped too). Useful for debugging the prompts and for testing.
+func WithDryRun() Option { return func(o *options) { o.DryRun = true } }
+
+// WithCandidates sets the number of candidates (alternative commit messages) to generate. All of them are returned
+// as [Response.Answers]; the first one is also returned as [Response.Answer], and the rest as
+// [Response.Alternatives]. The providers supporting it (e.g. the `n` parameter of OpenAI) generate all the
+// candidates in a single request.
+func WithCandidates(n int) Option { return func(o *options) { o.Candidates = n } }
+
+// WithUniqueCandidates enables or disables the de-duplication of candidates. Candidates are considered identical
+// if their subjects (the first line) are equal, ignoring the case. If too many candidates collapse, the provider
+// is asked for more (a few times at most).
+func WithUniqueCandidates(on bool) Option { return func(o *options) { o.UniqueCandidates = on } }
+
+// WithAuditLog enables the audit logging: one JSON object per line is written to w for every generation. The record
+// contains the timestamp, provider and model names, the SHA-256 hash of the diff, token usage, and the subject of
+// the generated message. Neither the diff nor any secrets are written.
+func WithAuditLog(w io.Writer) Option { return func(o *options) { o.AuditLog = w } }
+
+// WithAuditLogBody enables or disables writing the commit message body into the audit records.
+func WithAuditLogBody(on bool) Option { return func(o *options) { o.AuditLogBody = on } }
+
+// WithPostProcess sets the function applied to every generated message (including alternatives) right before
+// returning it. It runs after all the built-in sanitization, so it can be used to enforce the house rules the
+// prompt can't guarantee (e.g. uppercase ticket IDs).
+func WithPostProcess(fn func(string) string) Option { return func(o *options) { o.PostProcess = fn } }
+
+// WithSemverHint asks the model to note the intended semantic versioning bump in the `Semver:` footer of the commit
+// message. Has no effect when only the short message is requested.
+func WithSemverHint(on bool) Option { return func(o *options) { o.SemverHint = on } }
+
+// WithGitDir sets the path to the git repository. It's required by the options that need to inspect the repository
+// (e.g. [WithBlameContext]); without it, such options have no effect.
+func WithGitDir(dirPath string) Option { return func(o *options) { o.GitDir = dirPath } }
+
+// WithFixupTarget makes the query emit the `fixup! <target subject>` message for the given commit (hash or any
+// other revision) instead of generating a new one, for the `git rebase --autosquash` workflows. The provider is not
+// called. Requires [WithGitDir]; the error wrapping [git.ErrUnknownRevision] is returned if the commit does not exist.
+func WithFixupTarget(hash string) Option { return func(o *options) { o.FixupTarget = hash } }
+
+// WithBinaryMetadata enables the extraction of the metadata of the changed binary files (the size before and after,
+// the detected type, and the dimensions of the images), since the diff shows no content for them. The metadata is
+// passed to the model as the additional context, so it can describe the changes like "optimize logo.png (-40%
+// size)". Requires [WithGitDir]: the previous state is taken from HEAD, and the new one from the index.
+func WithBinaryMetadata(on bool) Option { return func(o *options) { o.BinaryMetadata = on } }
+
+// WithBlameContext enables running `git blame` on the changed regions and summarizing the prior authorship (authors
+// and commit subjects) as additional context for the model. It's expensive, so it's disabled by default. Requires
+// [WithGitDir].
+func WithBlameContext(on bool) Option { return func(o *options) { o.BlameContext = on } }
+
+// withContext adds a titled piece of additional context to the prompt.
+func withContext(title, text string) Option {
+	return func(o *options) { o.extraContext = append(o.extraContext, contextBlock{Title: title, Text: text}) }
+}
+
+// WithKeepIndexLines disables stripping the `index <hash>..<hash>` and file mode lines from the diff (they are
+// stripped by default, since they carry no meaning for the model).
+func WithKeepIndexLines(on bool) Option { return func(o *options) { o.KeepIndexLines = on } }
+
+// WithPlanThenWrite enables the two-pass mode: the model is asked for the list of key changes first, and then the
+// commit message is written based on that list (and the diff). The same provider is used for both passes. It
+// improves the quality, but doubles the cost.
+func WithPlanThenWrite(on bool) Option { return func(o *options) { o.PlanThenWrite = on } }
+
+// WithForceDirScope forces the scope of the commit message to be the top-level directory of the changed files,
+// regardless of what the model chooses. It has no effect when the files span multiple top-level directories (or
+// some of them are located in the root).
+func WithForceDirScope(on bool) Option { return func(o *options) { o.ForceDirScope = on } }
+
+// WithTestOnlyType forces the `test` type of the commit message when only the test files (e.g. `*_test.go`,
+// `*.spec.ts`, or the files in the `tests` directory) are changed, regardless of what the model chooses. The scope
+// chosen by the model is kept; if there is none, the directory of the changed tests is used (when it's the only one).
+func WithTestOnlyType(on bool) Option { return func(o *options) { o.TestOnlyType = on } }
+
+// WithOutputFormat sets the format of the generated text. By default, the commit message is generated.
+func WithOutputFormat(f OutputFormat) Option { return func(o *options) { o.OutputFormat = f } }
+
+// WithRetryOnDecodeError enables or disables a single automatic retry when the provider responds with a body that
+// can't be decoded (see [ErrMalformedResponse]). It's enabled by default, since such errors are usually transient.
+func WithRetryOnDecodeError(on bool) Option { return func(o *options) { o.SkipDecodeRetry = !on } }
+
+// WithStrictSubjectFormat enables or disables the normalization of the subject (the first line) of the generated
+// message: the `type(scope): subject` spacing is enforced, the trailing period is removed, and the repeated spaces
+// are collapsed, so the strict commit linters are satisfied. It's enabled by default.
+func WithStrictSubjectFormat(on bool) Option { return func(o *options) { o.LaxSubject = !on } }
+
+// WithContextLabels sets the brief labels describing the area of the changes (e.g. "infra", "frontend", "backend"),
+// so the model can pick the right tone and scope. They are included into the prompt as a single line; empty labels
+// are ignored.
+func WithContextLabels(labels ...string) Option {
+	return func(o *options) {
+		for _, l := range labels {
+			if l = strings.TrimSpace(l); l != "" {
+				o.ContextLabels = append(o.ContextLabels, l)
+			}
+		}
+	}
+}
+
+// WithDiscourageChore instructs the model to prefer a more specific type and use `chore` only as a last resort. If
+// `chore` is chosen anyway for the changes that touch the source code, a warning is added to the
+// [Response.Warnings].
+func WithDiscourageChore(on bool) Option { return func(o *options) { o.DiscourageChore = on } }
+
+// WithUserAgent overrides the User-Agent header sent to the providers (`describe-commit/<version>` by default).
+func WithUserAgent(ua string) Option { return func(o *options) { o.UserAgent = ua } }
+
+// WithAdditionsOnly strips the deleted lines from the diff hunks before sending it, leaving only the context and the
+// added lines. It's useful for documenting new features, when the deletions are distracting.
+func WithAdditionsOnly(on bool) Option { return func(o *options) { o.AdditionsOnly = on } }
+
+// WithMaxLineLength limits the length (in bytes) of every diff line: the longer lines (e.g. of the minified JS/CSS
+// or the generated files) are truncated with the `…(truncated)` marker, so a single line can't blow up the prompt.
+// Zero means the default limit (500 bytes), and the negative value disables the truncation.
+func WithMaxLineLength(n int) Option { return func(o *options) { o.MaxLineLength = n } }
+
+// WithSmartBody asks the model to add the commit message body only when the change introduces non-obvious behavior
+// (the criterion is stated explicitly in the prompt), and to write the subject line only otherwise. Has no effect
+// when only the short message is requested.
+func WithSmartBody(on bool) Option { return func(o *options) { o.SmartBody = on } }
+
+// WithMaxBytesPerFile limits the size of every file section of the diff (at a line boundary, with a note about the
+// truncation), so the prompt stays balanced across the files and one huge file doesn't crowd out the others. Zero
+// (the default) means no limit.
+func WithMaxBytesPerFile(n int) Option { return func(o *options) { o.MaxBytesPerFile = n } }
+
+// WithPlainText instructs the model to avoid the Markdown formatting (emphasis, inline code, code blocks) in the
+// commit message, since many git viewers render the raw text. As a safeguard, the `**`, `__`, backticks, and code
+// fences are also stripped from the answer.
+func WithPlainText(on bool) Option { return func(o *options) { o.PlainText = on } }
+
+// WithSecurityReview asks the model to flag the potentially security-relevant changes (authentication,
+// authorization, cryptography, input validation, etc.) in the `Security-relevant:` footer of the commit message,
+// naming the affected area without the exploitable specifics. Has no effect when only the short message is requested.
+func WithSecurityReview(on bool) Option { return func(o *options) { o.SecurityReview = on } }
+
+// WithASCIIOnly instructs the model to use the ASCII characters only, for the systems that can't handle the other
+// ones in the commit messages. The emoji are disabled (see [WithEmoji]). As a safeguard, the answer is also
+// transliterated: the accented letters and the typographic punctuation are replaced with their ASCII counterparts
+// (e.g. "é" with "e", or "—" with "-"), and the rest of the non-ASCII characters are dropped.
+func WithASCIIOnly(on bool) Option { return func(o *options) { o.ASCIIOnly = on } }
+
+// WithLocale sets the locale (a BCP 47 tag, e.g. "de-DE") of the dates, numbers, and versions the model includes in
+// the long-form outputs (the [FormatGitNote]); it's passed to the model as the formatting instruction. It has no
+// effect on the commit messages.
+func WithLocale(locale string) Option { return func(o *options) { o.Locale = locale } }
+
+// WithBodyOverflowToNote keeps the commit message short when the model writes a very long body: if the body has
+// more than the given number of lines, only the first ones are kept in the message, and the full body is returned
+// as [Response.OverflowNote], for the caller to attach it as a git note (or a PR comment). The trailers block at
+// the end of the body (e.g. `Signed-off-by: ...` or the model trailer) is always kept in the message (and is not
+// included into the note, nor counted). Zero (the default) disables it.
+func WithBodyOverflowToNote(maxBodyLines int) Option {
+	return func(o *options) { o.MaxBodyLines = maxBodyLines }
+}
+
+// WithTokenizer sets the [Tokenizer] used to count the tokens for the token budgets (e.g. the cost limit, see
+// [WithMaxCandidateCost], or the truncation of the diff on the context overflow). By default, the number of tokens
+// is estimated (see [EstimateTokens]).
+func WithTokenizer(t Tokenizer) Option { return func(o *options) { o.Tokenizer = t } }
+
+// WithOverflowModel sets the model (usually a larger-context sibling of the configured one) to retry the request
+// with once, when the provider reports that the context is too long (see [ErrContextTooLong]). Without it, the
+// request is retried once with the diff truncated by half (in tokens, see [WithTokenizer]).
+func WithOverflowModel(model string) Option { return func(o *options) { o.OverflowModel = model } }
+
+// WithConfidence asks the model to assess its confidence (0..1) in the generated message and to explain the
+// uncertainty briefly. They are returned as [Response.Confidence] and [Response.Rationale]; low confidence can be
+// used to trigger a human review. The model is asked to respond with a JSON object, using the structured output
+// mode of the provider.
+func WithConfidence(on bool) Option { return func(o *options) { o.Confidence = on } }
+
+// WithCommitsAsExamples reformats the recent commits (newline-separated subjects, as passed to [Provider.Query])
+// into the style examples, pairing every subject with its type and scope, so the model follows the established
+// pattern. Non-conventional subjects are skipped; if none is left, the commits are passed as is.
+func WithCommitsAsExamples(on bool) Option { return func(o *options) { o.CommitExamples = on } }
+
+// WithFileTypeSummary adds the brief summary of the changed file types (e.g. "3 Go files, 1 YAML file"), derived
+// from the file extensions, to the prompt. It helps the model to distinguish the code from the assets and the
+// configuration, and to pick the right type and scope.
+func WithFileTypeSummary(on bool) Option { return func(o *options) { o.FileTypeSummary = on } }
+
+// WithInlineFileNotes asks for the body where every bullet is prefixed with the relevant file (e.g. `- auth.go: add
+// token refresh`), mapping the changes to the files for the reviewers. The list of the changed files is passed to
+// the model, so it can attribute the changes accurately. It has no effect with the [Paragraph] body style and when
+// the short message only is requested.
+func WithInlineFileNotes(on bool) Option { return func(o *options) { o.InlineFileNotes = on } }
+
+// WithLanguage sets the natural language of the generated commit message, either as the ISO 639-1 code (e.g. "es",
+// "de") or as a plain name (e.g. "Spanish"). The Conventional Commit type and scope are kept in English. The prompt
+// is not changed when empty (the default).
+func WithLanguage(lang string) Option { return func(o *options) { o.Language = lang } }
+
+// WithBodyStyle sets the style of the commit message body: the bullet list of the key points ([BulletList], the
+// default) or a single prose paragraph ([Paragraph]).
+func WithBodyStyle(style BodyStyle) Option { return func(o *options) { o.BodyStyle = style } }
+
+// WithSeedMessage passes the partial message written by the user (e.g. the subject typed before the
+// `prepare-commit-msg` hook runs) to the model as the starting point: it's asked to complete and refine the message,
+// respecting the user's intent, rather than write a new one.
+func WithSeedMessage(seed string) Option { return func(o *options) { o.SeedMessage = seed } }
+
+// WithMaxCandidateCost limits the estimated total cost (in USD) of the request: the number of candidates (see
+// [WithCandidates]) is reduced to fit the budget before sending, based on the model price (see [LookupPrice]) and
+// the estimated number of the input tokens (see [WithTokenizer]); the output is estimated as the maximum number of
+// the output tokens per candidate. [ErrCostLimitExceeded] is returned if even a single candidate exceeds the limit.
+// For the models with the unknown price, the limit is not enforced (a warning is added to the response).
+func WithMaxCandidateCost(usd float64) Option { return func(o *options) { o.MaxCandidateCost = usd } }
+
+// WithDepsAware enables the detection of the dependency updates (like the ones made by Renovate or Dependabot): if
+// the changes are confined to the dependency manifests (`go.mod`, `package.json`, `requirements.txt`, and
+// `Cargo.toml`) and lock files, the version changes are parsed and the model is asked for the `build(deps)` message
+// listing the upgraded packages.
+func WithDepsAware(on bool) Option { return func(o *options) { o.DepsAware = on } }
+
+// WithMaxDiffBytes limits the size of the whole diff sent to the model. Unlike the hard cut, the file and hunk
+// headers are kept, so the model still sees every changed region, and the hunks are shortened evenly to their first
+// lines (as many as fit). A "... (truncated)" marker is added to every truncated file.
+func WithMaxDiffBytes(n int) Option { return func(o *options) { o.MaxDiffBytes = n } }
+
+// WithMaxHunks limits the number of the diff hunks (the `@@` regions) sent to the model, so the prompt size stays
+// predictable on sprawling commits. The hunks of the source files are kept first, then the larger ones; the file
+// headers are always kept, and a note about the omitted hunks is added to every affected file. The number of the
+// omitted hunks is reported as [Response.OmittedHunks]. Zero (the default) means no limit.
+func WithMaxHunks(n int) Option { return func(o *options) { o.MaxHunks = n } }
+
+// WithModelTrailer appends the `Generated-by: describe-commit (model: <name>)` trailer to the commit message, to
+// record the provenance of the generated message. The trailer is added after the other trailers (e.g.
+// `Signed-off-by`), if the message already has them. It is not added when only the short message is requested.
+// Use [WithTrailerFormat] to change the trailer.
+func WithModelTrailer(on bool) Option { return func(o *options) { o.ModelTrailer = on } }
+
+// WithTrailerFormat sets the format of the model trailer (see [WithModelTrailer]). The `{model}` placeholder is
+// replaced with the model name, e.g. `Assisted-by: {model}`.
+func WithTrailerFormat(format string) Option { return func(o *options) { o.TrailerFormat = format } }
+
+// WithMimicTopContributor adds the recent commit subjects (up to 15) of the most prolific author of the repository
+// (according to `git shortlog -sn`) to the prompt as the style examples, so the generated messages follow the
+// dominant house style. The git repository is read from the given directory (it may differ from [WithGitDir]).
+func WithMimicTopContributor(dirPath string) Option {
+	return func(o *options) { o.MimicRepoDir = dirPath }
+}
+
+// WithDescribeAsAuthor personalizes the message for the author (e.g. on the shared checkout, where several people
+// stage the changes): the model is asked to write as the author would, and the recent commit subjects of the author
+// (up to 15, read from the [WithGitDir] repository) are added to the prompt as the style examples. The diff is not
+// filtered by the author.
+func WithDescribeAsAuthor(name string) Option { return func(o *options) { o.AuthorName = name } }
+
+// WithRecentCommits adds the full messages (the subjects and the bodies) of the given number of the recent commits
+// of the [WithGitDir] repository to the prompt as the style examples, so the model follows the commit style of the
+// repository. Unlike the commits passed to the query (the subjects only), the bodies are included too. Nothing is
+// added for the repository without commits. Disabled by default.
+func WithRecentCommits(n int) Option { return func(o *options) { o.RecentCommits = n } }
+
+// WithTemperature sets the sampling temperature, within [0, 2]: the higher values make the messages more creative,
+// the lower ones - more focused and deterministic. The default is 0.1.
+func WithTemperature(t float64) Option { return func(o *options) { o.Temperature = &t } }
+
+// WithTopP sets the nucleus sampling (top_p) value, within (0, 1]: only the tokens comprising the top_p probability
+// mass are considered. The default is 0.1.
+func WithTopP(p float64) Option { return func(o *options) { o.TopP = &p } }
+
+// WithStopSequences sets the sequences (up to 4 for most models) the model stops generating at, e.g. to cut off the
+// models rambling past the commit body. Only the OpenAI and OpenRouter providers support it; the sequences are not
+// sent by default.
+func WithStopSequences(seqs ...string) Option { return func(o *options) { o.StopSequences = seqs } }
+
+// WithTotalBudget sets the hard time limit of the whole query: all the requests (the retries, the planning pass,
+// the additional candidate rounds, and the fix of the streamed message) and the waits between them must fit into
+// it. Every attempt gets the remaining part of the budget as its deadline, and no new attempts are made once the
+// budget is spent. When exceeded, the error wrapping both [ErrBudgetExceeded] and [context.DeadlineExceeded] is
+// returned. Zero (the default) means no limit.
+func WithTotalBudget(d time.Duration) Option { return func(o *options) { o.TotalBudget = d } }
+
+// WithRetries enables the retries of the transient failures: the rate limit (429), the server-side errors (500,
+// 502, 503, and 504), and the network errors. Up to maxRetries retries are made, with the exponential backoff (starting
+// from the base delay) and jitter between them; the `Retry-After` header of the response is respected when present.
+// The permanent errors (e.g. 400, 401, or 403) are not retried. The context cancellation (or the deadline) stops
+// the retries. Disabled by default.
+func WithRetries(maxRetries int, base time.Duration) Option {
+	return func(o *options) { o.Retries, o.RetryBase = maxRetries, base }
+}
+
+// WithStream enables the streaming mode: the message deltas are passed to the given function as soon as they are
+// generated by the model, and the final (normalized) message is returned as usual. Providers without the streaming
+// support pass the whole message at once. Streaming is not used when several candidates or the JSON output (see
+// [WithConfidence]) are requested.
+//
+// Since the streamed message can't be corrected before it's shown, it's validated (see [Response.Validate]) after
+// the completion. The validation issue is reported as a warning, unless [WithFixInvalidStream] is enabled.
+func WithStream(fn func(delta string)) Option { return func(o *options) { o.Stream = fn } }
+
+// WithCommitlintValidation lints the generated message with the local commitlint (the one installed in the
+// `node_modules` of the repository, see [WithGitDir], is preferred over the global one), using the given config
+// file (empty = the commitlint's default config lookup). If the message is rejected, it's fixed once using an
+// additional request with the linter errors (the diff is not sent again); the remaining problems are reported as
+// warnings. If commitlint is not installed, the message is not linted (a warning is added to the response).
+func WithCommitlintValidation(configPath string) Option {
+	return func(o *options) { o.Commitlint, o.CommitlintConfig = true, configPath }
+}
+
+// WithFixInvalidStream makes a quick non-streaming request to fix the streamed message if it's invalid (see
+// [Response.Validate]). The corrected message is returned as the [Response.Answer] with [Response.Fixed] set, so
+// the caller can replace the streamed output with it.
+func WithFixInvalidStream(on bool) Option { return func(o *options) { o.FixInvalidStream = on } }
+
+// WithStreamFallback makes the streaming request (see [WithStream]) fall back to the non-streaming one if no delta
+// arrives within the grace period (see [WithStreamGracePeriod]), e.g. when a proxy buffers the server-sent events.
+// The stalled request is canceled, and the same request is made without streaming; its answer is passed to the
+// stream function at once.
+func WithStreamFallback(on bool) Option { return func(o *options) { o.StreamFallback = on } }
+
+// WithStreamGracePeriod sets how long to wait for the first streamed delta before falling back to the non-streaming
+// request (see [WithStreamFallback]). Zero means the default (15 seconds).
+func WithStreamGracePeriod(d time.Duration) Option { return func(o *options) { o.StreamGrace = d } }
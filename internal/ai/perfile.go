@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"context"
+	"sync"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+const (
+	perFileConcurrency = 4  // max number of the concurrent queries made by [DescribePerFile]
+	perFileLogLength   = 10 // number of the recent commits passed as context by [DescribePerFile]
+)
+
+// DescribePerFile generates a separate commit message for every staged file in the repository, which is useful for
+// the per-file commit workflows. The result is keyed by the file path. The number of concurrent queries is limited,
+// and the first error cancels the rest.
+func DescribePerFile(ctx context.Context, p Provider, dirPath string, opts ...Option) (map[string]*Response, error) {
+	changes, err := git.Diff(ctx, dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := git.Log(ctx, dirPath, perFileLogLength)
+	if err != nil {
+		commits = "" // the repository may have no commits yet
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		files   = git.SplitPatch(changes)
+		results = make(map[string]*Response, len(files))
+		sem     = make(chan struct{}, perFileConcurrency)
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errOnce sync.Once
+		qErr    error
+	)
+
+	for _, file := range files {
+		select {
+		case <-ctx.Done():
+		case sem <- struct{}{}:
+			wg.Add(1)
+
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+
+				resp, err := p.Query(ctx, file.Text, commits, opts...)
+				if err != nil {
+					errOnce.Do(func() { qErr = err; cancel() })
+
+					return
+				}
+
+				mu.Lock()
+				results[file.Path] = resp
+				mu.Unlock()
+			}()
+		}
+	}
+
+	wg.Wait()
+
+	if qErr != nil {
+		return nil, qErr
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,89 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestDescribePerFile(t *testing.T) {
+	t.Parallel()
+
+	var files = make(map[string]string)
+
+	for i := range 8 {
+		files[fmt.Sprintf("pkg%d/file.go", i)] = fmt.Sprintf("package pkg%d\n", i)
+	}
+
+	var dir = newGitRepo(t, files)
+
+	var inFlight, maxInFlight atomic.Int32
+
+	var provider = providerFunc(func(_ context.Context, changes, _ string, _ ...ai.Option) (*ai.Response, error) {
+		var n = inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			if m := maxInFlight.Load(); n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		var paths = git.ChangedFiles(changes)
+		if len(paths) != 1 {
+			return nil, fmt.Errorf("expected a single file, got %v", paths)
+		}
+
+		return &ai.Response{Answer: "feat: Add " + paths[0]}, nil
+	})
+
+	got, err := ai.DescribePerFile(context.Background(), provider, dir)
+	assertNoError(t, err)
+
+	assertEqual(t, len(got), len(files))
+
+	for path := range files {
+		if resp, ok := got[path]; !ok || resp.Answer != "feat: Add "+path {
+			t.Errorf("unexpected response for %s: %+v", path, resp)
+		}
+	}
+
+	if m := maxInFlight.Load(); m > 4 || m < 1 {
+		t.Errorf("unexpected concurrency: %d", m)
+	}
+}
+
+func TestDescribePerFile_Error(t *testing.T) {
+	t.Parallel()
+
+	var dir = newGitRepo(t, map[string]string{"a.go": "package a\n", "b.go": "package b\n", "c.go": "package c\n"})
+
+	var provider = providerFunc(func(_ context.Context, changes, _ string, _ ...ai.Option) (*ai.Response, error) {
+		if strings.Contains(changes, "b.go") {
+			return nil, errors.New("boom")
+		}
+
+		return &ai.Response{Answer: "feat: Add foo"}, nil
+	})
+
+	if _, err := ai.DescribePerFile(context.Background(), provider, dir); err == nil || err.Error() != "boom" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+
+	cancel()
+
+	if _, err := ai.DescribePerFile(ctx, provider, dir); err == nil {
+		t.Fatal("expected an error for the canceled context")
+	}
+}
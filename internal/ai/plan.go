@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// planPrompt returns the instructions for the first pass of the "plan then write" mode.
+func planPrompt() string {
+	var b strings.Builder
+
+	b.WriteString("## Role\n")
+	b.WriteString("You are an AI assistant analyzing changes made in a Git repository.\n\n")
+	b.WriteString("## Task\n")
+	b.WriteString("List the key changes from the provided `git diff` as a short bullet list (one change per ")
+	b.WriteString("line, starting with \"- \"), most important first. Mention what was changed and why, if it's ")
+	b.WriteString("evident from the code. Do not write a commit message.\n\n")
+	b.WriteString("## Input\n")
+	b.WriteString(fmt.Sprintf("The output of `git diff` is wrapped between `%s` and `%s`, ", gitDiffBegin, gitDiffEnd))
+	b.WriteString(fmt.Sprintf(
+		"and the recent history (for context only) between `%s` and `%s`.\n",
+		gitLogBegin, gitLogEnd,
+	))
+
+	return b.String()
+}
+
+// planChanges runs the first pass of the "plan then write" mode, asking the model for the list of key changes.
+func planChanges(ctx context.Context, c completer, changes, commits string, o options) (string, Usage, error) {
+	o.Candidates, o.ShortMessageOnly, o.Confidence, o.Stream, o.SeedMessage = 1, false, false, nil, ""
+
+	if o.MaxOutputTokens == 0 {
+		o.MaxOutputTokens = defaultMaxOutputTokens
+	}
+
+	var prepared, _ = prepareChanges(changes, o)
+
+	res, err := complete(ctx, c, planPrompt(), prepared, commits, o)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("failed to plan the changes: %w", err)
+	}
+
+	if len(res.Answers) == 0 || strings.TrimSpace(res.Answers[0]) == "" {
+		return "", res.Usage, errors.New("failed to plan the changes: empty response")
+	}
+
+	return res.Answers[0], res.Usage, nil
+}
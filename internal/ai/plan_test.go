@@ -0,0 +1,60 @@
+package ai_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestWithPlanThenWrite(t *testing.T) {
+	t.Parallel()
+
+	var client = fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+		if n == 0 {
+			return newHttpResponse(http.StatusOK, `{
+				"choices":[{"message":{"content":"- add the retry loop\n- document the backoff"}}],
+				"usage":{"total_tokens":100}
+			}`), nil
+		}
+
+		return newHttpResponse(http.StatusOK, `{
+			"choices":[{"message":{"content":"feat(http): Retry failed requests"}}],
+			"usage":{"total_tokens":50}
+		}`), nil
+	}}
+
+	resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(&client)).Query(
+		context.Background(), "diff", "log", ai.WithPlanThenWrite(true), ai.WithShortMessageOnly(true),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat(http): Retry failed requests")
+	assertEqual(t, resp.Usage.TotalTokens, 150, "usage of both passes")
+
+	var requests = client.Requests()
+
+	assertEqual(t, len(requests), 2)
+
+	var plan, write = openaiMessages(t, requests[0]), openaiMessages(t, requests[1])
+
+	if !strings.Contains(plan[0], "List the key changes") || !strings.Contains(plan[1], "diff") {
+		t.Errorf("unexpected planning request: %v", plan)
+	}
+
+	for _, want := range []string{"Key changes", "- add the retry loop", "- document the backoff"} {
+		if !strings.Contains(write[0], want) || !strings.Contains(resp.Prompt, want) {
+			t.Errorf("expected the final prompt to contain %q", want)
+		}
+	}
+
+	// disabled by default
+	client = fakeHttpClient{handler: okClient("feat: Add foo").handler}
+
+	_, err = ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(&client)).Query(context.Background(), "diff", "log")
+	assertNoError(t, err)
+
+	assertEqual(t, len(client.Requests()), 1)
+}
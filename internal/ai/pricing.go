@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is the price of the model usage, in USD per 1M tokens.
+type ModelPrice struct {
+	Input, Output float64
+}
+
+// Cost returns the cost (in USD) of the given number of the input and output tokens.
+func (p ModelPrice) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1_000_000 //nolint:mnd
+}
+
+// modelPrices are the public list prices of the popular models (USD per 1M tokens), keyed by the model name prefix.
+var modelPrices = map[string]ModelPrice{ //nolint:gochecknoglobals
+	"gpt-4o":                {Input: 2.5, Output: 10},
+	"gpt-4o-mini":           {Input: 0.15, Output: 0.6},
+	"gpt-4.1":               {Input: 2, Output: 8},
+	"gpt-4.1-mini":          {Input: 0.4, Output: 1.6},
+	"gpt-4.1-nano":          {Input: 0.1, Output: 0.4},
+	"o3-mini":               {Input: 1.1, Output: 4.4},
+	"o4-mini":               {Input: 1.1, Output: 4.4},
+	"gemini-1.5-flash":      {Input: 0.075, Output: 0.3},
+	"gemini-1.5-pro":        {Input: 1.25, Output: 5},
+	"gemini-2.0-flash":      {Input: 0.1, Output: 0.4},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.3},
+	"gemini-2.5-flash":      {Input: 0.3, Output: 2.5},
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"claude-3-5-haiku":      {Input: 0.8, Output: 4},
+	"claude-3-5-sonnet":     {Input: 3, Output: 15},
+	"claude-3-7-sonnet":     {Input: 3, Output: 15},
+	"claude-sonnet-4":       {Input: 3, Output: 15},
+}
+
+// LookupPrice returns the list price of the model. The OpenRouter-style names (e.g. "openai/gpt-4o") are supported,
+// and the dated versions (e.g. "gpt-4o-2024-08-06") match their base model. False is returned for unknown models.
+func LookupPrice(model string) (ModelPrice, bool) {
+	model = strings.ToLower(model)
+
+	if i := strings.LastIndexByte(model, '/'); i >= 0 {
+		model = model[i+1:]
+	}
+
+	var (
+		price ModelPrice
+		found string
+	)
+
+	for prefix, p := range modelPrices { // the longest prefix wins (e.g. "gpt-4o-mini" over "gpt-4o")
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(found) {
+			price, found = p, prefix
+		}
+	}
+
+	return price, found != ""
+}
+
+// fitCandidatesCost reduces the number of candidates to fit the cost limit (see [WithMaxCandidateCost]). The output
+// (estimated as the maximum number of the output tokens) is paid once per candidate, and so is the input, unless
+// the provider generates all the candidates using a single request (batched). The warning is returned if the limit
+// can't be enforced (the price of the model is unknown).
+func fitCandidatesCost(model, instructions, changes, commits string, batched bool, o *options) (string, error) {
+	price, ok := LookupPrice(model)
+	if !ok {
+		return fmt.Sprintf("the candidates cost limit is not enforced: the price of the model %q is unknown", model), nil
+	}
+
+	var (
+		tok   = o.tokenizer(model)
+		input = tok.Count(instructions)
+	)
+
+	for _, turn := range userTurns(changes, commits, *o) {
+		input += tok.Count(turn)
+	}
+
+	var (
+		inputCost     = price.Cost(input, 0) // paid once (batched)
+		candidateCost = price.Cost(0, int(o.MaxOutputTokens))
+	)
+
+	if !batched { // the input is sent with every candidate request
+		inputCost, candidateCost = 0, candidateCost+inputCost
+	}
+
+	if inputCost+candidateCost > o.MaxCandidateCost {
+		return "", fmt.Errorf("%w: a single candidate is estimated at $%.4f, the limit is $%.4f",
+			ErrCostLimitExceeded, inputCost+candidateCost, o.MaxCandidateCost,
+		)
+	}
+
+	if candidateCost > 0 {
+		o.Candidates = min(o.Candidates, int((o.MaxCandidateCost-inputCost)/candidateCost))
+	}
+
+	return "", nil
+}
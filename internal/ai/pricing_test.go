@@ -0,0 +1,150 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestLookupPrice(t *testing.T) {
+	t.Parallel()
+
+	for model, want := range map[string]ai.ModelPrice{
+		"gpt-4o":                    {Input: 2.5, Output: 10},
+		"gpt-4o-mini":               {Input: 0.15, Output: 0.6},
+		"gpt-4o-2024-08-06":         {Input: 2.5, Output: 10},
+		"openai/gpt-4o-mini":        {Input: 0.15, Output: 0.6},
+		"Gemini-2.0-Flash-Lite":     {Input: 0.075, Output: 0.3},
+		"gemini-2.0-flash-001":      {Input: 0.1, Output: 0.4},
+		"anthropic/claude-sonnet-4": {Input: 3, Output: 15},
+	} {
+		t.Run(model, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := ai.LookupPrice(model)
+
+			assertEqual(t, ok, true)
+			assertEqual(t, got, want)
+		})
+	}
+
+	if _, ok := ai.LookupPrice("my-local-model"); ok {
+		t.Error("expected the unknown model")
+	}
+
+	assertEqual(t, ai.ModelPrice{Input: 2, Output: 8}.Cost(500_000, 250_000), 3.0)
+}
+
+func TestQuery_MaxCandidateCost(t *testing.T) {
+	t.Parallel()
+
+	// with 100K output tokens, every "gpt-4o" candidate costs $1; the input is negligible (less than a cent)
+	const maxOutputTokens = 100_000
+
+	for name, tc := range map[string]struct {
+		giveModel      string
+		giveCandidates int
+		giveBudget     float64
+		wantN          int
+		wantErr        bool
+		wantWarnings   int
+	}{
+		"fits":            {giveModel: "gpt-4o", giveCandidates: 5, giveBudget: 10, wantN: 5},
+		"reduced":         {giveModel: "gpt-4o", giveCandidates: 5, giveBudget: 3.5, wantN: 3},
+		"reduced to one":  {giveModel: "gpt-4o", giveCandidates: 3, giveBudget: 1.5, wantN: 1},
+		"cheaper model":   {giveModel: "gpt-4o-mini", giveCandidates: 5, giveBudget: 0.5, wantN: 5},
+		"single too much": {giveModel: "gpt-4o", giveCandidates: 2, giveBudget: 0.5, wantErr: true},
+		"unknown price":   {giveModel: "my-model", giveCandidates: 4, giveBudget: 0.01, wantN: 4, wantWarnings: 1},
+		"no limit":        {giveModel: "gpt-4o", giveCandidates: 4, wantN: 4},
+		"default single":  {giveModel: "gpt-4o", giveBudget: 1.5, wantN: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat: Add foo")
+
+			resp, err := ai.NewOpenAI("key", tc.giveModel, ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), "diff", "log",
+					ai.WithCandidates(tc.giveCandidates),
+					ai.WithMaxOutputTokens(maxOutputTokens),
+					ai.WithMaxCandidateCost(tc.giveBudget),
+				)
+
+			if tc.wantErr {
+				if !errors.Is(err, ai.ErrCostLimitExceeded) {
+					t.Fatalf("expected %v, got %v", ai.ErrCostLimitExceeded, err)
+				}
+
+				assertEqual(t, len(client.Requests()), 0, "no requests")
+
+				return
+			}
+
+			assertNoError(t, err)
+
+			assertEqual(t, strings.Contains(client.Requests()[0], fmt.Sprintf(`"n":%d,`, tc.wantN)), true, "n")
+			assertEqual(t, len(resp.Warnings), tc.wantWarnings, "warnings")
+		})
+	}
+}
+
+// markerTokenizer counts 100K tokens for every text containing the marker, and none for the others.
+type markerTokenizer string
+
+func (m markerTokenizer) Count(text string) int {
+	if strings.Contains(text, string(m)) {
+		return 100_000
+	}
+
+	return 0
+}
+
+func TestQuery_MaxCandidateCost_PerRequest(t *testing.T) {
+	t.Parallel()
+
+	// Anthropic makes a request per candidate, so the input ($0.3 for 100K "claude-sonnet-4" tokens) is paid for
+	// every candidate, together with the output ($0.015 for 1K tokens)
+	for name, tc := range map[string]struct {
+		giveBudget float64
+		wantN      int
+		wantErr    bool
+	}{
+		"fits":            {giveBudget: 2, wantN: 5},
+		"reduced":         {giveBudget: 1, wantN: 3},
+		"single too much": {giveBudget: 0.3, wantErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return newHttpResponse(http.StatusOK, `{"content":[{"type":"text","text":"feat: Add foo"}]}`), nil
+			}}
+
+			_, err := ai.NewAnthropic("key", "claude-sonnet-4-20250514", ai.WithAnthropicHttpClient(client)).
+				Query(context.Background(), "the-diff", "log",
+					ai.WithCandidates(5),
+					ai.WithMaxOutputTokens(1_000),
+					ai.WithMaxCandidateCost(tc.giveBudget),
+					ai.WithTokenizer(markerTokenizer("the-diff")),
+				)
+
+			if tc.wantErr {
+				if !errors.Is(err, ai.ErrCostLimitExceeded) {
+					t.Fatalf("expected %v, got %v", ai.ErrCostLimitExceeded, err)
+				}
+
+				assertEqual(t, len(client.Requests()), 0, "no requests")
+
+				return
+			}
+
+			assertNoError(t, err)
+			assertEqual(t, len(client.Requests()), tc.wantN, "requests")
+		})
+	}
+}
@@ -3,11 +3,14 @@ package ai
 import (
 	"fmt"
 	"strings"
+	"text/template"
 )
 
 const (
 	gitDiffBegin, gitDiffEnd = "[---GIT-DIFF-BEGIN---]", "[---GIT-DIFF-END---]"
 	gitLogBegin, gitLogEnd   = "[---GIT-LOG-BEGIN---]", "[---GIT-LOG-END---]"
+	seedBegin, seedEnd       = "[---USER-MESSAGE-BEGIN---]", "[---USER-MESSAGE-END---]"
+	messageBegin, messageEnd = "[---COMMIT-MESSAGE-BEGIN---]", "[---COMMIT-MESSAGE-END---]"
 )
 
 // wrapChanges wraps the provided diff output between the specified markers (to help the AI identify the changes).
@@ -20,11 +23,51 @@ func wrapCommits(log string) string {
 	return fmt.Sprintf("%s\n%s\n%s", gitLogBegin, log, gitLogEnd)
 }
 
-func GeneratePrompt(opts ...Option) string { //nolint:funlen
-	var (
-		opt = options{}.Apply(opts...)
-		b   strings.Builder
-	)
+// wrapSeed wraps the partial message written by the user between the specified markers.
+func wrapSeed(seed string) string {
+	return fmt.Sprintf("%s\n%s\n%s", seedBegin, seed, seedEnd)
+}
+
+// userTurns returns the user turns of the conversation: the wrapped changes and commits, followed by the seed
+// message (if any, see [WithSeedMessage]). When translating, the only turn is the wrapped message.
+func userTurns(changes, commits string, o options) []string {
+	if o.TranslateTo != "" {
+		return []string{fmt.Sprintf("%s\n%s\n%s", messageBegin, changes, messageEnd)}
+	}
+
+	var turns = []string{wrapChanges(changes), wrapCommits(commits)}
+
+	if seed := o.seed(); seed != "" {
+		turns = append(turns, wrapSeed(seed))
+	}
+
+	return turns
+}
+
+// GeneratePrompt generates the system prompt (the instructions) for the model. If the custom template is set (see
+// [WithPromptTemplate]), it's rendered instead; the invalid template falls back to the built-in prompt here, and
+// is reported as an error by the query. The generated prompts are memoized by the options affecting them.
+func GeneratePrompt(opts ...Option) string {
+	return cachedPrompt(options{}.Apply(opts...), generatePrompt)
+}
+
+// generatePrompt generates the system prompt for the options (see [GeneratePrompt]).
+func generatePrompt(opt options) string { //nolint:funlen
+	var b strings.Builder
+
+	if opt.TranslateTo != "" {
+		return generateTranslatePrompt(opt)
+	}
+
+	if opt.OutputFormat != FormatCommitMessage {
+		return generateFormatPrompt(opt)
+	}
+
+	if opt.PromptTemplate != "" {
+		if prompt, err := renderPromptTemplate(opt); err == nil {
+			return prompt
+		}
+	}
 
 	b.Grow(2560) //nolint:mnd // pre-allocate memory for the string builder
 
@@ -39,28 +82,30 @@ func GeneratePrompt(opts ...Option) string { //nolint:funlen
 		b.WriteString("## Task\n")
 		b.WriteString("Generate a concise, informative, and well-structured **SINGLE** Git commit ")
 		b.WriteString("message based on the provided input.\n")
+		writeLanguage(&b, opt)
+		writeASCIIOnly(&b, opt)
+		writeContextLabels(&b, opt)
 
 		b.WriteRune('\n')
 	}
 
-	{ // input
-		b.WriteString("## Input\n")
-		b.WriteString("You will receive:\n")
-		b.WriteString(fmt.Sprintf(
-			"1. The output of `git diff`, showing the staged changes, is wrapped between `%s` and `%s`.\n",
-			gitDiffBegin, gitDiffEnd,
-		))
-		b.WriteString(fmt.Sprintf(
-			"2. The output of `git log`, presenting recent commit history, is wrapped between `%s` and `%s`.\n",
-			gitLogBegin, gitLogEnd,
-		))
-		b.WriteRune('\n')
-	}
+	writeInputSection(&b)
+	writeSeedSection(&b, opt)
+	writeContextSection(&b, opt)
 
 	{ // output
 		b.WriteString("## Output\n")
-		b.WriteString("Produce a commit message in plain text without wrapping it in backticks, ")
-		b.WriteString("quotes, or code blocks.\n")
+
+		if opt.jsonOutput() {
+			b.WriteString("Produce a single JSON object (without wrapping it in code blocks) with the fields:\n")
+			b.WriteString("- `message`: the commit message (a string, following the guidelines below);\n")
+			b.WriteString("- `confidence`: how sure you are that the message describes the changes correctly, ")
+			b.WriteString("a number from 0 (guessing) to 1 (certain);\n")
+			b.WriteString("- `rationale`: a short explanation of the confidence (what is ambiguous or unclear).\n")
+		} else {
+			b.WriteString("Produce a commit message in plain text without wrapping it in backticks, ")
+			b.WriteString("quotes, or code blocks.\n")
+		}
 
 		b.WriteRune('\n')
 	}
@@ -104,6 +149,36 @@ func GeneratePrompt(opts ...Option) string { //nolint:funlen
 			b.WriteString(convDesc)
 		}
 
+		if scopes := likelyScopes(opt.ScopeHints); len(scopes) > 0 {
+			b.WriteString("- Likely scopes based on changed files: " + strings.Join(scopes, ", ") + "\n")
+		}
+
+		if opt.AuthorName != "" {
+			b.WriteString("- Write the message as " + opt.AuthorName + " would: follow the wording, length, and ")
+			b.WriteString("structure of their recent commit messages, if they are provided.\n")
+		}
+
+		if opt.MaxSubjectWords > 0 {
+			b.WriteString(fmt.Sprintf("- Keep the `<message>` to at most %d words: state **WHAT** changed in the ",
+				opt.MaxSubjectWords,
+			))
+			b.WriteString("imperative mood (e.g., \"Add rate-limiting\"), leave the details for the body.\n")
+		}
+
+		if opt.DiscourageChore {
+			b.WriteString("- Prefer the most specific `<type>`; use 'chore' only as a last resort, when no other ")
+			b.WriteString("type fits (e.g., for changes that don't touch the source code, tests, docs, or CI).\n")
+		}
+
+		if len(opt.AvoidPatterns) > 0 {
+			b.WriteString("- Avoid the wording, types, and scopes of these commit messages (they were reverted or ")
+			b.WriteString("rejected); do not repeat them:\n")
+
+			for _, pattern := range opt.AvoidPatterns {
+				b.WriteString("  - `" + pattern + "`\n")
+			}
+		}
+
 		if !opt.ShortMessageOnly {
 			b.WriteString("### Commit Message Structure\n")
 			b.WriteString("- **WHAT** and **WHY**: Summarize what was changed and why the change was needed.\n")
@@ -114,13 +189,51 @@ func GeneratePrompt(opts ...Option) string { //nolint:funlen
 			b.WriteString("by a blank line, then a detailed description if necessary.\n")
 			b.WriteString("- **No periods**: Omit periods at the end of each line.\n")
 			b.WriteString("### Commit Body (if necessary)\n")
+
+			if opt.SmartBody {
+				b.WriteString("- Add the body **only** if the change introduces non-obvious behavior, i.e. at least one ")
+				b.WriteString("of: it changes the public API or the user-visible behavior, has side effects or ")
+				b.WriteString("breaking changes, fixes a bug whose cause isn't evident from the subject, or involves ")
+				b.WriteString("trade-offs worth explaining. Otherwise (e.g., typos, renames, formatting, simple ")
+				b.WriteString("additions), output the subject line only.\n")
+			}
+
 			b.WriteString("- Start with a single-line summary.\n")
 			b.WriteString("- Exclude the provided diff output from the commit message.\n")
 			b.WriteString("- For complex changes, add a detailed description after a blank line:\n")
 			b.WriteString("  - Explain additional context or implementation details.\n")
-			b.WriteString("  - Include a summary and key points when necessary.\n")
+
+			if opt.BodyStyle == Paragraph {
+				b.WriteString("  - Write it as a single prose paragraph; do not use bullet points or lists.\n")
+			} else {
+				b.WriteString("  - Include a summary and key points when necessary.\n")
+			}
+
+			if opt.inlineFileNotes() {
+				b.WriteString("  - Prefix every bullet point with the path of the file it relates to, as listed in ")
+				b.WriteString("the changed files (e.g., `- auth.go: add token refresh`); describe the changes of ")
+				b.WriteString("each file in a single bullet point.\n")
+			}
+
 			b.WriteString("  - Avoid excessive detail; provide only what's needed for understanding.\n")
 			b.WriteString("- Avoid starting with \"This commit\"; directly describe the changes.\n")
+
+			if opt.PlainText {
+				b.WriteString("- Write plain text: do not use Markdown formatting (no bold or italic emphasis, inline ")
+				b.WriteString("code in backticks, or code blocks)")
+
+				if opt.BodyStyle != Paragraph {
+					b.WriteString("; simple \"-\" bullet points are allowed")
+				}
+
+				b.WriteString(".\n")
+			}
+
+			if opt.SemverHint {
+				b.WriteString("- End the body with a `Semver: <major|minor|patch|none>` footer (after a blank line) noting ")
+				b.WriteString("the intended version bump: `major` for breaking changes, `minor` for new features, ")
+				b.WriteString("`patch` for fixes, and `none` for everything else.\n")
+			}
 		} else {
 			b.WriteString("### Focus on the primary purpose of the commit\n")
 			b.WriteString("- Summarize all changes in a single, meaningful message.\n")
@@ -128,28 +241,7 @@ func GeneratePrompt(opts ...Option) string { //nolint:funlen
 		}
 
 		b.WriteRune('\n')
-		b.WriteString("**Example**:\n")
-		b.WriteRune('\n')
-		b.WriteString("```\n")
-
-		if opt.EnableEmoji {
-			b.WriteString("✨ ")
-		}
-
-		b.WriteString("feat(api): Add rate-limiting to endpoints\n")
-
-		if !opt.ShortMessageOnly {
-			b.WriteRune('\n')
-			b.WriteString("Implemented rate-limiting on all API endpoints to enhance security by preventing abuse ")
-			b.WriteString("through request limits. Integrated Redis to track API requests, aiding future analytics. ")
-			b.WriteString("Configuration is adjustable via environment variables.\n")
-			b.WriteRune('\n')
-			b.WriteString("- Enforces request limits to prevent abuse\n")
-			b.WriteString("- Utilizes Redis for tracking API requests\n")
-			b.WriteString("- Configurable through environment variables\n")
-		}
-
-		b.WriteString("```\n")
+		writeExample(&b, opt)
 
 		b.WriteRune('\n')
 	}
@@ -159,6 +251,16 @@ func GeneratePrompt(opts ...Option) string { //nolint:funlen
 		b.WriteString("- Exclude sensitive data (passwords, API keys, personal information, etc.) ")
 		b.WriteString("or code snippets from the commit message.\n")
 
+		if opt.SecurityReview && !opt.ShortMessageOnly {
+			b.WriteString("- Review the changes for the security impact: authentication and authorization, ")
+			b.WriteString("cryptography, input validation and sanitization, secrets handling, and permissions. ")
+			b.WriteString("If any change is potentially security-relevant, end the body with a ")
+			b.WriteString("`Security-relevant: <short description>` footer (after a blank line) naming the affected ")
+			b.WriteString("area (e.g., `Security-relevant: token validation`); omit the footer otherwise.\n")
+			b.WriteString("- Do not leak the specifics in the footer: no exploit details, vulnerable inputs, ")
+			b.WriteString("or attack steps.\n")
+		}
+
 		b.WriteRune('\n')
 	}
 
@@ -174,3 +276,152 @@ func GeneratePrompt(opts ...Option) string { //nolint:funlen
 
 	return b.String()
 }
+
+// writeInputSection writes the description of the input (the wrapped diff and log).
+func writeInputSection(b *strings.Builder) {
+	b.WriteString("## Input\n")
+	b.WriteString("You will receive:\n")
+	b.WriteString(fmt.Sprintf(
+		"1. The output of `git diff`, showing the staged changes, is wrapped between `%s` and `%s`.\n",
+		gitDiffBegin, gitDiffEnd,
+	))
+	b.WriteString(fmt.Sprintf(
+		"2. The output of `git log`, presenting recent commit history, is wrapped between `%s` and `%s`.\n",
+		gitLogBegin, gitLogEnd,
+	))
+	b.WriteRune('\n')
+}
+
+// writeSeedSection writes the instructions on using the partial message written by the user (if any).
+func writeSeedSection(b *strings.Builder, opt options) {
+	if opt.seed() == "" {
+		return
+	}
+
+	b.WriteString("## User's Draft\n")
+	b.WriteString(fmt.Sprintf(
+		"The user has already started writing the commit message; the draft is wrapped between `%s` and `%s`. ",
+		seedBegin, seedEnd,
+	))
+	b.WriteString("Use it as the starting point: complete and refine it rather than ignore it. Respect the user's ")
+	b.WriteString("evident intent (the described change, the type, the scope, and the wording) as long as it ")
+	b.WriteString("matches the changes, and fix only what does not follow the guidelines.\n")
+	b.WriteRune('\n')
+}
+
+// writeContextLabels writes the context labels line (if any).
+func writeContextLabels(b *strings.Builder, opt options) {
+	if len(opt.ContextLabels) > 0 {
+		b.WriteString("Context: " + strings.Join(opt.ContextLabels, ", ") + "\n")
+	}
+}
+
+// isoLanguages maps the ISO 639-1 codes of the common languages to their names.
+var isoLanguages = map[string]string{ //nolint:gochecknoglobals
+	"ar": "Arabic", "bg": "Bulgarian", "cs": "Czech", "da": "Danish", "de": "German", "el": "Greek", "en": "English",
+	"es": "Spanish", "et": "Estonian", "fa": "Persian", "fi": "Finnish", "fr": "French", "he": "Hebrew",
+	"hi": "Hindi", "hr": "Croatian", "hu": "Hungarian", "id": "Indonesian", "it": "Italian", "ja": "Japanese",
+	"ko": "Korean", "lt": "Lithuanian", "lv": "Latvian", "nl": "Dutch", "no": "Norwegian", "pl": "Polish",
+	"pt": "Portuguese", "ro": "Romanian", "ru": "Russian", "sk": "Slovak", "sl": "Slovenian", "sr": "Serbian",
+	"sv": "Swedish", "th": "Thai", "tr": "Turkish", "uk": "Ukrainian", "vi": "Vietnamese", "zh": "Chinese",
+}
+
+// languageName returns the name of the language given as the ISO 639-1 code (with an optional region, e.g.
+// "pt-BR") or as a plain name. Unknown values are returned as is.
+func languageName(lang string) string {
+	lang = strings.TrimSpace(lang)
+
+	code, region, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+
+	if name, ok := isoLanguages[strings.ToLower(code)]; ok {
+		if region != "" {
+			return name + " (" + strings.ToUpper(region) + ")"
+		}
+
+		return name
+	}
+
+	return lang
+}
+
+// writeLanguage writes the instruction on the language of the message (if set).
+func writeLanguage(b *strings.Builder, opt options) {
+	if name := languageName(opt.Language); name != "" {
+		b.WriteString("Write the commit message in " + name + " (keep the `<type>` and `<scope>` in English).\n")
+	}
+}
+
+// writeASCIIOnly writes the instruction to use the ASCII characters only (if enabled).
+func writeASCIIOnly(b *strings.Builder, opt options) {
+	if opt.ASCIIOnly {
+		b.WriteString("Use only the ASCII characters in the commit message: no emoji, accented letters, or ")
+		b.WriteString("typographic quotes and dashes (e.g., write \"cafe\" instead of \"café\").\n")
+	}
+}
+
+// writeContextSection writes the additional context (if any).
+func writeContextSection(b *strings.Builder, opt options) {
+	if len(opt.extraContext) == 0 {
+		return
+	}
+
+	b.WriteString("## Context\n")
+
+	for _, block := range opt.extraContext {
+		b.WriteString("### ")
+		b.WriteString(block.Title)
+		b.WriteRune('\n')
+		b.WriteString(strings.TrimRight(block.Text, "\n"))
+		b.WriteRune('\n')
+	}
+
+	b.WriteRune('\n')
+}
+
+// writeExample writes the example of the commit message, matching the options.
+func writeExample(b *strings.Builder, opt options) {
+	b.WriteString("**Example**:\n")
+	b.WriteRune('\n')
+	b.WriteString("```\n")
+
+	if opt.EnableEmoji {
+		b.WriteString("✨ ")
+	}
+
+	b.WriteString("feat(api): Add rate-limiting to endpoints\n")
+
+	if !opt.ShortMessageOnly {
+		b.WriteRune('\n')
+		b.WriteString("Implemented rate-limiting on all API endpoints to enhance security by preventing abuse ")
+		b.WriteString("through request limits. Integrated Redis to track API requests, aiding future analytics. ")
+		b.WriteString("Configuration is adjustable via environment variables.\n")
+
+		if opt.BodyStyle != Paragraph {
+			b.WriteRune('\n')
+			b.WriteString("- Enforces request limits to prevent abuse\n")
+			b.WriteString("- Utilizes Redis for tracking API requests\n")
+			b.WriteString("- Configurable through environment variables\n")
+		}
+	}
+
+	b.WriteString("```\n")
+}
+
+// renderPromptTemplate renders the custom prompt template (see [WithPromptTemplate]).
+func renderPromptTemplate(opt options) (string, error) {
+	tmpl, err := template.New("prompt").Parse(opt.PromptTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	var b strings.Builder
+
+	if err = tmpl.Execute(&b, struct{ ShortMessageOnly, EnableEmoji bool }{
+		ShortMessageOnly: opt.ShortMessageOnly,
+		EnableEmoji:      opt.EnableEmoji,
+	}); err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	return b.String(), nil
+}
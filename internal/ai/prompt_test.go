@@ -1,6 +1,7 @@
 package ai_test
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -110,3 +111,396 @@ func TestGeneratePrompt(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratePrompt_SemverHint(t *testing.T) {
+	t.Parallel()
+
+	const hint = "`Semver: <major|minor|patch|none>` footer"
+
+	if got := ai.GeneratePrompt(); strings.Contains(got, hint) {
+		t.Errorf("want %q to not contain %q", got, hint)
+	}
+
+	if got := ai.GeneratePrompt(ai.WithSemverHint(true)); !strings.Contains(got, hint) {
+		t.Errorf("want %q to contain %q", got, hint)
+	}
+
+	if got := ai.GeneratePrompt(ai.WithSemverHint(true), ai.WithShortMessageOnly(true)); strings.Contains(got, hint) {
+		t.Errorf("want %q to not contain %q", got, hint)
+	}
+}
+
+func TestGeneratePrompt_GitNote(t *testing.T) {
+	t.Parallel()
+
+	var got = ai.GeneratePrompt(ai.WithOutputFormat(ai.FormatGitNote), ai.WithShortMessageOnly(true))
+
+	for _, want := range []string{
+		"**SINGLE** Git note", "`git notes add -F -`", "must not repeat the commit subject",
+		"Input", "git diff", "git log",
+		"Note Guidelines", "Explain **why**", "alternatives that were considered", "Wrap lines at 72 characters",
+		"Do not use emojis", "Security",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q to contain %q", got, want)
+		}
+	}
+
+	for _, want := range []string{"Commit Message Structure", "`<type>(<scope>): <message>`"} {
+		if strings.Contains(got, want) {
+			t.Errorf("want %q to not contain %q", got, want)
+		}
+	}
+}
+
+func TestGeneratePrompt_ContextLabels(t *testing.T) {
+	t.Parallel()
+
+	if got := ai.GeneratePrompt(); strings.Contains(got, "Context:") {
+		t.Errorf("want %q to not contain the context labels", got)
+	}
+
+	for _, format := range []ai.OutputFormat{ai.FormatCommitMessage, ai.FormatGitNote, ai.FormatReviewComment} {
+		var got = ai.GeneratePrompt(
+			ai.WithOutputFormat(format),
+			ai.WithContextLabels("backend", " ", "database"),
+		)
+
+		if want := "\nContext: backend, database\n"; !strings.Contains(got, want) {
+			t.Errorf("want %q to contain %q", got, want)
+		}
+	}
+}
+
+func TestGeneratePrompt_DiscourageChore(t *testing.T) {
+	t.Parallel()
+
+	const hint = "use 'chore' only as a last resort"
+
+	if got := ai.GeneratePrompt(); strings.Contains(got, hint) {
+		t.Errorf("want %q to not contain %q", got, hint)
+	}
+
+	for _, short := range []bool{false, true} {
+		if got := ai.GeneratePrompt(ai.WithDiscourageChore(true), ai.WithShortMessageOnly(short)); !strings.Contains(got, hint) {
+			t.Errorf("want %q to contain %q", got, hint)
+		}
+	}
+}
+
+func TestGeneratePrompt_SmartBody(t *testing.T) {
+	t.Parallel()
+
+	const hint = "Add the body **only** if the change introduces non-obvious behavior"
+
+	if got := ai.GeneratePrompt(); strings.Contains(got, hint) {
+		t.Errorf("want %q to not contain %q", got, hint)
+	}
+
+	if got := ai.GeneratePrompt(ai.WithSmartBody(true)); !strings.Contains(got, hint) ||
+		!strings.Contains(got, "output the subject line only") {
+		t.Errorf("want %q to contain the smart body criterion", got)
+	}
+
+	if got := ai.GeneratePrompt(ai.WithSmartBody(true), ai.WithShortMessageOnly(true)); strings.Contains(got, hint) {
+		t.Errorf("want %q to not contain %q", got, hint)
+	}
+}
+
+func TestGeneratePrompt_PlainText(t *testing.T) {
+	t.Parallel()
+
+	const hint = "do not use Markdown formatting"
+
+	if got := ai.GeneratePrompt(); strings.Contains(got, hint) {
+		t.Errorf("want %q to not contain %q", got, hint)
+	}
+
+	if got := ai.GeneratePrompt(ai.WithPlainText(true)); !strings.Contains(got, hint) {
+		t.Errorf("want %q to contain %q", got, hint)
+	}
+}
+
+func TestGeneratePrompt_BodyStyle(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bullets   = "Include a summary and key points when necessary"
+		paragraph = "Write it as a single prose paragraph; do not use bullet points or lists"
+		example   = "- Enforces request limits to prevent abuse"
+	)
+
+	for name, tc := range map[string]struct {
+		giveOpts      []ai.Option
+		wantBullets   bool
+		wantParagraph bool
+	}{
+		"default":     {wantBullets: true},
+		"bullet list": {giveOpts: []ai.Option{ai.WithBodyStyle(ai.BulletList)}, wantBullets: true},
+		"paragraph":   {giveOpts: []ai.Option{ai.WithBodyStyle(ai.Paragraph)}, wantParagraph: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got = ai.GeneratePrompt(tc.giveOpts...)
+
+			assertEqual(t, strings.Contains(got, bullets), tc.wantBullets, "bullets guidance")
+			assertEqual(t, strings.Contains(got, example), tc.wantBullets, "bullets example")
+			assertEqual(t, strings.Contains(got, paragraph), tc.wantParagraph, "paragraph guidance")
+		})
+	}
+}
+
+func TestGeneratePrompt_BodyStyleWithPlainText(t *testing.T) {
+	t.Parallel()
+
+	const hint = "bullet points are allowed"
+
+	if got := ai.GeneratePrompt(ai.WithPlainText(true)); !strings.Contains(got, hint) {
+		t.Errorf("want %q to contain %q", got, hint)
+	}
+
+	if got := ai.GeneratePrompt(ai.WithPlainText(true), ai.WithBodyStyle(ai.Paragraph)); strings.Contains(got, hint) {
+		t.Errorf("want %q to not contain %q", got, hint)
+	}
+}
+
+func TestGeneratePrompt_InlineFileNotes(t *testing.T) {
+	t.Parallel()
+
+	const hint = "Prefix every bullet point with the path of the file it relates to"
+
+	for name, tc := range map[string]struct {
+		giveOpts []ai.Option
+		want     bool
+	}{
+		"default":    {},
+		"enabled":    {giveOpts: []ai.Option{ai.WithInlineFileNotes(true)}, want: true},
+		"short only": {giveOpts: []ai.Option{ai.WithInlineFileNotes(true), ai.WithShortMessageOnly(true)}},
+		"paragraph":  {giveOpts: []ai.Option{ai.WithInlineFileNotes(true), ai.WithBodyStyle(ai.Paragraph)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assertEqual(t, strings.Contains(ai.GeneratePrompt(tc.giveOpts...), hint), tc.want)
+		})
+	}
+}
+
+func TestGeneratePrompt_Language(t *testing.T) {
+	t.Parallel()
+
+	var defaultPrompt = ai.GeneratePrompt()
+
+	assertEqual(t, strings.Contains(defaultPrompt, "Write the commit message in"), false)
+	assertEqual(t, ai.GeneratePrompt(ai.WithLanguage("")), defaultPrompt, "empty language")
+	assertEqual(t, ai.GeneratePrompt(ai.WithLanguage(" ")), defaultPrompt, "blank language")
+
+	for give, want := range map[string]string{
+		"es":      "Write the commit message in Spanish (keep the `<type>` and `<scope>` in English).\n",
+		"DE":      "Write the commit message in German (",
+		"pt-br":   "Write the commit message in Portuguese (BR) (",
+		"zh_TW":   "Write the commit message in Chinese (TW) (",
+		"German":  "Write the commit message in German (",
+		"Klingon": "Write the commit message in Klingon (",
+	} {
+		t.Run(give, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ai.GeneratePrompt(ai.WithLanguage(give)); !strings.Contains(got, want) {
+				t.Errorf("want %q to contain %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGeneratePrompt_MaxSubjectWords(t *testing.T) {
+	t.Parallel()
+
+	assertEqual(t, strings.Contains(ai.GeneratePrompt(), "at most"), false, "no limit by default")
+
+	var got = ai.GeneratePrompt(ai.WithMaxSubjectWords(5))
+
+	if want := "- Keep the `<message>` to at most 5 words: state **WHAT** changed"; !strings.Contains(got, want) {
+		t.Errorf("want %q to contain %q", got, want)
+	}
+}
+
+func TestGeneratePrompt_Template(t *testing.T) {
+	t.Parallel()
+
+	const tmpl = "Write the message{{if .ShortMessageOnly}} subject only{{end}}{{if .EnableEmoji}} with emoji{{end}}."
+
+	for name, tc := range map[string]struct {
+		giveOpts []ai.Option
+		want     string
+	}{
+		"plain":    {want: "Write the message."},
+		"short":    {giveOpts: []ai.Option{ai.WithShortMessageOnly(true)}, want: "Write the message subject only."},
+		"emoji":    {giveOpts: []ai.Option{ai.WithEmoji(true)}, want: "Write the message with emoji."},
+		"no value": {giveOpts: []ai.Option{ai.WithPromptTemplate("")}, want: ai.GeneratePrompt()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts = append([]ai.Option{ai.WithPromptTemplate(tmpl)}, tc.giveOpts...)
+
+			assertEqual(t, ai.GeneratePrompt(opts...), tc.want)
+		})
+	}
+}
+
+func TestQuery_InvalidPromptTemplate(t *testing.T) {
+	t.Parallel()
+
+	for name, tmpl := range map[string]string{
+		"parse error":   "{{if .EnableEmoji}}unclosed",
+		"unknown field": "{{.Foo}}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat: Add foo")
+
+			_, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), "diff", "log", ai.WithPromptTemplate(tmpl))
+
+			if err == nil || !strings.Contains(err.Error(), "invalid prompt template") {
+				t.Fatalf("expected the template error, got %v", err)
+			}
+
+			assertEqual(t, len(client.Requests()), 0)
+		})
+	}
+
+	// the valid template is sent as is
+	var client = okClient("feat: Add foo")
+
+	resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+		Query(context.Background(), "diff", "log", ai.WithPromptTemplate("Describe the changes."))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Prompt, "Describe the changes.")
+	assertEqual(t, openaiMessages(t, client.Requests()[0])[0], "Describe the changes.")
+}
+
+func TestGeneratePrompt_AvoidPatterns(t *testing.T) {
+	t.Parallel()
+
+	assertEqual(t, strings.Contains(ai.GeneratePrompt(), "were reverted"), false, "nothing to avoid by default")
+
+	var got = ai.GeneratePrompt(ai.WithAvoidPatterns([]string{"feat(api): Add foo", "fix: Tweak bar"}))
+
+	for _, want := range []string{
+		"- Avoid the wording, types, and scopes of these commit messages (they were reverted or rejected)",
+		"  - `feat(api): Add foo`\n  - `fix: Tweak bar`\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q to contain %q", got, want)
+		}
+	}
+}
+
+func TestGeneratePrompt_ScopeHints(t *testing.T) {
+	t.Parallel()
+
+	assertEqual(t, strings.Contains(ai.GeneratePrompt(), "Likely scopes"), false, "no hints by default")
+
+	var got = ai.GeneratePrompt(ai.WithScopeHints([]string{"internal/ai/query.go", "api/a.go", "api/b.go", "go.mod"}))
+
+	if want := "- Likely scopes based on changed files: api, internal/ai\n"; !strings.Contains(got, want) {
+		t.Errorf("want %q to contain %q", got, want)
+	}
+}
+
+func TestGeneratePrompt_DescribeAsAuthor(t *testing.T) {
+	t.Parallel()
+
+	assertEqual(t, strings.Contains(ai.GeneratePrompt(), "Write the message as"), false, "not personalized by default")
+
+	var got = ai.GeneratePrompt(ai.WithDescribeAsAuthor("Jane Doe"))
+
+	if want := "- Write the message as Jane Doe would: follow the wording, length, and structure of their recent " +
+		"commit messages"; !strings.Contains(got, want) {
+		t.Errorf("want %q to contain %q", got, want)
+	}
+}
+
+func TestQuery_AvoidReverted(t *testing.T) {
+	t.Parallel()
+
+	const log = "fix: Fix bar\nRevert \"feat(api): Add the rate-limiting\"\nfeat(api): Add the rate-limiting\n"
+
+	var provider = ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(okClient("feat: Add foo")))
+
+	resp, err := provider.Query(context.Background(), "diff", log, ai.WithAvoidReverted(true))
+	assertNoError(t, err)
+
+	if !strings.Contains(resp.Prompt, "rejected); do not repeat them:\n  - `feat(api): Add the rate-limiting`\n") {
+		t.Errorf("expected the reverted commit to be avoided:\n%s", resp.Prompt)
+	}
+
+	// disabled by default
+	resp, err = provider.Query(context.Background(), "diff", log)
+	assertNoError(t, err)
+
+	assertEqual(t, strings.Contains(resp.Prompt, "do not repeat them"), false)
+}
+
+func TestGeneratePrompt_ASCIIOnly(t *testing.T) {
+	t.Parallel()
+
+	assertEqual(t, strings.Contains(ai.GeneratePrompt(), "ASCII"), false, "disabled by default")
+
+	for _, opts := range [][]ai.Option{
+		{ai.WithASCIIOnly(true), ai.WithEmoji(true)},
+		{ai.WithEmoji(true), ai.WithASCIIOnly(true)},
+	} {
+		var got = ai.GeneratePrompt(opts...)
+
+		if want := "Use only the ASCII characters in the commit message"; !strings.Contains(got, want) {
+			t.Errorf("want %q to contain %q", got, want)
+		}
+
+		assertEqual(t, got, ai.GeneratePrompt(ai.WithASCIIOnly(true)), "the emoji are disabled")
+	}
+}
+
+func TestGeneratePrompt_Locale(t *testing.T) {
+	t.Parallel()
+
+	const want = "according to the `de-DE` locale conventions"
+
+	var note = ai.GeneratePrompt(ai.WithOutputFormat(ai.FormatGitNote), ai.WithLocale("de-DE"))
+
+	if !strings.Contains(note, "Format the dates, numbers, and versions mentioned in the note "+want) {
+		t.Errorf("want %q to contain the locale", note)
+	}
+
+	assertEqual(t, strings.Contains(ai.GeneratePrompt(ai.WithOutputFormat(ai.FormatGitNote)), "locale"), false, "unset")
+	assertEqual(t, ai.GeneratePrompt(ai.WithLocale("de-DE")), ai.GeneratePrompt(), "no-op for the commit messages")
+}
+
+func TestGeneratePrompt_SecurityReview(t *testing.T) {
+	t.Parallel()
+
+	var got = ai.GeneratePrompt(ai.WithSecurityReview(true))
+
+	for _, want := range []string{
+		"Review the changes for the security impact: authentication and authorization, cryptography, input validation",
+		"end the body with a `Security-relevant: <short description>` footer",
+		"Do not leak the specifics in the footer",
+		"Exclude sensitive data", // the existing guideline is kept
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q to contain %q", got, want)
+		}
+	}
+
+	assertEqual(t, strings.Contains(ai.GeneratePrompt(), "Security-relevant"), false, "disabled by default")
+	assertEqual(t,
+		ai.GeneratePrompt(ai.WithShortMessageOnly(true), ai.WithSecurityReview(true)),
+		ai.GeneratePrompt(ai.WithShortMessageOnly(true)),
+		"no-op for the short message",
+	)
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxPromptCacheSize limits the number of the prompts kept in the [promptCache]; the cache is reset when it's full.
+const maxPromptCacheSize = 64
+
+// promptCache memoizes the generated prompts by the options affecting them, so the repeated queries (e.g. in a
+// batch) don't rebuild the same prompt.
+var promptCache = struct { //nolint:gochecknoglobals
+	sync.Mutex
+	m map[promptKey]string
+}{m: make(map[promptKey]string)}
+
+// promptKey is the comparable set of the options the prompt depends on. Every option read by the prompt generators
+// must be added here, or the prompts generated for different options get mixed up.
+type promptKey struct {
+	ShortMessageOnly, EnableEmoji, SemverHint, SmartBody, PlainText, DiscourageChore bool
+	InlineFileNotes, JSONOutput, ASCIIOnly, SecurityReview                           bool
+
+	BodyStyle       BodyStyle
+	OutputFormat    OutputFormat
+	MaxSubjectWords int
+
+	Language, TranslateTo, PromptTemplate, AuthorName, Seed, Locale string
+	ContextLabels, AvoidPatterns, ScopeHints                        string // joined with NUL
+}
+
+// newPromptKey returns the cache key of the prompt for the options.
+func newPromptKey(opt options) promptKey {
+	return promptKey{
+		ShortMessageOnly: opt.ShortMessageOnly,
+		EnableEmoji:      opt.EnableEmoji,
+		SemverHint:       opt.SemverHint,
+		SmartBody:        opt.SmartBody,
+		PlainText:        opt.PlainText,
+		DiscourageChore:  opt.DiscourageChore,
+		InlineFileNotes:  opt.inlineFileNotes(),
+		JSONOutput:       opt.jsonOutput(),
+		ASCIIOnly:        opt.ASCIIOnly,
+		SecurityReview:   opt.SecurityReview,
+		BodyStyle:        opt.BodyStyle,
+		OutputFormat:     opt.OutputFormat,
+		MaxSubjectWords:  opt.MaxSubjectWords,
+		Language:         opt.Language,
+		TranslateTo:      opt.TranslateTo,
+		PromptTemplate:   opt.PromptTemplate,
+		AuthorName:       opt.AuthorName,
+		Seed:             opt.seed(),
+		Locale:           opt.Locale,
+		ContextLabels:    strings.Join(opt.ContextLabels, "\x00"),
+		AvoidPatterns:    strings.Join(opt.AvoidPatterns, "\x00"),
+		ScopeHints:       strings.Join(opt.ScopeHints, "\x00"),
+	}
+}
+
+// cachedPrompt returns the prompt generated by the function for the options, memoized in the [promptCache]. The
+// prompts with the additional context are not cached, since the context differs from query to query.
+func cachedPrompt(opt options, generate func(options) string) string {
+	if len(opt.extraContext) > 0 {
+		return generate(opt)
+	}
+
+	var key = newPromptKey(opt)
+
+	promptCache.Lock()
+	prompt, ok := promptCache.m[key]
+	promptCache.Unlock()
+
+	if ok {
+		return prompt
+	}
+
+	prompt = generate(opt)
+
+	promptCache.Lock()
+	defer promptCache.Unlock()
+
+	if len(promptCache.m) >= maxPromptCacheSize {
+		clear(promptCache.m)
+	}
+
+	promptCache.m[key] = prompt
+
+	return prompt
+}
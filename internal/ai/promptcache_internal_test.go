@@ -0,0 +1,46 @@
+package ai
+
+import "testing"
+
+func TestGeneratePrompt_Cached(t *testing.T) { // not parallel: the cache is shared
+	var opts = []Option{WithEmoji(true), WithLanguage("cache-test")}
+
+	var first = GeneratePrompt(opts...)
+
+	promptCache.Lock()
+	cached, ok := promptCache.m[newPromptKey(options{}.Apply(opts...))]
+	promptCache.m[newPromptKey(options{}.Apply(opts...))] = "cached"
+	promptCache.Unlock()
+
+	assertEqual(t, ok, true)
+	assertEqual(t, cached, first)
+
+	assertEqual(t, GeneratePrompt(opts...), "cached")
+	assertEqual(t, GeneratePrompt(WithLanguage("cache-test"), WithEmoji(true)), "cached") // the same options
+	assertEqual(t, GeneratePrompt(WithEmoji(false), WithLanguage("cache-test")) != "cached", true)
+
+	// the prompts with the additional context are not cached
+	var withCtx = append(opts[:len(opts):len(opts)], withContext("Title", "text"))
+
+	if got := GeneratePrompt(withCtx...); got == "cached" {
+		t.Error("the prompt with the additional context must not be taken from the cache")
+	}
+}
+
+func BenchmarkGeneratePrompt(b *testing.B) {
+	var opts = []Option{WithEmoji(true), WithLanguage("de"), WithContextLabels("backend")}
+
+	b.Run("cached", func(b *testing.B) {
+		for b.Loop() {
+			_ = GeneratePrompt(opts...)
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		var opt = options{}.Apply(opts...)
+
+		for b.Loop() {
+			_ = generatePrompt(opt)
+		}
+	})
+}
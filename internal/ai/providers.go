@@ -2,7 +2,10 @@ package ai
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type (
@@ -10,17 +13,135 @@ type (
 	Provider interface {
 		// Query the remote provider for the given string.
 		Query(_ context.Context, changes, commits string, _ ...Option) (*Response, error)
+
+		// Name returns the name of the provider (e.g. [ProviderOpenAI]).
+		Name() string
+	}
+
+	// StreamingProvider is a provider that can stream the generated message as it's being generated.
+	StreamingProvider interface {
+		Provider
+
+		// QueryStream queries the remote provider, sending the message deltas to the first channel as soon as they
+		// are generated. The second channel receives a single terminal error (if any); both channels are closed on
+		// completion. The caller must drain the deltas channel or cancel the context.
+		QueryStream(_ context.Context, changes, commits string, _ ...Option) (<-chan string, <-chan error)
 	}
 
 	// Response is a response from an AI provider.
 	Response struct {
-		Prompt string // used to generate the answer
-		Answer string // what the AI responded
+		Provider     string   // the name of the provider that generated the answer (see [Provider.Name])
+		Model        string   // the model that generated the answer (empty if no model was used)
+		Prompt       string   // used to generate the answer (with the user turns appended, on the dry run)
+		Answer       string   // what the AI responded (the first of the Answers)
+		Answers      []string // all the candidates, in order (see [WithCandidates])
+		Subject      string   // the first line of the answer (commit messages only)
+		Body         string   // the answer without the subject (empty if only the short message is requested)
+		Alternatives []string // other candidates (if requested using [WithCandidates])
+		Usage        Usage    // token usage statistics (zero if the provider does not report it)
+		Warnings     []string // non-fatal issues found in the answer (e.g. a too generic type)
+		Confidence   float64  // how sure the model is in the answer, 0..1 (if requested using [WithConfidence])
+		Rationale    string   // the model's explanation of the uncertainty (if requested using [WithConfidence])
+		Fixed        bool     // the invalid message has been fixed (see [WithFixInvalidStream], [WithCommitlintValidation])
+		OmittedHunks int      // the number of the diff hunks not sent to the model (see [WithMaxHunks])
+		Truncated    bool     // the answer was cut off by the output tokens limit (see [WithMaxOutputTokens])
+		OverflowNote string   // the full body (without the trailers) trimmed in the answer (see [WithBodyOverflowToNote])
+
+		maxSubjectWords int // the limit checked by [Response.Validate] (see [WithMaxSubjectWords])
+	}
+
+	// Usage contains the token usage statistics.
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
 	}
 )
 
 const defaultMaxOutputTokens = 500
 
+// defaultTimeout is the timeout of the default HTTP client of the remote providers.
+const defaultTimeout = 60 * time.Second
+
+// add returns the sum of two usage statistics.
+func (u Usage) add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// finalizeAnswer normalizes the raw answer of the model, so the output is consistent regardless of the provider:
+// the line endings are converted to "\n", the trailing whitespaces are removed from every line, and the leading and
+// trailing empty lines are dropped.
+func finalizeAnswer(raw string) string {
+	var lines = strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// maxDrainBytes limits the number of bytes read from the response body when draining it.
+const maxDrainBytes = 4 << 20 // 4 MiB
+
+// drainAndClose reads the rest of the body and closes it. Without draining, the underlying connection can't be
+// reused by the keep-alive, even if the body is closed.
+func drainAndClose(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+	_ = body.Close()
+}
+
+// chatMessage is the message of the OpenAI-compatible chat completion requests.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatMessages returns the messages of the OpenAI-compatible chat completion request: the instructions as the system
+// message, followed by the user turns.
+func chatMessages(instructions, changes, commits string, o options) []chatMessage {
+	var messages = []chatMessage{{Role: "system", Content: instructions}}
+
+	for _, turn := range userTurns(changes, commits, o) {
+		messages = append(messages, chatMessage{Role: "user", Content: turn})
+	}
+
+	return messages
+}
+
+// responseFormat is the `response_format` field of the OpenAI-compatible chat completion requests.
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+// jsonResponseFormat returns the JSON object response format if the JSON output is requested (nil otherwise).
+func jsonResponseFormat(o options) *responseFormat {
+	if o.jsonOutput() {
+		return &responseFormat{Type: "json_object"}
+	}
+
+	return nil
+}
+
+// streamOptions is the `stream_options` field of the OpenAI-compatible chat completion requests.
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatStreamOptions returns the stream options (with the usage statistics) if the answer should be streamed (nil
+// otherwise).
+func chatStreamOptions(o options) *streamOptions {
+	if o.streaming() {
+		return &streamOptions{IncludeUsage: true}
+	}
+
+	return nil
+}
+
 // httpClient is an interface for the common HTTP client.
 type httpClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -28,14 +149,21 @@ type httpClient interface {
 
 // Do not forget to update the [SupportedProviders] function if you add or remove providers.
 const (
-	ProviderGemini     = "gemini"
-	ProviderOpenAI     = "openai"
-	ProviderOpenRouter = "openrouter"
+	ProviderGemini      = "gemini"
+	ProviderOpenAI      = "openai"
+	ProviderOpenRouter  = "openrouter"
+	ProviderHuggingFace = "huggingface"
+	ProviderAnthropic   = "anthropic"
+	ProviderOllama      = "ollama"
+	ProviderMistral     = "mistral"
 )
 
 // SupportedProviders returns a list of supported AI providers.
 func SupportedProviders() []string {
-	return []string{ProviderGemini, ProviderOpenAI, ProviderOpenRouter}
+	return []string{
+		ProviderGemini, ProviderOpenAI, ProviderOpenRouter, ProviderHuggingFace, ProviderAnthropic, ProviderOllama,
+		ProviderMistral,
+	}
 }
 
 // IsProviderSupported checks if the given provider is supported.
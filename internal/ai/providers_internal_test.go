@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProviderTimeout(t *testing.T) {
+	t.Parallel()
+
+	var custom = &http.Client{Timeout: time.Second}
+
+	for name, tc := range map[string]struct {
+		giveDefault, giveTimeout, giveCustom httpClient
+	}{
+		"OpenAI": {
+			giveDefault: NewOpenAI("", "").httpClient,
+			giveTimeout: NewOpenAI("", "", WithOpenAITimeout(10*time.Second)).httpClient,
+			giveCustom:  NewOpenAI("", "", WithOpenAIHttpClient(custom), WithOpenAITimeout(10*time.Second)).httpClient,
+		},
+		"Anthropic": {
+			giveDefault: NewAnthropic("", "").httpClient,
+			giveTimeout: NewAnthropic("", "", WithAnthropicTimeout(10*time.Second)).httpClient,
+			giveCustom: NewAnthropic("", "",
+				WithAnthropicTimeout(10*time.Second), WithAnthropicHttpClient(custom),
+			).httpClient,
+		},
+		"Gemini": {
+			giveDefault: NewGemini("", "").httpClient,
+			giveTimeout: NewGemini("", "", WithGeminiTimeout(10*time.Second)).httpClient,
+			giveCustom:  NewGemini("", "", WithGeminiHttpClient(custom), WithGeminiTimeout(10*time.Second)).httpClient,
+		},
+		"HuggingFace": {
+			giveDefault: NewHuggingFace("", "").httpClient,
+			giveTimeout: NewHuggingFace("", "", WithHuggingFaceTimeout(10*time.Second)).httpClient,
+			giveCustom: NewHuggingFace("", "",
+				WithHuggingFaceHttpClient(custom), WithHuggingFaceTimeout(10*time.Second),
+			).httpClient,
+		},
+		"OpenRouter": {
+			giveDefault: NewOpenRouter("", "").httpClient,
+			giveTimeout: NewOpenRouter("", "", WithOpenRouterTimeout(10*time.Second)).httpClient,
+			giveCustom: NewOpenRouter("", "",
+				WithOpenRouterHttpClient(custom), WithOpenRouterTimeout(10*time.Second),
+			).httpClient,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assertEqual(t, tc.giveDefault.(*http.Client).Timeout, 60*time.Second)
+			assertEqual(t, tc.giveTimeout.(*http.Client).Timeout, 10*time.Second)
+			assertEqual(t, tc.giveCustom.(*http.Client), custom) // the custom client is not changed
+			assertEqual(t, custom.Timeout, time.Second)
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+// trackingBody is a response body that tracks whether it was fully read and closed.
+type trackingBody struct {
+	r               io.Reader
+	drained, closed bool
+}
+
+func (b *trackingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		b.drained = true
+	}
+
+	return n, err
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+
+	return nil
+}
+
+func TestProviders_DrainBodyOnError(t *testing.T) {
+	t.Parallel()
+
+	for name, newProvider := range allProviders() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var body = trackingBody{
+				// the decoder stops after the first JSON value, so the trailing data must be drained explicitly
+				r: strings.NewReader(`{"error":{"message":"oops"}}` + strings.Repeat(" ", 64<<10)),
+			}
+
+			var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusBadRequest, Body: &body}, nil
+			}}
+
+			if _, err := newProvider(&client).Query(context.Background(), "diff", "log"); err == nil {
+				t.Fatal("expected an error")
+			}
+
+			assertEqual(t, body.drained, true, "drained")
+			assertEqual(t, body.closed, true, "closed")
+		})
+	}
+}
+
+func TestProviders_UserAgent(t *testing.T) {
+	t.Parallel()
+
+	for name, newProvider := range allProviders() {
+		for want, opts := range map[string][]ai.Option{
+			"describe-commit/0.0.0": nil,
+			"my-tool/1.2.3":         {ai.WithUserAgent("my-tool/1.2.3")},
+		} {
+			t.Run(name+" "+want, func(t *testing.T) {
+				t.Parallel()
+
+				var got string
+
+				var client = fakeHttpClient{handler: func(req *http.Request, _ int) (*http.Response, error) {
+					got = req.Header.Get("User-Agent")
+
+					return newHttpResponse(http.StatusInternalServerError, ""), nil
+				}}
+
+				_, _ = newProvider(&client).Query(context.Background(), "diff", "log", opts...)
+
+				assertEqual(t, got, want)
+			})
+		}
+	}
+}
+
+func TestProviders_FinalizeAnswer(t *testing.T) {
+	t.Parallel()
+
+	for name, newProvider := range map[string]func(*fakeHttpClient) ai.Provider{
+		"openai": func(c *fakeHttpClient) ai.Provider { return ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(c)) },
+		"openrouter": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewOpenRouter("key", "model", ai.WithOpenRouterHttpClient(c))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := newProvider(okClient("\r\n \t\nfeat: Add foo  \r\n\r\n- Bar\t\n- Baz \n\n\t")).
+				Query(context.Background(), "diff", "log")
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, "feat: Add foo\n\n- Bar\n- Baz")
+
+			if _, err = newProvider(okClient(" \r\n\t ")).Query(context.Background(), "diff", "log"); err == nil {
+				t.Fatal("expected an error for the blank answer")
+			}
+		})
+	}
+}
+
+func TestProviders_Sampling(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		newProvider func(*fakeHttpClient) ai.Provider
+		wantDefault []string
+		wantCustom  []string
+	}{
+		"openai": {
+			newProvider: func(c *fakeHttpClient) ai.Provider { return ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(c)) },
+			wantDefault: []string{`"temperature":0.1`, `"top_p":0.1`},
+			wantCustom:  []string{`"temperature":1.3`, `"top_p":0.9`},
+		},
+		"openrouter": {
+			newProvider: func(c *fakeHttpClient) ai.Provider {
+				return ai.NewOpenRouter("", "", ai.WithOpenRouterHttpClient(c))
+			},
+			wantDefault: []string{`"temperature":0.1`, `"top_p":0.1`},
+			wantCustom:  []string{`"temperature":1.3`, `"top_p":0.9`},
+		},
+		"gemini": {
+			newProvider: func(c *fakeHttpClient) ai.Provider { return ai.NewGemini("", "", ai.WithGeminiHttpClient(c)) },
+			wantDefault: []string{`"temperature":0.1`, `"topP":0.1`},
+			wantCustom:  []string{`"temperature":1.3`, `"topP":0.9`},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, opts := range [][]ai.Option{nil, {ai.WithTemperature(1.3), ai.WithTopP(0.9)}} {
+				var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+					return newHttpResponse(http.StatusInternalServerError, ""), nil
+				}}
+
+				_, _ = tc.newProvider(&client).Query(context.Background(), "diff", "log", opts...)
+
+				var body, want = client.Requests()[0], tc.wantDefault
+
+				if opts != nil {
+					want = tc.wantCustom
+				}
+
+				for _, w := range want {
+					if !strings.Contains(body, w) {
+						t.Errorf("expected %q to contain %q", body, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestProviders_InvalidSampling(t *testing.T) {
+	t.Parallel()
+
+	for name, opt := range map[string]ai.Option{
+		"negative temperature": ai.WithTemperature(-0.1),
+		"too high temperature": ai.WithTemperature(2.1),
+		"zero top_p":           ai.WithTopP(0),
+		"too high top_p":       ai.WithTopP(1.5),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat: Add foo")
+
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), "diff", "log", opt)
+			if err == nil || !strings.Contains(err.Error(), "must be within") {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			assertEqual(t, len(client.Requests()), 0, "no requests")
+		})
+	}
+
+	t.Run("bounds", func(t *testing.T) {
+		t.Parallel()
+
+		for _, temperature := range []float64{0, 2} {
+			_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient("feat: Add foo"))).
+				Query(context.Background(), "diff", "log", ai.WithTemperature(temperature), ai.WithTopP(1))
+			assertNoError(t, err)
+		}
+	})
+}
+
+func TestProviders_Usage(t *testing.T) {
+	t.Parallel()
+
+	var providers = map[string]func(*fakeHttpClient) ai.Provider{
+		"openai": func(c *fakeHttpClient) ai.Provider { return ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(c)) },
+		"openrouter": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewOpenRouter("", "", ai.WithOpenRouterHttpClient(c))
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		giveBody  string
+		wantUsage ai.Usage
+	}{
+		"reported": {
+			giveBody: `{
+				"choices":[{"message":{"content":"feat: Add foo"}}],
+				"usage":{"prompt_tokens":120,"completion_tokens":15,"total_tokens":135}
+			}`,
+			wantUsage: ai.Usage{PromptTokens: 120, CompletionTokens: 15, TotalTokens: 135},
+		},
+		"absent": {
+			giveBody: `{"choices":[{"message":{"content":"feat: Add foo"}}]}`,
+		},
+		"null": {
+			giveBody: `{"choices":[{"message":{"content":"feat: Add foo"}}],"usage":null}`,
+		},
+	} {
+		for providerName, newProvider := range providers {
+			t.Run(providerName+"/"+name, func(t *testing.T) {
+				t.Parallel()
+
+				var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+					return newHttpResponse(http.StatusOK, tc.giveBody), nil
+				}}
+
+				resp, err := newProvider(&client).Query(context.Background(), "diff", "log")
+				assertNoError(t, err)
+
+				assertEqual(t, resp.Answer, "feat: Add foo")
+				assertEqual(t, resp.Usage, tc.wantUsage)
+			})
+		}
+	}
+}
+
+func TestProviders_DryRun(t *testing.T) {
+	t.Parallel()
+
+	for name, newProvider := range allProviders() {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				t.Error("the API must not be called")
+
+				return newHttpResponse(http.StatusInternalServerError, ""), nil
+			}}
+
+			var provider = newProvider(&client)
+
+			resp, err := provider.Query(context.Background(), "the diff", "the log",
+				ai.WithDryRun(), ai.WithPlanThenWrite(true), ai.WithCandidates(3),
+			)
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, "")
+			assertEqual(t, len(resp.Answers), 0)
+			assertEqual(t, resp.Provider, provider.Name())
+
+			var want = ai.GeneratePrompt() + "\n\n" +
+				"[---GIT-DIFF-BEGIN---]\nthe diff\n[---GIT-DIFF-END---]\n\n" +
+				"[---GIT-LOG-BEGIN---]\nthe log\n[---GIT-LOG-END---]"
+
+			assertEqual(t, resp.Prompt, want)
+		})
+	}
+}
+
+func TestProviders_StopSequences(t *testing.T) {
+	t.Parallel()
+
+	for name, newProvider := range map[string]func(*fakeHttpClient) ai.Provider{
+		"openai": func(c *fakeHttpClient) ai.Provider { return ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(c)) },
+		"openrouter": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewOpenRouter("key", "model", ai.WithOpenRouterHttpClient(c))
+		},
+	} {
+		for caseName, tc := range map[string]struct {
+			giveOpts []ai.Option
+			want     string // empty = the field is omitted
+		}{
+			"default": {},
+			"empty":   {giveOpts: []ai.Option{ai.WithStopSequences()}},
+			"set":     {giveOpts: []ai.Option{ai.WithStopSequences("\n\n\n", "---")}, want: `["\n\n\n","---"]`},
+		} {
+			t.Run(name+" "+caseName, func(t *testing.T) {
+				t.Parallel()
+
+				var client = okClient("feat: Add foo")
+
+				_, err := newProvider(client).Query(context.Background(), "diff", "log", tc.giveOpts...)
+				assertNoError(t, err)
+
+				var req map[string]json.RawMessage
+
+				assertNoError(t, json.Unmarshal([]byte(client.Requests()[0]), &req))
+
+				stop, ok := req["stop"]
+
+				assertEqual(t, ok, tc.want != "", "the stop field presence")
+				assertEqual(t, string(stop), tc.want)
+			})
+		}
+	}
+}
+
+func TestProviders_Truncated(t *testing.T) {
+	t.Parallel()
+
+	const chat = `{"choices":[{"message":{"content":"feat: Add foo"},"finish_reason":%q}]}`
+
+	for name, tc := range map[string]struct {
+		giveTruncated, giveStopped string // the bodies with the answer cut off by the limit, and the finished one
+	}{
+		"gemini": {
+			giveTruncated: `{"candidates":[{"content":{"parts":[{"text":"feat: Add foo"}]},"finishReason":"MAX_TOKENS"}]}`,
+			giveStopped:   `{"candidates":[{"content":{"parts":[{"text":"feat: Add foo"}]},"finishReason":"STOP"}]}`,
+		},
+		"openai":      {giveTruncated: fmt.Sprintf(chat, "length"), giveStopped: fmt.Sprintf(chat, "stop")},
+		"openrouter":  {giveTruncated: fmt.Sprintf(chat, "length"), giveStopped: fmt.Sprintf(chat, "stop")},
+		"huggingface": {giveTruncated: fmt.Sprintf(chat, "length"), giveStopped: fmt.Sprintf(chat, "stop")},
+		"mistral":     {giveTruncated: fmt.Sprintf(chat, "length"), giveStopped: fmt.Sprintf(chat, "stop")},
+		"anthropic": {
+			giveTruncated: `{"content":[{"type":"text","text":"feat: Add foo"}],"stop_reason":"max_tokens"}`,
+			giveStopped:   `{"content":[{"type":"text","text":"feat: Add foo"}],"stop_reason":"end_turn"}`,
+		},
+		"ollama": {
+			giveTruncated: `{"message":{"content":"feat: Add foo"},"done_reason":"length"}`,
+			giveStopped:   `{"message":{"content":"feat: Add foo"},"done_reason":"stop"}`,
+		},
+	} {
+		for body, want := range map[string]bool{tc.giveTruncated: true, tc.giveStopped: false} {
+			t.Run(fmt.Sprintf("%s/%t", name, want), func(t *testing.T) {
+				t.Parallel()
+
+				var client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+					return newHttpResponse(http.StatusOK, body), nil
+				}}
+
+				resp, err := allProviders()[name](&client).Query(context.Background(), "diff", "log")
+				assertNoError(t, err)
+
+				assertEqual(t, resp.Answer, "feat: Add foo")
+				assertEqual(t, resp.Truncated, want)
+			})
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// maxBlameEntries limits the number of commits included into the blame context.
+const maxBlameEntries = 10
+
+// maxCandidateRounds limits the number of requests made to collect the requested number of unique candidates.
+const maxCandidateRounds = 3
+
+type (
+	// completion is the result of a single round-trip to the remote provider.
+	completion struct {
+		Answers   []string // one per returned candidate, in the order they were received
+		Usage     Usage
+		Streamed  bool   // the answer was streamed (see [WithStream])
+		Truncated bool   // the (first) answer was cut off by the output tokens limit
+		Model     string // the model that produced the answers (set by [complete], e.g. to the overflow model)
+	}
+
+	// completer performs a single request to the remote provider. It's implemented by every provider, and the
+	// shared [query] function builds the [Response] on top of it.
+	completer interface {
+		Name() string
+		model() string
+		batchesCandidates() bool // false = a separate request (with the same input) is made for every candidate
+		complete(_ context.Context, instructions, changes, commits string, _ options) (*completion, error)
+	}
+)
+
+// query is the shared part of the [Provider.Query] implementations.
+func query( //nolint:funlen
+	ctx context.Context,
+	c completer,
+	changes, commits string,
+	opts ...Option,
+) (_ *Response, err error) {
+	if vErr := (options{}).Apply(opts...).validate(); vErr != nil {
+		return nil, vErr
+	}
+
+	if o := (options{}).Apply(opts...); o.FixupTarget != "" {
+		resp, fErr := fixupResponse(ctx, o)
+		if fErr != nil {
+			return nil, fErr
+		}
+
+		resp.Provider = c.Name()
+
+		return resp, nil
+	}
+
+	if _, ok := QueryInputFromContext(ctx); !ok {
+		ctx = ContextWithQueryInput(ctx, QueryInput{Changes: changes, Commits: commits, Opts: opts})
+	}
+
+	if budget := (options{}).Apply(opts...).TotalBudget; budget > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeoutCause(ctx, budget, ErrBudgetExceeded)
+		defer cancel()
+
+		defer func() {
+			if err != nil && errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+				err = fmt.Errorf("%w: %w", ErrBudgetExceeded, err)
+			}
+		}()
+	}
+
+	opts, cErr := withRepoContext(ctx, changes, opts)
+	if cErr != nil {
+		return nil, cErr
+	}
+
+	opts = withChangesContext(changes, opts)
+	opts = withRevertedPatterns(commits, opts)
+	commits, opts = withCommitExamples(commits, opts)
+
+	var usage Usage
+
+	if o := (options{}).Apply(opts...); o.PlanThenWrite && !o.DryRun {
+		plan, planUsage, err := planChanges(ctx, c, changes, commits, o)
+		if err != nil {
+			return nil, err
+		}
+
+		usage = planUsage
+		opts = append(opts[:len(opts):len(opts)], withContext("Key changes (use them to write the message)", plan))
+	}
+
+	var (
+		opt          = options{}.Apply(opts...)
+		instructions = GeneratePrompt(opts...)
+	)
+
+	if opt.MaxOutputTokens == 0 {
+		opt.MaxOutputTokens = defaultMaxOutputTokens // set default value
+	}
+
+	if opt.Candidates < 1 {
+		opt.Candidates = 1 // set default value
+	}
+
+	var (
+		prepared, omittedHunks = prepareChanges(changes, opt)
+		warnings               []string
+	)
+
+	if opt.MaxCandidateCost > 0 {
+		warning, err := fitCandidatesCost(opt.modelOr(c.model()), instructions, prepared, commits, c.batchesCandidates(), &opt)
+		if err != nil {
+			return nil, err
+		}
+
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if opt.DryRun {
+		return &Response{
+			Provider:     c.Name(),
+			Model:        opt.modelOr(c.model()),
+			Prompt:       strings.Join(append([]string{instructions}, userTurns(prepared, commits, opt)...), "\n\n"),
+			Warnings:     warnings,
+			OmittedHunks: omittedHunks,
+		}, nil
+	}
+
+	candidates, assessed, err := collectCandidates(ctx, c, instructions, prepared, commits, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	var answers = candidates.Answers
+
+	usage = usage.add(candidates.Usage)
+	opt.modelOverride = candidates.Model // the fix requests go to the model that produced the answer
+
+	var first, isAssessed = assessed[answers[0]]
+
+	for i := range answers {
+		answers[i] = polishAnswer(answers[i], changes, candidates.Model, opt)
+	}
+
+	var response = Response{
+		Provider:     c.Name(),
+		Model:        candidates.Model,
+		Prompt:       instructions,
+		Answer:       answers[0],
+		Answers:      answers,
+		Alternatives: answers[1:],
+		Usage:        usage,
+		Warnings:     append(warnings, validateAnswer(answers[0], changes, opt)...),
+		Confidence:   first.Confidence,
+		Rationale:    first.Rationale,
+		OmittedHunks: omittedHunks,
+		Truncated:    candidates.Truncated,
+
+		maxSubjectWords: opt.MaxSubjectWords,
+	}
+
+	if opt.jsonOutput() && !isAssessed {
+		response.Warnings = append(response.Warnings, "the model did not report the confidence")
+	}
+
+	if opt.streaming() && opt.OutputFormat == FormatCommitMessage {
+		if err := checkStreamed(ctx, c, instructions, changes, &response, opt); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.Commitlint && opt.OutputFormat == FormatCommitMessage {
+		if err := checkCommitlint(ctx, c, instructions, changes, &response, opt); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.OutputFormat == FormatCommitMessage {
+		if opt.MaxBodyLines > 0 && !opt.ShortMessageOnly {
+			response.Answer, response.OverflowNote = overflowBody(response.Answer, opt.MaxBodyLines)
+			response.Answers[0] = response.Answer
+		}
+
+		response.Subject, response.Body = splitAnswer(response.Answer, opt)
+	}
+
+	if opt.AuditLog != nil {
+		if err := writeAuditRecord(opt.AuditLog, changes, &response, opt.AuditLogBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return &response, nil
+}
+
+// collectCandidates requests the candidates (asking for more, if the duplicates are dropped) and normalizes them.
+// The self-assessments (if requested) are returned keyed by the normalized answer. The completion is truncated if
+// the first answer is cut off by the output tokens limit. Once the context overflows, the rest of the rounds use the
+// overflow model, which is reported as the completion model.
+func collectCandidates(
+	ctx context.Context,
+	c completer,
+	instructions, changes, commits string,
+	opt options,
+) (*completion, map[string]assessment, error) {
+	var (
+		want      = opt.Candidates
+		answers   = make([]string, 0, want)
+		assessed  = make(map[string]assessment)
+		usage     Usage
+		truncated bool
+	)
+
+	for round := 0; round < maxCandidateRounds && len(answers) < want; round++ {
+		opt.Candidates = want - len(answers) // request only the missing candidates
+
+		res, err := complete(ctx, c, instructions, changes, commits, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		usage = usage.add(res.Usage)
+		opt.modelOverride = res.Model // keep using the overflow model, if the context overflowed
+
+		if round == 0 {
+			truncated = res.Truncated // the first answer comes from the first round
+		}
+
+		if opt.streaming() && !res.Streamed && len(res.Answers) > 0 {
+			opt.Stream(res.Answers[0]) // the provider does not support streaming, so pass the whole answer at once
+		}
+
+		for _, answer := range res.Answers {
+			var (
+				a  assessment
+				ok bool
+			)
+
+			if opt.jsonOutput() {
+				answer, a, ok = parseJSONAnswer(answer)
+			}
+
+			if opt.ShortMessageOnly && opt.OutputFormat == FormatCommitMessage {
+				answer, _, _ = strings.Cut(answer, "\n")
+			}
+
+			if ok {
+				assessed[answer] = a
+			}
+
+			answers = append(answers, answer)
+		}
+
+		if !opt.UniqueCandidates {
+			break // the provider is not asked for more candidates if duplicates are allowed
+		}
+
+		answers = uniqueCandidates(answers)
+	}
+
+	if len(answers) == 0 {
+		return nil, nil, errors.New("no response from the AI provider")
+	}
+
+	if len(answers) > want {
+		answers = answers[:want]
+	}
+
+	return &completion{Answers: answers, Usage: usage, Truncated: truncated, Model: opt.modelOverride}, assessed, nil
+}
+
+// polishAnswer applies the built-in rewrites, the model trailer, and the user-defined post-processing to the
+// generated message.
+func polishAnswer(answer, changes, model string, o options) string {
+	answer = rewriteAnswer(answer, changes, o)
+
+	if o.ModelTrailer && !o.ShortMessageOnly && o.OutputFormat == FormatCommitMessage {
+		answer = withModelTrailer(answer, model, o.TrailerFormat)
+	}
+
+	if o.ASCIIOnly {
+		answer = toASCII(answer)
+	}
+
+	// the user-defined post-processing goes last, after all the built-in sanitization
+	if o.PostProcess != nil {
+		answer = o.PostProcess(answer)
+	}
+
+	return answer
+}
+
+// uniqueCandidates removes candidates with the same subject (the first line, compared case-insensitively),
+// preserving the order of the first occurrences.
+func uniqueCandidates(candidates []string) []string {
+	var (
+		seen   = make(map[string]struct{}, len(candidates))
+		unique = make([]string, 0, len(candidates))
+	)
+
+	for _, candidate := range candidates {
+		subject, _, _ := strings.Cut(candidate, "\n")
+
+		var key = strings.ToLower(strings.TrimSpace(subject))
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		unique = append(unique, candidate)
+	}
+
+	return unique
+}
+
+// withRepoContext appends the additional context gathered from the repository to the options.
+func withRepoContext(ctx context.Context, changes string, opts []Option) ([]Option, error) {
+	var opt = options{}.Apply(opts...)
+
+	if opt.MimicRepoDir != "" {
+		author, examples, err := topContributorExamples(ctx, opt.MimicRepoDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if examples != "" {
+			opts = append(opts[:len(opts):len(opts)], withContext(
+				"Commit messages of "+author+", the top contributor of the repository (follow their style)",
+				examples,
+			))
+		}
+	}
+
+	if opt.GitDir == "" {
+		return opts, nil
+	}
+
+	if opt.AuthorName != "" {
+		examples, err := authorExamples(ctx, opt.GitDir, opt.AuthorName)
+		if err != nil {
+			return nil, err
+		}
+
+		if examples != "" {
+			opts = append(opts[:len(opts):len(opts)],
+				withContext("Recent commit messages of "+opt.AuthorName+" (write in their style)", examples),
+			)
+		}
+	}
+
+	if opt.RecentCommits > 0 {
+		log, err := git.RecentCommits(ctx, opt.GitDir, opt.RecentCommits)
+		if err != nil {
+			return nil, err
+		}
+
+		if log != "" {
+			opts = append(opts[:len(opts):len(opts)], withContext(
+				"Recent commit messages of the repository, separated by blank lines (follow their style and structure)",
+				log,
+			))
+		}
+	}
+
+	if opt.RepoNameContext {
+		remote, err := git.RemoteURL(ctx, opt.GitDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if name := repoName(remote); name != "" {
+			opts = append(opts[:len(opts):len(opts)],
+				withContext("The repository (use it to infer the default scope or the project area)", name),
+			)
+		}
+	}
+
+	if opt.BlameContext {
+		summary, err := git.Blame(ctx, opt.GitDir, changes, maxBlameEntries)
+		if err != nil {
+			return nil, err
+		}
+
+		if summary != "" {
+			opts = append(opts[:len(opts):len(opts)],
+				withContext("Prior authorship of the changed lines (from `git blame`)", summary),
+			)
+		}
+	}
+
+	if opt.BinaryMetadata {
+		binaries, err := git.BinaryChanges(ctx, opt.GitDir, changes)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(binaries) > 0 {
+			opts = append(opts[:len(opts):len(opts)], withContext(
+				"Metadata of the changed binary files (their content is not shown in the diff)",
+				formatBinaryChanges(binaries),
+			))
+		}
+	}
+
+	return opts, nil
+}
+
+// complete requests the completion. The request is retried once if the response can't be decoded (unless disabled),
+// or if the context is too long (with the overflow model, or the truncated changes). The stalled stream is replaced
+// with the non-streaming request, if enabled (see [WithStreamFallback]). The model that produced the answers is set
+// on the completion.
+func complete(ctx context.Context, c completer, instructions, changes, commits string, o options) (*completion, error) {
+	var first = completeWithRetries
+
+	if o.StreamFallback && o.streaming() {
+		first = completeWithStreamFallback
+	}
+
+	res, err := first(ctx, c, instructions, changes, commits, o)
+	if err != nil && ctx.Err() == nil {
+		switch {
+		case errors.Is(err, ErrMalformedResponse) && !o.SkipDecodeRetry:
+			res, err = completeWithRetries(ctx, c, instructions, changes, commits, o)
+
+		case errors.Is(err, ErrContextTooLong):
+			if o.OverflowModel != "" {
+				o.modelOverride = o.OverflowModel
+			} else {
+				var tok = o.tokenizer(o.modelOr(c.model()))
+
+				changes = truncateToTokens(changes, tok.Count(changes)/2, tok) //nolint:mnd
+			}
+
+			res, err = completeWithRetries(ctx, c, instructions, changes, commits, o)
+		}
+	}
+
+	if err != nil {
+		return res, err
+	}
+
+	res.Model = o.modelOr(c.model())
+
+	return res, nil
+}
@@ -0,0 +1,87 @@
+package ai
+
+import "encoding/json"
+
+// responseJSON is the stable JSON schema of the [Response].
+type responseJSON struct {
+	Provider     string   `json:"provider"`
+	Model        string   `json:"model"`
+	Answer       string   `json:"answer"`
+	Subject      string   `json:"subject"`
+	Body         string   `json:"body"`
+	Alternatives []string `json:"alternatives"`
+	Prompt       string   `json:"prompt"`
+	Usage        Usage    `json:"usage"`
+	Warnings     []string `json:"warnings"`
+	Confidence   float64  `json:"confidence,omitempty"`
+	Rationale    string   `json:"rationale,omitempty"`
+	Fixed        bool     `json:"fixed"`
+	OmittedHunks int      `json:"omitted_hunks"`
+	Truncated    bool     `json:"truncated"`
+	OverflowNote string   `json:"overflow_note,omitempty"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface, so the response can be consumed by the other tools. The
+// schema is stable: the lists are never null, and all the candidates except the answer are the alternatives.
+func (r Response) MarshalJSON() ([]byte, error) {
+	var j = responseJSON{
+		Provider:     r.Provider,
+		Model:        r.Model,
+		Answer:       r.Answer,
+		Subject:      r.Subject,
+		Body:         r.Body,
+		Alternatives: r.Alternatives,
+		Prompt:       r.Prompt,
+		Usage:        r.Usage,
+		Warnings:     r.Warnings,
+		Confidence:   r.Confidence,
+		Rationale:    r.Rationale,
+		Fixed:        r.Fixed,
+		OmittedHunks: r.OmittedHunks,
+		Truncated:    r.Truncated,
+		OverflowNote: r.OverflowNote,
+	}
+
+	if j.Alternatives == nil {
+		j.Alternatives = []string{}
+	}
+
+	if j.Warnings == nil {
+		j.Warnings = []string{}
+	}
+
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface (see [Response.MarshalJSON]).
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var j responseJSON
+
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+
+	*r = Response{
+		Provider:     j.Provider,
+		Model:        j.Model,
+		Prompt:       j.Prompt,
+		Answer:       j.Answer,
+		Subject:      j.Subject,
+		Body:         j.Body,
+		Usage:        j.Usage,
+		Warnings:     j.Warnings,
+		Confidence:   j.Confidence,
+		Rationale:    j.Rationale,
+		Fixed:        j.Fixed,
+		OmittedHunks: j.OmittedHunks,
+		Truncated:    j.Truncated,
+		OverflowNote: j.OverflowNote,
+	}
+
+	if j.Answer != "" || len(j.Alternatives) > 0 {
+		r.Answers = append([]string{j.Answer}, j.Alternatives...)
+		r.Alternatives = r.Answers[1:]
+	}
+
+	return nil
+}
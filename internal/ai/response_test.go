@@ -0,0 +1,87 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestResponse_JSON(t *testing.T) {
+	t.Parallel()
+
+	var client = okClient("feat: Add foo\n\n- Bar")
+
+	resp, err := ai.NewOpenAI("", "gpt-test", ai.WithOpenAIHttpClient(client)).
+		Query(context.Background(), "diff", "log")
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Provider, ai.ProviderOpenAI)
+	assertEqual(t, resp.Model, "gpt-test")
+
+	data, err := json.Marshal(resp)
+	assertNoError(t, err)
+
+	var schema map[string]any
+
+	assertNoError(t, json.Unmarshal(data, &schema))
+
+	for _, key := range []string{
+		"provider", "model", "answer", "subject", "body", "alternatives", "prompt", "usage", "warnings", "fixed",
+		"omitted_hunks",
+	} {
+		if _, ok := schema[key]; !ok {
+			t.Errorf("the %q key is missing in %s", key, data)
+		}
+	}
+
+	assertEqual(t, schema["provider"], "openai")
+	assertEqual(t, schema["body"], "- Bar")
+
+	if alternatives, ok := schema["alternatives"].([]any); !ok || len(alternatives) != 0 {
+		t.Errorf("the alternatives must be an empty list, got %v", schema["alternatives"])
+	}
+
+	var decoded ai.Response
+
+	assertNoError(t, json.Unmarshal(data, &decoded))
+
+	assertEqual(t, decoded.Provider, resp.Provider)
+	assertEqual(t, decoded.Model, resp.Model)
+	assertEqual(t, decoded.Answer, resp.Answer)
+	assertEqual(t, decoded.Subject, "feat: Add foo")
+	assertEqual(t, decoded.Body, resp.Body)
+	assertEqual(t, decoded.Prompt, resp.Prompt)
+	assertEqual(t, decoded.Usage, resp.Usage)
+	assertEqual(t, slices.Equal(decoded.Answers, resp.Answers), true, "answers")
+
+	t.Run("candidates", func(t *testing.T) {
+		t.Parallel()
+
+		var want = ai.Response{
+			Answer:       "feat: Add foo",
+			Answers:      []string{"feat: Add foo", "fix: Fix foo"},
+			Alternatives: []string{"fix: Fix foo"},
+			Warnings:     []string{"too generic"},
+			Confidence:   0.5,
+			Fixed:        true,
+			OmittedHunks: 2,
+		}
+
+		data, err := json.Marshal(want)
+		assertNoError(t, err)
+
+		var got ai.Response
+
+		assertNoError(t, json.Unmarshal(data, &got))
+
+		assertEqual(t, slices.Equal(got.Answers, want.Answers), true, "answers")
+		assertEqual(t, slices.Equal(got.Alternatives, want.Alternatives), true, "alternatives")
+		assertEqual(t, slices.Equal(got.Warnings, want.Warnings), true, "warnings")
+		assertEqual(t, got.Confidence, want.Confidence)
+		assertEqual(t, got.Fixed, want.Fixed)
+		assertEqual(t, got.OmittedHunks, want.OmittedHunks)
+	})
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryDelay limits the delay between the retries (both the computed and the requested by the provider ones).
+const maxRetryDelay = 2 * time.Minute
+
+// completeWithRetries performs the completion, retrying the transient failures (see [isTransient]) up to the
+// configured number of times. The exponential backoff with jitter is used between the attempts, unless the provider
+// asks for the specific delay (the `Retry-After` header). The context cancellation stops the retries.
+func completeWithRetries(
+	ctx context.Context,
+	c completer,
+	instructions, changes, commits string,
+	o options,
+) (*completion, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := c.complete(ctx, instructions, changes, commits, o)
+		if err == nil || attempt >= o.Retries || ctx.Err() != nil || !isTransient(err) {
+			return res, err
+		}
+
+		var timer = time.NewTimer(retryDelay(err, o.RetryBase, attempt))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isTransient reports whether the request may succeed if repeated: the rate limit, the server-side failures (500,
+// 502, 503, and 504), and the network errors. The errors sent in the middle of the stream are not retried, since the
+// part of the message is already passed to the caller.
+func isTransient(err error) bool {
+	if apiErr := (*APIError)(nil); errors.As(err, &apiErr) {
+		if apiErr.InStream {
+			return false
+		}
+
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+
+		return false
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr)
+}
+
+// retryDelay returns the delay before the next attempt (the zero-based number of the failed one). The delay requested
+// by the provider takes precedence; otherwise, the base delay is doubled with every attempt, and the random part
+// (up to the half of it) is subtracted, so the clients do not retry in lockstep.
+func retryDelay(err error, base time.Duration, attempt int) time.Duration {
+	if apiErr := (*APIError)(nil); errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, maxRetryDelay)
+	}
+
+	if base <= 0 {
+		return 0
+	}
+
+	var delay = maxRetryDelay
+
+	if attempt < 32 && base < maxRetryDelay>>attempt { //nolint:mnd // avoid the overflow
+		delay = base << attempt
+	}
+
+	return delay - rand.N(delay/2+1) //nolint:gosec // no need for the cryptographically secure randomness
+}
+
+// parseRetryAfter parses the `Retry-After` header value: either the number of seconds, or the HTTP date. Zero is
+// returned if the value is missing or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+
+	return 0
+}
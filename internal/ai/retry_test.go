@@ -0,0 +1,132 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_Retries(t *testing.T) {
+	t.Parallel()
+
+	var netErr = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	for name, tc := range map[string]struct {
+		giveFailures []func() (*http.Response, error) // the responses before the successful one
+		giveRetries  int
+		wantErr      error
+		wantRequests int
+	}{
+		"503 twice": {
+			giveFailures: []func() (*http.Response, error){
+				func() (*http.Response, error) { return newHttpResponse(http.StatusServiceUnavailable, ""), nil },
+				func() (*http.Response, error) { return newHttpResponse(http.StatusServiceUnavailable, ""), nil },
+			},
+			giveRetries:  3,
+			wantRequests: 3,
+		},
+		"rate limited and network error": {
+			giveFailures: []func() (*http.Response, error){
+				func() (*http.Response, error) { return newHttpResponse(http.StatusTooManyRequests, ""), nil },
+				func() (*http.Response, error) { return nil, netErr },
+			},
+			giveRetries:  2,
+			wantRequests: 3,
+		},
+		"retries exhausted": {
+			giveFailures: []func() (*http.Response, error){
+				func() (*http.Response, error) { return newHttpResponse(http.StatusBadGateway, ""), nil },
+				func() (*http.Response, error) { return newHttpResponse(http.StatusGatewayTimeout, ""), nil },
+			},
+			giveRetries:  1,
+			wantErr:      ai.ErrProviderUnavailable,
+			wantRequests: 2,
+		},
+		"disabled by default": {
+			giveFailures: []func() (*http.Response, error){
+				func() (*http.Response, error) { return newHttpResponse(http.StatusInternalServerError, ""), nil },
+			},
+			wantErr:      ai.ErrProviderUnavailable,
+			wantRequests: 1,
+		},
+		"permanent error": {
+			giveFailures: []func() (*http.Response, error){
+				func() (*http.Response, error) { return newHttpResponse(http.StatusUnauthorized, ""), nil },
+			},
+			giveRetries:  3,
+			wantErr:      ai.ErrUnauthorized,
+			wantRequests: 1,
+		},
+		"bad request": {
+			giveFailures: []func() (*http.Response, error){
+				func() (*http.Response, error) { return newHttpResponse(http.StatusBadRequest, ""), nil },
+			},
+			giveRetries:  3,
+			wantErr:      ai.ErrBadRequest,
+			wantRequests: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = &fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+				if n < len(tc.giveFailures) {
+					return tc.giveFailures[n]()
+				}
+
+				return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+			}}
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+				Query(context.Background(), "diff", "log", ai.WithRetries(tc.giveRetries, time.Millisecond))
+
+			assertEqual(t, len(client.Requests()), tc.wantRequests, "requests")
+
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+
+				return
+			}
+
+			assertNoError(t, err)
+			assertEqual(t, resp.Answer, "feat: Add foo")
+		})
+	}
+}
+
+func TestQuery_RetriesRespectContext(t *testing.T) {
+	t.Parallel()
+
+	var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		var resp = newHttpResponse(http.StatusTooManyRequests, `{"error":{"message":"slow down"}}`)
+
+		resp.Header.Set("Retry-After", "3600") // much longer than the deadline, so the base delay must be ignored
+
+		return resp, nil
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var start = time.Now()
+
+	_, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).
+		Query(ctx, "diff", "log", ai.WithRetries(5, time.Millisecond))
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the retry loop ignored the deadline: %s", elapsed)
+	}
+
+	assertEqual(t, len(client.Requests()), 1)
+}
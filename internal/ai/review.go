@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// ReviewComment is a single code review annotation (see [FormatReviewComment]).
+type ReviewComment struct {
+	File string // the changed file path (empty for the general comments)
+	Line int    // the line number in the new state of the file (0 if unknown or outside the changed regions)
+	Type string // the label, e.g. "issue" or "nitpick"
+	Text string
+}
+
+// reviewLabels are the supported "conventional comments" labels (https://conventionalcomments.org/).
+var reviewLabels = [...]string{ //nolint:gochecknoglobals
+	"praise", "nitpick", "suggestion", "issue", "todo", "question", "thought", "chore", "note",
+}
+
+// reviewCommentRegex matches the `<file>[:<line>]: <label>[ (<decorations>)]: <text>` lines. The file may be `-`
+// for the general comments.
+var reviewCommentRegex = regexp.MustCompile("^\\s*(?:[-*]\\s+)?`?([^\\s:`]+)`?(?::(\\d+))?`?:\\s+([a-zA-Z]+)" +
+	"(?:\\s*\\([^)]*\\))?:\\s*(.+)$")
+
+// generateReviewPrompt generates the system prompt for the [FormatReviewComment].
+func generateReviewPrompt(opt options) string {
+	var b strings.Builder
+
+	b.Grow(2048) //nolint:mnd // pre-allocate memory for the string builder
+
+	{ // role
+		b.WriteString("## Role\n")
+		b.WriteString("You are an AI assistant specializing in reviewing code changes.\n")
+
+		b.WriteRune('\n')
+	}
+
+	{ // task
+		b.WriteString("## Task\n")
+		b.WriteString("Review the provided changes and write code review comments in the ")
+		b.WriteString("\"conventional comments\" style, attributed to the changed files and lines.\n")
+		writeContextLabels(&b, opt)
+
+		b.WriteRune('\n')
+	}
+
+	writeInputSection(&b)
+	writeContextSection(&b, opt)
+
+	{ // output
+		b.WriteString("## Output\n")
+		b.WriteString("Your output should be the list of comments in plain text, one comment per line, ")
+		b.WriteString("without any additional text, headings, or code blocks. Use the following format:\n")
+		b.WriteString("```\n")
+		b.WriteString("<file>:<line>: <label>: <comment>\n")
+		b.WriteString("```\n")
+		b.WriteString("- `<file>`: The path of the changed file, exactly as in the diff. ")
+		b.WriteString("Use `-` for the comments that are not related to a specific file.\n")
+		b.WriteString("- `<line>`: The line number in the new version of the file (taken from the hunk headers).\n")
+		b.WriteString("- `<label>`: One of: `" + strings.Join(reviewLabels[:], "`, `") + "`.\n")
+		b.WriteString("- `<comment>`: A short, actionable comment (a single line).\n")
+
+		b.WriteRune('\n')
+	}
+
+	{ // guidelines
+		b.WriteString("## Review Guidelines\n")
+		b.WriteString("- Comment on the changed lines only.\n")
+		b.WriteString("- Use `issue` for bugs and the real problems, `suggestion` for the proposed improvements, ")
+		b.WriteString("and `nitpick` for the trivial, preference-based requests.\n")
+		b.WriteString("- Use `praise` for the things that are done well, but do not overuse it.\n")
+		b.WriteString("- Be specific and concise; skip the obvious and do not repeat yourself.\n")
+
+		if !opt.EnableEmoji {
+			b.WriteString("- Do not use emojis.\n")
+		}
+
+		b.WriteRune('\n')
+	}
+
+	writeSecuritySection(&b, "comments")
+
+	{ // instructions
+		b.WriteString("## Instructions for the AI\n")
+		b.WriteString("- Analyze the provided `git diff` to understand the current changes.\n")
+		b.WriteString("- Analyze the provided `git log` output to better understand the codebase, ")
+		b.WriteString("but do not review the previous commits.\n")
+	}
+
+	return b.String()
+}
+
+// ParseReviewComments parses the comments generated in the [FormatReviewComment] format. The changes (the same diff
+// that was reviewed) are used to attribute the comments to the changed files: comments on unknown files become the
+// general ones, and the lines outside the changed regions are reset to 0. Lines with unknown labels are skipped.
+func ParseReviewComments(text, changes string) []ReviewComment {
+	var (
+		files    = git.SplitPatch(changes)
+		comments []ReviewComment
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		var m = reviewCommentRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		var c = ReviewComment{Type: strings.ToLower(m[3]), Text: strings.TrimSpace(m[4])}
+
+		if !slices.Contains(reviewLabels[:], c.Type) {
+			continue
+		}
+
+		if file, ok := findFileDiff(files, m[1]); ok {
+			c.File = file.Path
+
+			if n, err := strconv.Atoi(m[2]); err == nil && inHunks(file.Hunks(), n) {
+				c.Line = n
+			}
+		}
+
+		comments = append(comments, c)
+	}
+
+	return comments
+}
+
+// findFileDiff finds the changed file by its path (the models sometimes shorten the paths, so a unique suffix
+// match is accepted too).
+func findFileDiff(files []git.FileDiff, path string) (git.FileDiff, bool) {
+	var (
+		found git.FileDiff
+		count int
+	)
+
+	for _, f := range files {
+		if f.Path == path {
+			return f, true
+		}
+
+		if strings.HasSuffix(f.Path, "/"+path) {
+			found, count = f, count+1
+		}
+	}
+
+	return found, count == 1
+}
+
+// inHunks reports whether the line (in the new state of the file) belongs to one of the hunks.
+func inHunks(hunks []git.Hunk, line int) bool {
+	for _, h := range hunks {
+		if line >= h.NewStart && line < h.NewStart+h.NewLines {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package ai_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+const reviewDiff = `diff --git a/internal/cache/cache.go b/internal/cache/cache.go
+index 1111111..2222222 100644
+--- a/internal/cache/cache.go
++++ b/internal/cache/cache.go
+@@ -10,3 +10,5 @@ func (c *Cache) Get(key string) (string, bool) {
+ 	c.mu.Lock()
++	defer c.mu.Unlock()
++
+ 	v, ok := c.items[key]
+diff --git a/README.md b/README.md
+index 3333333..4444444 100644
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-# Cache
++# The Cache
+`
+
+func TestQuery_ReviewComments(t *testing.T) {
+	t.Parallel()
+
+	var client = okClient(strings.Join([]string{
+		"internal/cache/cache.go:11: praise: Deferring the unlock makes the early returns safe.",
+		"- `cache.go:12`: nitpick (non-blocking): Drop the empty line.",
+		"README.md:40: suggestion: Mention the eviction policy.",
+		"-: question: Is the cache shared between the goroutines?",
+		"unknown.go:1: issue: This file is not changed.",
+		"internal/cache/cache.go:11: complaint: Unknown label.",
+		"Some text the model added anyway.",
+	}, "\n"))
+
+	resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).Query(
+		context.Background(),
+		reviewDiff,
+		"log",
+		ai.WithOutputFormat(ai.FormatReviewComment),
+		ai.WithShortMessageOnly(true), // must not cut the comments
+	)
+	assertNoError(t, err)
+
+	var prompt = openaiMessages(t, client.Requests()[0])[0]
+
+	for _, want := range []string{"conventional comments", "<file>:<line>: <label>: <comment>", "`nitpick`"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected the prompt to contain %q", want)
+		}
+	}
+
+	var want = []ai.ReviewComment{
+		{File: "internal/cache/cache.go", Line: 11, Type: "praise", Text: "Deferring the unlock makes the early returns safe."},
+		{File: "internal/cache/cache.go", Line: 12, Type: "nitpick", Text: "Drop the empty line."},
+		{File: "README.md", Type: "suggestion", Text: "Mention the eviction policy."},
+		{Type: "question", Text: "Is the cache shared between the goroutines?"},
+		{Type: "issue", Text: "This file is not changed."},
+	}
+
+	if got := ai.ParseReviewComments(resp.Answer, reviewDiff); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
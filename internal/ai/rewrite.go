@@ -0,0 +1,178 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// rewriteAnswer applies the deterministic (built-in) rewrites to the generated message.
+func rewriteAnswer(answer, changes string, o options) string {
+	if o.OutputFormat != FormatCommitMessage {
+		return answer // the rewrites are specific to the commit messages
+	}
+
+	if !o.LaxSubject {
+		subject, rest, hasRest := strings.Cut(answer, "\n")
+
+		if subject = normalizeSubject(subject); hasRest {
+			answer = subject + "\n" + rest
+		} else {
+			answer = subject
+		}
+	}
+
+	if o.TestOnlyType {
+		if files := git.ChangedFiles(changes); isTestOnly(files) {
+			answer = withTestType(answer, likelyScopes(files))
+		}
+	}
+
+	if o.ForceDirScope {
+		if dir := commonTopLevelDir(git.ChangedFiles(changes)); dir != "" {
+			answer = withScope(answer, dir)
+		}
+	}
+
+	if o.PlainText {
+		answer = stripMarkdown(answer)
+	}
+
+	return answer
+}
+
+var (
+	// subjectHeaderRegex matches the conventional commit header with the loose spacing around the scope, the
+	// breaking change mark, and the colon (e.g. `feat (api) :add foo`)
+	subjectHeaderRegex = regexp.MustCompile(`^((?:[^\sA-Za-z0-9]+ )?[A-Za-z]+) ?(?:\( *([^()]*?) *\))? ?(!)? ?: *(\S.*)$`)
+
+	// spacesRegex matches the runs of the whitespace characters
+	spacesRegex = regexp.MustCompile(`\s+`)
+)
+
+// normalizeSubject makes the subject (the first line of the message) acceptable for the strict commit linters: the
+// repeated spaces are collapsed, the `type(scope): subject` spacing is enforced (for the conventional commits),
+// and the trailing period is removed. The letter case is kept as is.
+func normalizeSubject(s string) string {
+	s = strings.TrimSpace(spacesRegex.ReplaceAllString(s, " "))
+
+	if m := subjectHeaderRegex.FindStringSubmatch(s); m != nil {
+		var b strings.Builder
+
+		b.WriteString(m[1])
+
+		if m[2] != "" {
+			b.WriteString("(" + m[2] + ")")
+		}
+
+		b.WriteString(m[3] + ": " + m[4])
+
+		s = b.String()
+	}
+
+	if strings.HasSuffix(s, ".") && !strings.HasSuffix(s, "..") { // keep the ellipsis
+		s = strings.TrimRight(strings.TrimSuffix(s, "."), " ")
+	}
+
+	return s
+}
+
+// the Markdown emphasis and inline code (with the text inside to keep); the intraword underscores are not touched
+var (
+	markdownBoldRegex       = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+	markdownUnderscoreRegex = regexp.MustCompile(`(^|[^\w])__([^_\n]+)__([^\w]|$)`)
+	markdownCodeRegex       = regexp.MustCompile("`([^`\n]+)`")
+)
+
+// stripMarkdown removes the Markdown emphasis (`**bold**`, `__bold__`), inline code backticks, and code fences from
+// the message, keeping the text itself.
+func stripMarkdown(message string) string {
+	var lines = strings.Split(message, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "```") {
+			lines = append(lines[:i], lines[i+1:]...)
+			i--
+
+			continue
+		}
+
+		lines[i] = markdownBoldRegex.ReplaceAllString(lines[i], "$1")
+		lines[i] = markdownUnderscoreRegex.ReplaceAllString(lines[i], "$1$2$3")
+		lines[i] = markdownCodeRegex.ReplaceAllString(lines[i], "$1")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// withScope replaces the scope of the conventional commit header (the first line). Non-conventional messages are
+// returned as is.
+func withScope(message, scope string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+
+	header, ok := ParseHeader(subject)
+	if !ok {
+		return message
+	}
+
+	header.Scope = scope
+
+	if hasRest {
+		return header.String() + "\n" + rest
+	}
+
+	return header.String()
+}
+
+// testEmoji is the GitMoji of the test changes.
+const testEmoji = "✅"
+
+// withTestType sets the `test` type of the conventional commit header (the first line), and clears the breaking
+// change mark. The scope chosen by the model is kept; if there is none, the only likely scope (if any) is used.
+// Non-conventional messages are returned as is.
+func withTestType(message string, scopes []string) string {
+	subject, rest, hasRest := strings.Cut(message, "\n")
+
+	header, ok := ParseHeader(subject)
+	if !ok {
+		return message
+	}
+
+	header.Type, header.Breaking = "test", false
+
+	if header.Emoji != "" {
+		header.Emoji = testEmoji
+	}
+
+	if header.Scope == "" && len(scopes) == 1 {
+		header.Scope = scopes[0]
+	}
+
+	if hasRest {
+		return header.String() + "\n" + rest
+	}
+
+	return header.String()
+}
+
+// commonTopLevelDir returns the top-level directory shared by all the files. An empty string is returned if the
+// files span multiple top-level directories, or any of them is located in the root.
+func commonTopLevelDir(files []string) string {
+	var common string
+
+	for _, file := range files {
+		dir, _, found := strings.Cut(file, "/")
+		if !found || dir == "" {
+			return "" // the file is in the root
+		}
+
+		if common == "" {
+			common = dir
+		} else if common != dir {
+			return ""
+		}
+	}
+
+	return common
+}
@@ -0,0 +1,36 @@
+package ai
+
+import "testing"
+
+func TestNormalizeSubject(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct{ give, want string }{
+		"already normalized":         {give: "feat(api): Add foo", want: "feat(api): Add foo"},
+		"no space after colon":       {give: "feat(api):Add foo", want: "feat(api): Add foo"},
+		"space before colon":         {give: "fix : Handle nil", want: "fix: Handle nil"},
+		"several spaces after colon": {give: "fix:   Handle nil", want: "fix: Handle nil"},
+		"space before scope":         {give: "feat (api): Add foo", want: "feat(api): Add foo"},
+		"spaces inside scope":        {give: "feat( api ): Add foo", want: "feat(api): Add foo"},
+		"breaking mark":              {give: "feat(api) ! :Drop v1", want: "feat(api)!: Drop v1"},
+		"emoji":                      {give: "✨  feat:Add foo", want: "✨ feat: Add foo"},
+		"trailing period":            {give: "docs: Update README.", want: "docs: Update README"},
+		"period after space":         {give: "docs: Update README .", want: "docs: Update README"},
+		"ellipsis kept":              {give: "chore: Wait for it...", want: "chore: Wait for it..."},
+		"double spaces":              {give: "refactor:  Move  the   parser", want: "refactor: Move the parser"},
+		"tabs and padding":           {give: "  fix:\tHandle\tnil  ", want: "fix: Handle nil"},
+		"case kept":                  {give: "Docs:add the FAQ", want: "Docs: add the FAQ"},
+		"not conventional":           {give: "Add  foo to the bar.", want: "Add foo to the bar"},
+		"colon in description":       {give: "fix: Handle  key:value pairs", want: "fix: Handle key:value pairs"},
+		"empty description":          {give: "feat:", want: "feat:"},
+		"empty":                      {give: "", want: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := normalizeSubject(tc.give); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
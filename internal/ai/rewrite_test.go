@@ -0,0 +1,228 @@
+package ai_test
+
+import (
+	"context"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestWithForceDirScope(t *testing.T) {
+	t.Parallel()
+
+	const (
+		apiFile     = "diff --git a/api/server.go b/api/server.go\n--- a/api/server.go\n+++ b/api/server.go\n"
+		apiTestFile = "diff --git a/api/server_test.go b/api/server_test.go\n--- a/api/server_test.go\n+++ b/api/server_test.go\n"
+		webFile     = "diff --git a/web/index.ts b/web/index.ts\n--- a/web/index.ts\n+++ b/web/index.ts\n"
+		rootFile    = "diff --git a/go.mod b/go.mod\n--- a/go.mod\n+++ b/go.mod\n"
+	)
+
+	for name, tc := range map[string]struct {
+		giveDiff, giveAnswer string
+		giveOff              bool
+		want                 string
+	}{
+		"single dir, scope replaced": {
+			giveDiff:   apiFile + apiTestFile,
+			giveAnswer: "feat(server): Add graceful shutdown\n\nDetails",
+			want:       "feat(api): Add graceful shutdown\n\nDetails",
+		},
+		"single dir, scope added": {
+			giveDiff:   apiFile,
+			giveAnswer: "✨ feat!: Add graceful shutdown",
+			want:       "✨ feat(api)!: Add graceful shutdown",
+		},
+		"multiple dirs": {
+			giveDiff:   apiFile + webFile,
+			giveAnswer: "feat(server): Add graceful shutdown",
+			want:       "feat(server): Add graceful shutdown",
+		},
+		"root file": {
+			giveDiff:   apiFile + rootFile,
+			giveAnswer: "feat: Add graceful shutdown",
+			want:       "feat: Add graceful shutdown",
+		},
+		"not conventional": {
+			giveDiff:   apiFile,
+			giveAnswer: "Add graceful shutdown",
+			want:       "Add graceful shutdown",
+		},
+		"disabled": {
+			giveDiff:   apiFile,
+			giveAnswer: "feat(server): Add graceful shutdown",
+			giveOff:    true,
+			want:       "feat(server): Add graceful shutdown",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient(tc.giveAnswer))).
+				Query(context.Background(), tc.giveDiff, "", ai.WithForceDirScope(!tc.giveOff))
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, tc.want)
+		})
+	}
+}
+
+func TestWithTestOnlyType(t *testing.T) {
+	t.Parallel()
+
+	const (
+		apiFile     = "diff --git a/api/server.go b/api/server.go\n--- a/api/server.go\n+++ b/api/server.go\n"
+		apiTestFile = "diff --git a/api/server_test.go b/api/server_test.go\n--- a/api/server_test.go\n+++ b/api/server_test.go\n"
+		webSpecFile = "diff --git a/web/app.spec.ts b/web/app.spec.ts\n--- a/web/app.spec.ts\n+++ b/web/app.spec.ts\n"
+		fixtureFile = "diff --git a/api/testdata/a.json b/api/testdata/a.json\n--- a/api/testdata/a.json\n+++ b/api/testdata/a.json\n"
+	)
+
+	for name, tc := range map[string]struct {
+		giveDiff, giveAnswer string
+		giveOff              bool
+		want                 string
+	}{
+		"tests only, scope added": {
+			giveDiff:   apiTestFile + fixtureFile,
+			giveAnswer: "feat!: Add graceful shutdown tests\n\nDetails",
+			want:       "test(api): Add graceful shutdown tests\n\nDetails",
+		},
+		"tests only, scope kept": {
+			giveDiff:   apiTestFile + webSpecFile,
+			giveAnswer: "✨ fix(server): Cover graceful shutdown",
+			want:       "✅ test(server): Cover graceful shutdown",
+		},
+		"tests only, multiple dirs": {
+			giveDiff:   apiTestFile + webSpecFile,
+			giveAnswer: "fix: Cover graceful shutdown",
+			want:       "test: Cover graceful shutdown",
+		},
+		"not only tests": {
+			giveDiff:   apiFile + apiTestFile,
+			giveAnswer: "feat(api): Add graceful shutdown",
+			want:       "feat(api): Add graceful shutdown",
+		},
+		"disabled": {
+			giveDiff:   apiTestFile,
+			giveAnswer: "feat: Add graceful shutdown tests",
+			giveOff:    true,
+			want:       "feat: Add graceful shutdown tests",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient(tc.giveAnswer))).
+				Query(context.Background(), tc.giveDiff, "", ai.WithTestOnlyType(!tc.giveOff))
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, tc.want)
+		})
+	}
+}
+
+func TestWithPlainText(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		giveOff    bool
+		want       string
+	}{
+		"emphasis and inline code": {
+			giveAnswer: "feat(api): Add `RateLimiter`\n\nA **summary**: requests are __limited__ by `limit`.\n- **Redis** based",
+			want:       "feat(api): Add RateLimiter\n\nA summary: requests are limited by limit.\n- Redis based",
+		},
+		"code fences": {
+			giveAnswer: "docs: Describe usage\n\n```yaml\nlimit: 10\n```",
+			want:       "docs: Describe usage\n\nlimit: 10",
+		},
+		"intraword underscores and lone asterisks are kept": {
+			giveAnswer: "fix: Handle a__b__c and a * b ** c",
+			want:       "fix: Handle a__b__c and a * b ** c",
+		},
+		"disabled": {
+			giveAnswer: "feat: Add **foo**",
+			giveOff:    true,
+			want:       "feat: Add **foo**",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient(tc.giveAnswer))).
+				Query(context.Background(), "diff", "", ai.WithPlainText(!tc.giveOff))
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, tc.want)
+		})
+	}
+}
+
+func TestWithStrictSubjectFormat(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		giveOpts   []ai.Option
+		want       string
+	}{
+		"default": {
+			giveAnswer: "feat(api) :Add  the limiter.\n\nThe body.  Untouched.",
+			want:       "feat(api): Add the limiter\n\nThe body.  Untouched.",
+		},
+		"disabled": {
+			giveAnswer: "feat(api) :Add  the limiter.",
+			giveOpts:   []ai.Option{ai.WithStrictSubjectFormat(false)},
+			want:       "feat(api) :Add  the limiter.",
+		},
+		"not a commit message": {
+			giveAnswer: "Add  the limiter.",
+			giveOpts:   []ai.Option{ai.WithOutputFormat(ai.FormatGitNote)},
+			want:       "Add  the limiter.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient(tc.giveAnswer))).
+				Query(context.Background(), "diff", "", tc.giveOpts...)
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, tc.want)
+		})
+	}
+}
+
+func TestWithASCIIOnly(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		giveOff    bool
+		want       string
+	}{
+		"accents and emoji": {
+			giveAnswer: "✨ feat(café): Add the naïve “smart” résumé parser 🚀\n\n" +
+				"- Support Łódź, Ærøskøbing — and Straße…\n- Keep 👍🏽 reactions",
+			want: "feat(cafe): Add the naive \"smart\" resume parser\n\n" +
+				"- Support Lodz, AEroskobing - and Strasse...\n- Keep reactions",
+		},
+		"not transliterable": {giveAnswer: "docs: Translate the 日本語 guide", want: "docs: Translate the guide"},
+		"ascii":              {giveAnswer: "fix: Handle the nil map", want: "fix: Handle the nil map"},
+		"disabled": {
+			giveAnswer: "feat: Add the café ✨",
+			giveOff:    true,
+			want:       "feat: Add the café ✨",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(okClient(tc.giveAnswer))).
+				Query(context.Background(), "diff", "", ai.WithASCIIOnly(!tc.giveOff))
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, tc.want)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"slices"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// singleflightProvider shares a single upstream request between concurrent identical queries.
+type singleflightProvider struct {
+	p Provider
+	g singleflight.Group
+}
+
+var _ Provider = (*singleflightProvider)(nil) // ensure the interface is implemented
+
+// Singleflight wraps the provider so that concurrent identical queries (the same changes, commits, and options
+// affecting the output) share a single upstream request. The shared request is not canceled together with the
+// context of the caller that started it; instead, every caller stops waiting for it when its own context is done.
+//
+// Queries using options that can't be compared (like [WithPostProcess], [WithAuditLog], or [WithStream]) are never
+// shared.
+func Singleflight(p Provider) Provider { return &singleflightProvider{p: p} }
+
+func (s *singleflightProvider) Query(
+	ctx context.Context,
+	changes, commits string,
+	opts ...Option,
+) (*Response, error) {
+	key, ok := queryKey(changes, commits, opts...)
+	if !ok {
+		return s.p.Query(ctx, changes, commits, opts...)
+	}
+
+	var ch = s.g.DoChan(key, func() (any, error) {
+		return s.p.Query(context.WithoutCancel(ctx), changes, commits, opts...)
+	})
+
+	var res singleflight.Result
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-ch:
+	}
+
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	var resp = res.Val.(*Response) //nolint:forcetypeassert
+
+	// every caller gets its own copy of the response
+	var clone = *resp
+
+	clone.Answers, clone.Alternatives = slices.Clone(resp.Answers), slices.Clone(resp.Alternatives)
+
+	return &clone, nil
+}
+
+// queryKey returns the [GenerationKey] of the query (the model of the provider is not included, since it's the same
+// for all the queries of the provider). False is returned if the options can't be compared.
+func queryKey(changes, commits string, opts ...Option) (string, bool) {
+	if o := (options{}).Apply(opts...); o.AuditLog != nil || o.PostProcess != nil || o.Stream != nil {
+		return "", false
+	}
+
+	return GenerationKey("", changes, commits, opts...), true
+}
+
+// Name implements the [Provider] interface (the name of the wrapped provider is returned).
+func (s *singleflightProvider) Name() string { return s.p.Name() }
@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// blockingProvider counts the queries and blocks them until released.
+type blockingProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (p *blockingProvider) Query(context.Context, string, string, ...Option) (*Response, error) {
+	p.calls.Add(1)
+	<-p.release
+
+	return &Response{Answer: "feat: Add foo", Answers: []string{"feat: Add foo"}}, nil
+}
+
+func (p *blockingProvider) Name() string { return "blocking" }
+
+// waitingContext signals when the query starts waiting for the result (the Done method is called).
+type waitingContext struct {
+	context.Context
+
+	once    sync.Once
+	waiting chan<- struct{}
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.once.Do(func() { c.waiting <- struct{}{} })
+
+	return c.Context.Done()
+}
+
+func TestSingleflight_SharesInFlightQuery(t *testing.T) {
+	t.Parallel()
+
+	const n = 10
+
+	var (
+		upstream = &blockingProvider{release: make(chan struct{})}
+		provider = Singleflight(upstream)
+		waiting  = make(chan struct{}, n)
+		wg       sync.WaitGroup
+	)
+
+	wg.Add(n)
+
+	for range n {
+		go func() {
+			defer wg.Done()
+
+			resp, err := provider.Query(&waitingContext{Context: context.Background(), waiting: waiting}, "diff", "log")
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			if resp.Answer != "feat: Add foo" {
+				t.Errorf("unexpected answer: %q", resp.Answer)
+			}
+		}()
+	}
+
+	for range n { // wait for all the queries to join the in-flight one
+		<-waiting
+	}
+
+	close(upstream.release)
+	wg.Wait()
+
+	if got := upstream.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d; want 1", got)
+	}
+}
+
+func TestSingleflight_CanceledCaller(t *testing.T) {
+	t.Parallel()
+
+	var (
+		upstream    = &blockingProvider{release: make(chan struct{})}
+		provider    = Singleflight(upstream)
+		waiting     = make(chan struct{}, 2)
+		ctx, cancel = context.WithCancel(context.Background())
+		first       = make(chan error, 1)
+		second      = make(chan error, 1)
+	)
+
+	go func() {
+		_, err := provider.Query(&waitingContext{Context: ctx, waiting: waiting}, "diff", "log")
+		first <- err
+	}()
+
+	<-waiting // the first caller started the shared query
+
+	go func() {
+		_, err := provider.Query(&waitingContext{Context: context.Background(), waiting: waiting}, "diff", "log")
+		second <- err
+	}()
+
+	<-waiting // the second caller joined it
+
+	cancel()
+
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("the first caller: expected the context error, got %v", err)
+	}
+
+	close(upstream.release)
+
+	if err := <-second; err != nil {
+		t.Errorf("the second caller: the shared query must not be canceled, got %v", err)
+	}
+
+	if got := upstream.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d; want 1", got)
+	}
+}
+
+func TestQueryKey(t *testing.T) {
+	t.Parallel()
+
+	var base, ok = queryKey("diff", "log", WithEmoji(false))
+
+	if !ok {
+		t.Fatal("expected the key")
+	}
+
+	for _, opt := range []Option{WithEmoji(true), WithShortMessageOnly(true)} { // the options affecting the output
+		if key, _ := queryKey("diff", "log", opt); key == base {
+			t.Errorf("expected the options to be a part of the key")
+		}
+	}
+
+	if _, ok = queryKey("diff", "log", WithPostProcess(func(s string) string { return s })); ok {
+		t.Error("the queries with the functions in the options must not be shared")
+	}
+}
@@ -0,0 +1,37 @@
+package ai_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestSingleflight(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls  atomic.Int32
+		client = fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			calls.Add(1)
+
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+		}}
+		provider = ai.Singleflight(ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(&client)))
+	)
+
+	resp, err := provider.Query(context.Background(), "diff", "log", ai.WithEmoji(false))
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+
+	// the sharing of the in-flight queries is tested by TestSingleflight_SharesInFlightQuery
+	for _, opt := range []ai.Option{ai.WithEmoji(true), ai.WithShortMessageOnly(true)} {
+		_, err = provider.Query(context.Background(), "diff", "log", opt)
+		assertNoError(t, err)
+	}
+
+	assertEqual(t, calls.Load(), int32(3), "upstream calls")
+}
@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxEventSize limits the size of a single server-sent event line.
+const maxEventSize = 1 << 20 // 1 MiB
+
+// sseDone is the data of the event that marks the end of the OpenAI-compatible stream.
+const sseDone = "[DONE]"
+
+// readEvents reads the server-sent events stream (https://html.spec.whatwg.org/multipage/server-sent-events.html),
+// calling the given function with the data of every event. Comments and the fields other than `data` are ignored.
+// Reading stops at the end of the stream, on the `[DONE]` event, or when the function returns an error.
+func readEvents(r io.Reader, fn func(data []byte) error) error {
+	var (
+		scanner = bufio.NewScanner(r)
+		data    bytes.Buffer
+		done    bool
+	)
+
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize) //nolint:mnd
+
+	var dispatch = func() error {
+		if data.Len() == 0 {
+			return nil
+		}
+
+		defer data.Reset()
+
+		if string(data.Bytes()) == sseDone {
+			done = true
+
+			return nil
+		}
+
+		return fn(data.Bytes())
+	}
+
+	for !done && scanner.Scan() {
+		var line = scanner.Bytes()
+
+		if len(line) == 0 { // an empty line dispatches the event
+			if err := dispatch(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if line[0] == ':' { // comment (e.g. the keep-alive)
+			continue
+		}
+
+		if field, value, _ := bytes.Cut(line, []byte(":")); string(field) == "data" {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+
+			data.Write(bytes.TrimPrefix(value, []byte(" ")))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+	}
+
+	if done {
+		return nil
+	}
+
+	return dispatch() // the stream may end without the trailing empty line
+}
+
+// finishReasonLength is the finish reason of the OpenAI-compatible APIs, reported when the output is cut off by the
+// output tokens limit.
+const finishReasonLength = "length"
+
+// readChatStream reads the OpenAI-compatible chat completion stream, passing the content deltas to the given
+// function. The whole (finalized) content is returned as the single answer.
+//
+// If the provider sends the error object instead of the chunk (e.g. when it fails after the stream has started),
+// reading is aborted and the [APIError] is returned. On any error, the body is closed at once, without draining,
+// since the server may keep the stream open.
+func readChatStream(provider string, body io.ReadCloser, onDelta func(string)) (*completion, error) {
+	var (
+		text      strings.Builder
+		usage     Usage
+		truncated bool
+	)
+
+	if err := readEvents(body, func(data []byte) error {
+		var chunk struct {
+			Error   *apiErrorDetails `json:"error"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+				TotalTokens      int `json:"total_tokens"`
+			} `json:"usage"`
+		}
+
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+		}
+
+		if chunk.Error != nil {
+			return newStreamError(provider, chunk.Error)
+		}
+
+		if chunk.Usage != nil { // sent with the last chunk (if requested)
+			usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" { // sent with the last content chunk
+			truncated = chunk.Choices[0].FinishReason == finishReasonLength
+		}
+
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			text.WriteString(chunk.Choices[0].Delta.Content)
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+
+		return nil
+	}); err != nil {
+		_ = body.Close()
+
+		return nil, err
+	}
+
+	var answer = finalizeAnswer(text.String())
+	if answer == "" {
+		return nil, errors.New("no content found in the stream")
+	}
+
+	return &completion{Answers: []string{answer}, Usage: usage, Streamed: true, Truncated: truncated}, nil
+}
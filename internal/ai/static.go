@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// StaticProvider is the offline provider generating the deterministic message from the list of the changed files
+// (e.g. "chore: Update 2 files", with the files listed in the body), without any network requests. It's meant for
+// the docs, the demos, and the tests of the code using the providers. The zero value is ready to use.
+type StaticProvider struct{}
+
+var _ Provider = StaticProvider{} // ensure the interface is implemented
+
+// staticName is the name (and the model name) of the [StaticProvider].
+const staticName = "static"
+
+// Query implements the [Provider] interface.
+func (p StaticProvider) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	return query(ctx, p, changes, commits, opts...)
+}
+
+// Name implements the [Provider] interface.
+func (StaticProvider) Name() string { return staticName }
+
+// model returns the model name.
+func (StaticProvider) model() string { return staticName }
+
+// batchesCandidates reports whether all the candidates are generated using a single request.
+func (StaticProvider) batchesCandidates() bool { return true }
+
+// complete generates the message listing the changed files. The message is always the same for the same changes,
+// so a single answer is returned regardless of the requested number of candidates.
+func (StaticProvider) complete(_ context.Context, _, changes, _ string, _ options) (*completion, error) {
+	return &completion{Answers: []string{staticMessage(git.ChangedFiles(changes))}}, nil
+}
+
+// staticMessage returns the message of the [StaticProvider] for the changed files.
+func staticMessage(files []string) string {
+	switch len(files) {
+	case 0:
+		return "chore: Update files"
+	case 1:
+		return "chore: Update 1 file\n\n- " + files[0]
+	}
+
+	return fmt.Sprintf("chore: Update %d files\n\n- %s", len(files), strings.Join(files, "\n- "))
+}
@@ -0,0 +1,49 @@
+package ai_test
+
+import (
+	"context"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestStaticProvider(t *testing.T) {
+	t.Parallel()
+
+	const (
+		oneFile  = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package foo\n+package main\n"
+		twoFiles = oneFile +
+			"diff --git a/docs/README.md b/docs/README.md\nnew file mode 100644\n--- /dev/null\n" +
+			"+++ b/docs/README.md\n@@ -0,0 +1 @@\n+# Docs\n"
+	)
+
+	for name, tc := range map[string]struct {
+		giveChanges string
+		giveOpts    []ai.Option
+		want        string
+	}{
+		"one file":   {giveChanges: oneFile, want: "chore: Update 1 file\n\n- main.go"},
+		"two files":  {giveChanges: twoFiles, want: "chore: Update 2 files\n\n- main.go\n- docs/README.md"},
+		"not a diff": {giveChanges: "some changes", want: "chore: Update files"},
+		"short": {
+			giveChanges: twoFiles,
+			giveOpts:    []ai.Option{ai.WithShortMessageOnly(true)},
+			want:        "chore: Update 2 files",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var p ai.StaticProvider
+
+			for range 2 { // stable output
+				resp, err := p.Query(context.Background(), tc.giveChanges, "log", tc.giveOpts...)
+				assertNoError(t, err)
+
+				assertEqual(t, resp.Answer, tc.want)
+				assertEqual(t, resp.Provider, "static")
+				assertEqual(t, resp.Usage, ai.Usage{})
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package ai
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultStreamGrace is the default time to wait for the first streamed delta (see [WithStreamFallback]).
+const defaultStreamGrace = 15 * time.Second
+
+// fixPrompt returns the instructions for the request fixing the invalid streamed message. The original instructions
+// are kept, so the fixed message follows the same guidelines.
+func fixPrompt(instructions, message string, problem error) string {
+	var b strings.Builder
+
+	b.WriteString(strings.TrimRight(instructions, "\n"))
+	b.WriteString("\n\n## Correction\n")
+	b.WriteString("The commit message below was written for the changes, but it does not follow the guidelines (")
+	b.WriteString(problem.Error())
+	b.WriteString("). Rewrite it to follow the guidelines, keeping its meaning. The diff and the log are omitted; ")
+	b.WriteString("do not ask for them. Output only the corrected commit message.\n\n")
+	b.WriteString("```\n")
+	b.WriteString(message)
+	b.WriteString("\n```\n")
+
+	return b.String()
+}
+
+// fixAnswer replaces the answer with the one fixed using an additional non-streaming request, describing the problem
+// to the model. The warnings about the replaced answer are dropped, the rest of them are kept.
+func fixAnswer(
+	ctx context.Context,
+	c completer,
+	instructions, changes string,
+	r *Response,
+	problem error,
+	o options,
+) error {
+	o.Stream, o.Candidates = nil, 1
+
+	// the changes are not sent again to keep the request quick and cheap - the message already describes them
+	res, err := complete(ctx, c, fixPrompt(instructions, r.Answer, problem), "", "", o)
+	if err != nil {
+		return err
+	}
+
+	if len(res.Answers) == 0 {
+		return errors.New("empty response")
+	}
+
+	var fixed = res.Answers[0]
+
+	if o.jsonOutput() { // the instructions (and the response format) ask for the object with the self-assessment
+		if message, a, ok := parseJSONAnswer(fixed); ok {
+			fixed, r.Confidence, r.Rationale = message, a.Confidence, a.Rationale
+		}
+	}
+
+	if o.ShortMessageOnly {
+		fixed, _, _ = strings.Cut(fixed, "\n")
+	}
+
+	for _, warning := range validateAnswer(r.Answer, changes, o) {
+		if i := slices.Index(r.Warnings, warning); i >= 0 {
+			r.Warnings = slices.Delete(r.Warnings, i, i+1)
+		}
+	}
+
+	r.Answer, r.Fixed, r.Usage = polishAnswer(fixed, changes, res.Model, o), true, r.Usage.add(res.Usage)
+	r.Answers[0] = r.Answer
+	r.Warnings = append(r.Warnings, validateAnswer(r.Answer, changes, o)...)
+
+	return nil
+}
+
+// checkStreamed validates the streamed message (it can't be corrected before it's shown). The issue is reported as
+// a warning, or the message is fixed using an additional non-streaming request (see [WithFixInvalidStream]).
+func checkStreamed(ctx context.Context, c completer, instructions, changes string, r *Response, o options) error {
+	var problem = r.Validate()
+	if problem == nil {
+		return nil
+	}
+
+	if !o.FixInvalidStream {
+		r.Warnings = append(r.Warnings, problem.Error())
+
+		return nil
+	}
+
+	if err := fixAnswer(ctx, c, instructions, changes, r, problem, o); err != nil {
+		return fmt.Errorf("failed to fix the streamed message: %w", err)
+	}
+
+	if err := r.Validate(); err != nil {
+		r.Warnings = append(r.Warnings, err.Error())
+	}
+
+	return nil
+}
+
+// completeWithStreamFallback performs the streaming completion. If no delta arrives within the grace period, the
+// request is canceled and repeated without streaming (the late deltas of the canceled request are dropped).
+func completeWithStreamFallback(
+	ctx context.Context,
+	c completer,
+	instructions, changes, commits string,
+	o options,
+) (*completion, error) {
+	var (
+		streamCtx, cancel = context.WithCancel(ctx)
+		stream            = o.Stream
+		mu                sync.Mutex
+		started, stalled  bool
+	)
+
+	defer cancel()
+
+	o.Stream = func(delta string) {
+		mu.Lock()
+
+		if stalled {
+			mu.Unlock()
+
+			return
+		}
+
+		started = true
+		mu.Unlock()
+
+		stream(delta)
+	}
+
+	var isStalled = func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return stalled
+	}
+
+	var timer = time.AfterFunc(cmp.Or(o.StreamGrace, defaultStreamGrace), func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !started {
+			stalled = true
+
+			cancel()
+		}
+	})
+
+	res, err := completeWithRetries(streamCtx, c, instructions, changes, commits, o)
+
+	if !timer.Stop() && isStalled() && ctx.Err() == nil {
+		o.Stream = nil // the answer is passed to the stream function by the caller (see [collectCandidates])
+
+		return completeWithRetries(ctx, c, instructions, changes, commits, o)
+	}
+
+	return res, err
+}
+
+// queryStream is the shared part of the [StreamingProvider.QueryStream] implementations. The query is made in the
+// background with the streaming enabled (see [WithStream]), and the deltas are sent to the channel. When only the
+// short message is requested, the stream is cut off after the first newline, and the request is canceled.
+func queryStream(
+	ctx context.Context,
+	c completer,
+	changes, commits string,
+	opts ...Option,
+) (<-chan string, <-chan error) {
+	var (
+		deltas = make(chan string)
+		errs   = make(chan error, 1)
+	)
+
+	go func() {
+		defer close(errs)
+		defer close(deltas)
+
+		var (
+			shortOnly   = options{}.Apply(opts...).ShortMessageOnly
+			cut, lost   bool // the stream is cut off (short message), or the deltas are lost (canceled)
+			sCtx, abort = context.WithCancel(ctx)
+		)
+
+		defer abort()
+
+		_, err := query(sCtx, c, changes, commits, append(opts[:len(opts):len(opts)], WithStream(func(delta string) {
+			if cut {
+				return
+			}
+
+			if shortOnly {
+				if before, _, found := strings.Cut(delta, "\n"); found {
+					delta, cut = before, true
+
+					defer abort() // the rest of the message is not needed
+				}
+			}
+
+			if delta == "" {
+				return
+			}
+
+			select {
+			case deltas <- delta:
+			case <-sCtx.Done():
+				lost = true
+			}
+		}))...)
+
+		if err == nil && lost {
+			err = ctx.Err() // the caller stopped reading
+		}
+
+		if err != nil && !(cut && ctx.Err() == nil && errors.Is(err, context.Canceled)) {
+			errs <- err
+		}
+	}()
+
+	return deltas, errs
+}
+
+type (
+	// EventKind is the kind of the [Event].
+	EventKind int
+
+	// Event is the progress event of the query made by [QueryChan].
+	Event struct {
+		Kind     EventKind
+		Text     string    // the message delta ([EventDelta] only)
+		Response *Response // the final response ([EventDone] only)
+		Err      error     // the query error ([EventError] only)
+	}
+)
+
+const (
+	// EventDelta carries the message delta, as soon as it's generated by the model.
+	EventDelta EventKind = iota
+
+	// EventDone is the last event of the successful query, carrying the final (normalized) response.
+	EventDone
+
+	// EventError is the last event of the failed query.
+	EventError
+)
+
+// QueryChan queries the provider in the background with the streaming enabled (see [WithStream]), sending the
+// message deltas, and then the final response (or the error) to the channel, so the caller can `range` over the
+// events (e.g. to render the progress in the UI). The channel is closed on completion. The caller must drain the
+// channel or cancel the context. The error is returned (and nothing is queried) if the options are invalid.
+func QueryChan(ctx context.Context, p Provider, changes, commits string, opts ...Option) (<-chan Event, error) {
+	if err := (options{}).Apply(opts...).validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		events = make(chan Event)
+		send   = func(e Event) {
+			select {
+			case events <- e:
+			case <-ctx.Done(): // the caller stopped reading
+			}
+		}
+	)
+
+	go func() {
+		defer close(events)
+
+		resp, err := p.Query(ctx, changes, commits, append(opts[:len(opts):len(opts)], WithStream(func(delta string) {
+			if delta != "" {
+				send(Event{Kind: EventDelta, Text: delta})
+			}
+		}))...)
+		if err != nil {
+			send(Event{Kind: EventError, Err: err})
+
+			return
+		}
+
+		send(Event{Kind: EventDone, Response: resp})
+	}()
+
+	return events, nil
+}
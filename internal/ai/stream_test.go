@@ -0,0 +1,523 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+// sseBody builds the OpenAI-compatible stream with the given content deltas.
+func sseBody(deltas ...string) string {
+	var b strings.Builder
+
+	b.WriteString(": keep-alive\n\n")
+
+	for _, delta := range deltas {
+		j, _ := json.Marshal(delta)
+
+		b.WriteString(`data: {"choices":[{"delta":{"content":` + string(j) + "}}]}\n\n")
+	}
+
+	b.WriteString(`data: {"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}` + "\n\n")
+	b.WriteString("data: [DONE]\n\n")
+
+	return b.String()
+}
+
+func TestQuery_Stream(t *testing.T) {
+	t.Parallel()
+
+	for name, newProvider := range map[string]func(*fakeHttpClient) ai.Provider{
+		"openai": func(c *fakeHttpClient) ai.Provider { return ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(c)) },
+		"openrouter": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewOpenRouter("key", "model", ai.WithOpenRouterHttpClient(c))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return newHttpResponse(http.StatusOK, sseBody("feat(api): ", "Add ", "foo\n\n", "- Details")), nil
+			}}
+
+			var deltas []string
+
+			resp, err := newProvider(client).Query(context.Background(), "diff", "log",
+				ai.WithStream(func(delta string) { deltas = append(deltas, delta) }),
+			)
+			assertNoError(t, err)
+
+			assertEqual(t, strings.Join(deltas, "|"), "feat(api): |Add |foo\n\n|- Details")
+			assertEqual(t, resp.Answer, "feat(api): Add foo\n\n- Details")
+			assertEqual(t, resp.Usage.TotalTokens, 15)
+			assertEqual(t, resp.Fixed, false)
+			assertEqual(t, len(resp.Warnings), 0)
+
+			var request = client.Requests()[0]
+
+			assertEqual(t, strings.Contains(request, `"stream":true`), true, "stream")
+			assertEqual(t, strings.Contains(request, `"stream_options":{"include_usage":true}`), true, "options")
+		})
+	}
+}
+
+func TestQuery_StreamWithoutProviderSupport(t *testing.T) {
+	t.Parallel()
+
+	var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusOK, `{"candidates":[{"content":{"parts":[{"text":"feat: Add foo"}]}}]}`), nil
+	}}
+
+	var deltas []string
+
+	resp, err := ai.NewGemini("key", "model", ai.WithGeminiHttpClient(client)).Query(context.Background(), "diff", "log",
+		ai.WithStream(func(delta string) { deltas = append(deltas, delta) }),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, len(deltas), 1)
+	assertEqual(t, deltas[0], "feat: Add foo")
+}
+
+func TestQuery_StreamValidation(t *testing.T) {
+	t.Parallel()
+
+	var newClient = func() *fakeHttpClient {
+		return &fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+			if n == 0 {
+				return newHttpResponse(http.StatusOK, sseBody("Added ", "foo")), nil
+			}
+
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+		}}
+	}
+
+	t.Run("warning", func(t *testing.T) {
+		t.Parallel()
+
+		var client = newClient()
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithStream(func(string) {}))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "Added foo")
+		assertEqual(t, resp.Fixed, false)
+		assertEqual(t, len(resp.Warnings), 1)
+		assertEqual(t, strings.Contains(resp.Warnings[0], "invalid commit message"), true)
+		assertEqual(t, len(client.Requests()), 1)
+	})
+
+	t.Run("fix", func(t *testing.T) {
+		t.Parallel()
+
+		var client = newClient()
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithStream(func(string) {}), ai.WithFixInvalidStream(true))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+		assertEqual(t, resp.Fixed, true)
+		assertEqual(t, len(resp.Warnings), 0)
+
+		var requests = client.Requests()
+
+		assertEqual(t, len(requests), 2)
+		assertEqual(t, strings.Contains(requests[1], `"stream"`), false, "not streamed")
+
+		var fix = openaiMessages(t, requests[1])[0]
+
+		assertEqual(t, strings.Contains(fix, "## Correction"), true, "correction")
+		assertEqual(t, strings.Contains(fix, "Added foo"), true, "the invalid message")
+		assertEqual(t, strings.Contains(fix, "does not follow the `<type>(<scope>): <description>` format"), true)
+	})
+
+	t.Run("fix keeps the other warnings", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+			if n == 0 {
+				return newHttpResponse(http.StatusOK, sseBody("chore: Update ", strings.Repeat("foo ", 20)+"bar")), nil
+			}
+
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"chore: Update foo"}}]}`), nil
+		}}
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).Query(
+			context.Background(), "diff --git a/foo.go b/foo.go\n+foo", "log",
+			ai.WithStream(func(string) {}), ai.WithFixInvalidStream(true), ai.WithDiscourageChore(true),
+			ai.WithMaxCandidateCost(1), // the price of the model is unknown, so it's reported as a warning
+		)
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "chore: Update foo")
+		assertEqual(t, resp.Fixed, true)
+		assertEqual(t, len(resp.Warnings), 2, "the warning about the streamed message is replaced")
+		assertEqual(t, strings.Contains(resp.Warnings[0], "the candidates cost limit is not enforced"), true)
+		assertEqual(t, strings.Contains(resp.Warnings[1], "`chore` type"), true)
+	})
+
+	t.Run("valid message is not fixed", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK, sseBody("fix: ", "Handle the empty input")), nil
+		}}
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), "diff", "log", ai.WithStream(func(string) {}), ai.WithFixInvalidStream(true))
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "fix: Handle the empty input")
+		assertEqual(t, resp.Fixed, false)
+		assertEqual(t, len(client.Requests()), 1)
+	})
+}
+
+func TestQuery_StreamErrorFrame(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveFrame    string
+		wantSentinel error
+		wantCode     string
+	}{
+		"numeric code": {
+			giveFrame:    `{"error":{"message":"Provider returned error","code":502}}`,
+			wantSentinel: ai.ErrProviderUnavailable,
+			wantCode:     "502",
+		},
+		"rate limit": {
+			giveFrame:    `{"error":{"message":"Rate limit exceeded","code":429}}`,
+			wantSentinel: ai.ErrRateLimited,
+			wantCode:     "429",
+		},
+		"string code": {
+			giveFrame:    `{"error":{"message":"The server had an error","type":"server_error","code":"server_error"}}`,
+			wantSentinel: ai.ErrProviderUnavailable,
+			wantCode:     "server_error",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var pr, pw = io.Pipe()
+
+			go func() {
+				_, _ = io.WriteString(pw, `data: {"choices":[{"delta":{"content":"feat: "}}]}`+"\n\n")
+				_, _ = io.WriteString(pw, `data: {"choices":[{"delta":{"content":"Add"}}]}`+"\n\n")
+				_, _ = io.WriteString(pw, "data: "+tc.giveFrame+"\n\n")
+				// the stream is kept open (the writer is never closed), so reading it to the end would hang
+			}()
+
+			var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: pr}, nil
+			}}
+
+			var deltas []string
+
+			_, err := ai.NewOpenRouter("key", "model", ai.WithOpenRouterHttpClient(client)).
+				Query(context.Background(), "diff", "log", ai.WithStream(func(d string) { deltas = append(deltas, d) }))
+
+			if !errors.Is(err, tc.wantSentinel) {
+				t.Fatalf("expected %v, got %v", tc.wantSentinel, err)
+			}
+
+			var apiErr *ai.APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected the APIError, got %T", err)
+			}
+
+			assertEqual(t, apiErr.InStream, true)
+			assertEqual(t, apiErr.Code, tc.wantCode)
+			assertEqual(t, apiErr.Provider, "OpenRouter")
+			assertEqual(t, strings.Join(deltas, ""), "feat: Add")
+			assertEqual(t, len(client.Requests()), 1)
+		})
+	}
+}
+
+func TestQueryStream(t *testing.T) {
+	t.Parallel()
+
+	var providers = map[string]func(*fakeHttpClient) ai.StreamingProvider{
+		"openai": func(c *fakeHttpClient) ai.StreamingProvider {
+			return ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(c))
+		},
+		"openrouter": func(c *fakeHttpClient) ai.StreamingProvider {
+			return ai.NewOpenRouter("", "", ai.WithOpenRouterHttpClient(c))
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		giveCode   int
+		giveBody   string
+		giveOpts   []ai.Option
+		wantDeltas []string
+		wantErr    error
+	}{
+		"deltas": {
+			giveCode:   http.StatusOK,
+			giveBody:   sseBody("feat: ", "Add foo", "\n\nThe body"),
+			wantDeltas: []string{"feat: ", "Add foo", "\n\nThe body"},
+		},
+		"short message only": {
+			giveCode:   http.StatusOK,
+			giveBody:   sseBody("feat: ", "Add foo\n", "\nThe body"),
+			giveOpts:   []ai.Option{ai.WithShortMessageOnly(true)},
+			wantDeltas: []string{"feat: ", "Add foo"},
+		},
+		"error": {
+			giveCode: http.StatusUnauthorized,
+			giveBody: `{"error":{"message":"invalid key"}}`,
+			wantErr:  ai.ErrUnauthorized,
+		},
+	} {
+		for providerName, newProvider := range providers {
+			t.Run(providerName+"/"+name, func(t *testing.T) {
+				t.Parallel()
+
+				var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+					return newHttpResponse(tc.giveCode, tc.giveBody), nil
+				}}
+
+				deltas, errs := newProvider(client).QueryStream(context.Background(), "diff", "log", tc.giveOpts...)
+
+				var got []string
+
+				for delta := range deltas {
+					got = append(got, delta)
+				}
+
+				var err = <-errs
+
+				if tc.wantErr != nil {
+					if !errors.Is(err, tc.wantErr) {
+						t.Fatalf("expected %v, got %v", tc.wantErr, err)
+					}
+				} else {
+					assertNoError(t, err)
+				}
+
+				assertEqual(t, strings.Join(got, "|"), strings.Join(tc.wantDeltas, "|"))
+				assertEqual(t, strings.Contains(client.Requests()[0], `"stream":true`), true, "stream")
+
+				if _, open := <-errs; open {
+					t.Error("expected the errors channel to be closed")
+				}
+			})
+		}
+	}
+}
+
+func TestQueryStream_Canceled(t *testing.T) {
+	t.Parallel()
+
+	var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusOK, sseBody("feat: ", "Add foo", " and bar")), nil
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	deltas, errs := ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)).QueryStream(ctx, "diff", "log")
+
+	assertEqual(t, <-deltas, "feat: ")
+
+	cancel() // the rest of the deltas is not read
+
+	for range deltas {
+		// drain the rest until the channel is closed
+	}
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancellation error, got %v", err)
+	}
+}
+
+func TestQuery_StreamFallback(t *testing.T) {
+	t.Parallel()
+
+	var client = &fakeHttpClient{handler: func(req *http.Request, n int) (*http.Response, error) {
+		if n > 0 {
+			return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+		}
+
+		var body, w = io.Pipe() // the stream stalls (e.g. the proxy buffers it) until the request is canceled
+
+		go func() {
+			<-req.Context().Done()
+
+			_ = w.CloseWithError(req.Context().Err())
+		}()
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/event-stream"}},
+			Body:       body,
+		}, nil
+	}}
+
+	var deltas []string
+
+	resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).Query(context.Background(), "diff", "log",
+		ai.WithStream(func(delta string) { deltas = append(deltas, delta) }),
+		ai.WithStreamFallback(true),
+		ai.WithStreamGracePeriod(50*time.Millisecond),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, strings.Join(deltas, "|"), "feat: Add foo") // passed at once
+
+	var requests = client.Requests()
+
+	assertEqual(t, len(requests), 2)
+	assertEqual(t, strings.Contains(requests[0], `"stream":true`), true, "the first request is streaming")
+	assertEqual(t, strings.Contains(requests[1], `"stream":true`), false, "the fallback is not streaming")
+}
+
+func TestQuery_StreamFallbackNotNeeded(t *testing.T) {
+	t.Parallel()
+
+	var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		return newHttpResponse(http.StatusOK, sseBody("feat: ", "Add foo")), nil
+	}}
+
+	var deltas []string
+
+	resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(client)).Query(context.Background(), "diff", "log",
+		ai.WithStream(func(delta string) { deltas = append(deltas, delta) }),
+		ai.WithStreamFallback(true),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat: Add foo")
+	assertEqual(t, strings.Join(deltas, "|"), "feat: |Add foo")
+	assertEqual(t, len(client.Requests()), 1)
+}
+
+func TestQueryChan(t *testing.T) {
+	t.Parallel()
+
+	// collect drains the channel, and returns the deltas and the last event
+	var collect = func(t *testing.T, events <-chan ai.Event) (deltas []string, last ai.Event) {
+		t.Helper()
+
+		for e := range events {
+			if e.Kind == ai.EventDelta {
+				deltas = append(deltas, e.Text)
+
+				continue
+			}
+
+			last = e
+		}
+
+		return deltas, last
+	}
+
+	t.Run("done", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK, sseBody("feat: ", "Add foo", "\n\nThe body")), nil
+		}}
+
+		events, err := ai.QueryChan(context.Background(), ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)),
+			"diff", "log",
+		)
+		assertNoError(t, err)
+
+		deltas, last := collect(t, events)
+
+		assertEqual(t, strings.Join(deltas, "|"), "feat: |Add foo|\n\nThe body")
+		assertEqual(t, last.Kind, ai.EventDone)
+		assertEqual(t, last.Response.Answer, "feat: Add foo\n\nThe body")
+		assertEqual(t, last.Response.Usage.TotalTokens, 15)
+		assertEqual(t, strings.Contains(client.Requests()[0], `"stream":true`), true, "stream")
+	})
+
+	t.Run("without streaming support", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK, `{"candidates":[{"content":{"parts":[{"text":"feat: Add foo"}]}}]}`), nil
+		}}
+
+		events, err := ai.QueryChan(context.Background(), ai.NewGemini("", "", ai.WithGeminiHttpClient(client)),
+			"diff", "log",
+		)
+		assertNoError(t, err)
+
+		deltas, last := collect(t, events)
+
+		assertEqual(t, strings.Join(deltas, "|"), "feat: Add foo")
+		assertEqual(t, last.Kind, ai.EventDone)
+		assertEqual(t, last.Response.Answer, "feat: Add foo")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusUnauthorized, `{"error":{"message":"invalid key"}}`), nil
+		}}
+
+		events, err := ai.QueryChan(context.Background(), ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)),
+			"diff", "log",
+		)
+		assertNoError(t, err)
+
+		deltas, last := collect(t, events)
+
+		assertEqual(t, len(deltas), 0)
+		assertEqual(t, last.Kind, ai.EventError)
+		assertEqual(t, errors.Is(last.Err, ai.ErrUnauthorized), true, "unauthorized")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{}
+
+		events, err := ai.QueryChan(context.Background(), ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)),
+			"diff", "log", ai.WithTemperature(5),
+		)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		assertEqual(t, events == nil, true)
+		assertEqual(t, len(client.Requests()), 0)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		var client = &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+			return newHttpResponse(http.StatusOK, sseBody("feat: ", "Add foo", " and bar")), nil
+		}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		events, err := ai.QueryChan(ctx, ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)), "diff", "log")
+		assertNoError(t, err)
+
+		assertEqual(t, (<-events).Text, "feat: ")
+
+		cancel() // the rest of the events is not read
+
+		for range events {
+			// drain the rest until the channel is closed
+		}
+	})
+}
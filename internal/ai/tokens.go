@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens of the text. The package ships the cheap estimation (see [EstimateTokens]), and a
+// precise implementation (e.g. tiktoken or sentencepiece based) can be plugged in using [WithTokenizer]. All the
+// token budgets (the cost limit, the truncation of the diff) are computed using it.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// estimator is the default [Tokenizer] based on [EstimateTokens].
+type estimator struct{ model string }
+
+// Count implements the [Tokenizer] interface.
+func (e estimator) Count(text string) int { return EstimateTokens(e.model, text) }
+
+// truncateToTokens truncates the diff (at a line boundary) so that, including the note about the omitted part (see
+// [truncateChanges]), it has at most the given number of tokens. The tokenizer is called O(log n) times.
+func truncateToTokens(diff string, maxTokens int, tok Tokenizer) string {
+	if tok.Count(diff) <= maxTokens {
+		return diff
+	}
+
+	var offsets = []int{0} // the offsets of the line beginnings
+
+	for i := range len(diff) {
+		if diff[i] == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+
+	var lo, hi = 0, len(offsets) - 1 // the number of the kept lines; lo always fits (or nothing does)
+
+	for lo < hi {
+		var mid = (lo + hi + 1) / 2 //nolint:mnd
+
+		if tok.Count(truncateChanges(diff, offsets[mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return truncateChanges(diff, offsets[lo])
+}
+
+// EstimateTokens returns the approximate number of tokens in the text for the given model, without calling the
+// provider. It's cheap enough to be used to warn the user before an expensive call, or to fit the input into the
+// context window.
+//
+// For the OpenAI models, the text is split into pieces the same way the tiktoken (BPE) pre-tokenizer does it (words
+// with the leading space, digit groups, punctuation, and whitespace runs), and every piece is weighted by its length.
+// For the English text and source code, the estimate is usually within ±20% of the real count; for other languages
+// it's less accurate (mostly overestimated). For the other models, the common "4 characters per token" rule is
+// used, which is usually within ±30%.
+func EstimateTokens(model, text string) int {
+	if text == "" {
+		return 0
+	}
+
+	if isOpenAIModel(model) {
+		return estimateBPETokens(text)
+	}
+
+	return (utf8.RuneCountInString(text) + 3) / 4 //nolint:mnd // ceil(chars / 4)
+}
+
+// isOpenAIModel reports whether the model uses the tiktoken-like tokenizer.
+func isOpenAIModel(model string) bool {
+	model = strings.ToLower(model)
+
+	if i := strings.LastIndexByte(model, '/'); i >= 0 { // OpenRouter-style names, e.g. "openai/gpt-4o"
+		model = model[i+1:]
+	}
+
+	for _, prefix := range []string{"gpt-", "gpt4", "chatgpt", "o1", "o3", "o4", "text-embedding", "davinci"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// estimateBPETokens estimates the number of tokens using the tiktoken-like pre-tokenization.
+func estimateBPETokens(text string) int {
+	var (
+		runes  = []rune(text)
+		tokens int
+	)
+
+	for i := 0; i < len(runes); {
+		var n, t = nextPiece(runes[i:])
+
+		tokens += t
+		i += n
+	}
+
+	return tokens
+}
+
+// nextPiece returns the length (in runes) of the next pre-tokenized piece and the estimated number of its tokens.
+func nextPiece(runes []rune) (length, tokens int) {
+	var (
+		r      = runes[0]
+		isWord = func(r rune) bool { return unicode.IsLetter(r) && !isWideRune(r) }
+		span   = func(from int, fn func(rune) bool) int {
+			for from < len(runes) && fn(runes[from]) {
+				from++
+			}
+
+			return from
+		}
+	)
+
+	switch {
+	case isWideRune(r): // CJK and similar characters - roughly one token each
+		return 1, 1
+
+	case isWord(r), len(runes) > 1 && isWord(runes[1]) && !unicode.IsDigit(r) && r != '\n' && r != '\r':
+		// a word with the optional single leading character (e.g. " foo", ".Println", "/internal"); the common
+		// words are single tokens, the long ones are split
+		var from = 0
+
+		if !isWord(r) {
+			from = 1
+		}
+
+		length = span(from+1, isWord)
+
+		return length, 1 + (length-from-1)/8 //nolint:mnd
+
+	case unicode.IsDigit(r): // numbers are split into groups of up to 3 digits
+		length = span(0, unicode.IsDigit)
+
+		return length, (length + 2) / 3 //nolint:mnd
+
+	case unicode.IsSpace(r) && (len(runes) == 1 || unicode.IsSpace(runes[1])): // whitespace runs are merged
+		return span(0, unicode.IsSpace), 1
+	}
+
+	// punctuation and symbols with the optional leading space and trailing newlines; the common sequences are
+	// merged, non-ASCII symbols (e.g. emoji) take more tokens
+	length = span(1, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r) })
+
+	var ascii, other int
+
+	for _, s := range runes[:length] {
+		if s == ' ' {
+			continue
+		}
+
+		if s < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+
+	length = span(length, func(r rune) bool { return r == '\n' || r == '\r' })
+
+	return length, max(1, (ascii+1)/2+other*2) //nolint:mnd
+}
+
+// isWideRune reports whether the rune belongs to the CJK (and similar) scripts.
+func isWideRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai)
+}
@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+// lineTokenizer counts every line as a single token.
+type lineTokenizer struct{}
+
+func (lineTokenizer) Count(text string) int { return strings.Count(text, "\n") }
+
+func TestTruncateToTokens(t *testing.T) {
+	t.Parallel()
+
+	var diff = "+a\n+b\n+c\n+d\n+e\n"
+
+	for name, tc := range map[string]struct {
+		maxTokens int
+		want      string
+	}{
+		"fits":        {maxTokens: 5, want: diff},
+		"truncated":   {maxTokens: 3, want: "+a\n+b\n... (the diff is truncated, 9 bytes omitted)\n"},
+		"single line": {maxTokens: 1, want: "... (the diff is truncated, 15 bytes omitted)\n"},
+		"nothing fits": {
+			maxTokens: 0, want: "... (the diff is truncated, 15 bytes omitted)\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := truncateToTokens(diff, tc.maxTokens, lineTokenizer{}); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveModel, giveText string
+		want                int     // the real number of tokens
+		tolerance           float64 // allowed relative error
+	}{
+		"empty": {giveModel: "gpt-4o", giveText: "", want: 0},
+		"hello world": {
+			giveModel: "gpt-4o-mini", giveText: "hello world", want: 2, tolerance: 0.2,
+		},
+		"english sentence": {
+			giveModel: "gpt-4o", giveText: "The quick brown fox jumps over the lazy dog.", want: 10, tolerance: 0.2,
+		},
+		"digits": {
+			giveModel: "openai/gpt-4.1", giveText: "1234567890", want: 4, tolerance: 0.2,
+		},
+		"source code": {
+			giveModel: "gpt-4o",
+			giveText:  "func main() {\n\tfmt.Println(\"Hello, World!\")\n}",
+			want:      13,
+			tolerance: 0.2,
+		},
+		"long text": {
+			giveModel: "gpt-4o",
+			giveText:  strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100),
+			want:      1000,
+			tolerance: 0.2,
+		},
+		"fallback": {
+			giveModel: "gemini-2.0-flash", giveText: "abcdefghijklmnop", want: 4,
+		},
+		"fallback rounds up": {
+			giveModel: "claude-3-5-haiku", giveText: "abcde", want: 2,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				got  = ai.EstimateTokens(tc.giveModel, tc.giveText)
+				diff = math.Abs(float64(got-tc.want)) / math.Max(float64(tc.want), 1)
+			)
+
+			if diff > tc.tolerance {
+				t.Errorf("got %d, want %d (±%.0f%%)", got, tc.want, tc.tolerance*100)
+			}
+		})
+	}
+}
+
+// countingTokenizer counts every word as a single token, and records the number of calls.
+type countingTokenizer struct {
+	mu    sync.Mutex
+	calls int
+	mul   int // the multiplier of the count (0 = 1)
+}
+
+func (c *countingTokenizer) Count(text string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+
+	return len(strings.Fields(text)) * max(c.mul, 1)
+}
+
+func (c *countingTokenizer) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls
+}
+
+func TestWithTokenizer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("cost limit", func(t *testing.T) {
+		t.Parallel()
+
+		var tok = &countingTokenizer{mul: 1_000_000} // every word is "expensive"
+
+		_, err := ai.NewOpenAI("key", "gpt-4o", ai.WithOpenAIHttpClient(okClient("feat: Add foo"))).
+			Query(context.Background(), "diff", "log", ai.WithMaxCandidateCost(1), ai.WithTokenizer(tok))
+
+		if !errors.Is(err, ai.ErrCostLimitExceeded) {
+			t.Fatalf("expected %v, got %v", ai.ErrCostLimitExceeded, err)
+		}
+
+		if tok.Calls() == 0 {
+			t.Error("the tokenizer is not used")
+		}
+	})
+
+	t.Run("overflow truncation", func(t *testing.T) {
+		t.Parallel()
+
+		const overflow = `{"error":{"message":"too long","code":"context_length_exceeded"}}`
+
+		var (
+			tok    = &countingTokenizer{}
+			client = &fakeHttpClient{handler: func(_ *http.Request, n int) (*http.Response, error) {
+				if n == 0 {
+					return newHttpResponse(http.StatusBadRequest, overflow), nil
+				}
+
+				return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":"feat: Add foo"}}]}`), nil
+			}}
+			// long words: the byte-based truncation would keep many more lines than the token-based one
+			diff = "diff --git a/foo.go b/foo.go\n" + strings.Repeat("+a b c d e f g h\n", 100) +
+				strings.Repeat("+"+strings.Repeat("x", 100)+"\n", 100)
+		)
+
+		_, err := ai.NewOpenAI("key", "gpt-4o", ai.WithOpenAIHttpClient(client)).
+			Query(context.Background(), diff, "log", ai.WithTokenizer(tok))
+		assertNoError(t, err)
+
+		var requests = client.Requests()
+
+		assertEqual(t, len(requests), 2)
+
+		// 904 words in total, so about 450 are kept: ~55 short lines (8 words each), and no long ones
+		var second = openaiMessages(t, requests[1])[1]
+
+		if n := strings.Count(second, "+a b c"); n < 50 || n > 56 || strings.Contains(second, "xxx") {
+			t.Errorf("expected the diff to be truncated by tokens, got %d short lines:\n%s", n, second)
+		}
+	})
+}
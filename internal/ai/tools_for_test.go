@@ -0,0 +1,204 @@
+package ai_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+// fakeHttpClient is a fake HTTP client that records the requests and responds using the handler.
+type fakeHttpClient struct {
+	mu       sync.Mutex
+	handler  func(req *http.Request, n int) (*http.Response, error) // n is the zero-based request number
+	requests []string                                               // recorded request bodies
+}
+
+// Do implements the HTTP client interface.
+func (c *fakeHttpClient) Do(req *http.Request) (*http.Response, error) {
+	var body string
+
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		body = string(b)
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, body)
+	var n = len(c.requests) - 1
+	c.mu.Unlock()
+
+	return c.handler(req, n)
+}
+
+// Requests returns the recorded request bodies.
+func (c *fakeHttpClient) Requests() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.requests...)
+}
+
+// newHttpResponse creates a new HTTP response with the given status code and body.
+func newHttpResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Status:     http.StatusText(code),
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// assertNoError fails the test if err is not nil, indicating an unexpected error occurred.
+func assertNoError(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// assertEqual checks if two values of a comparable type are equal.
+func assertEqual[T comparable](t *testing.T, got, want T, msgPrefix ...string) {
+	t.Helper()
+
+	var p string
+
+	if len(msgPrefix) > 0 {
+		p = msgPrefix[0] + ": "
+	}
+
+	if got != want {
+		t.Errorf("%sgot %v, want %v", p, got, want)
+	}
+}
+
+// newGitRepo initializes a new git repository in a temporary directory, writes the files and stages them.
+func newGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	var dir = t.TempDir()
+
+	runGit(t, dir, "init", "--quiet")
+	writeFiles(t, dir, files)
+
+	return dir
+}
+
+// writeFiles writes the files (paths are relative to the dir) and stages them.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		var path = filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runGit(t, dir, "add", "-A")
+}
+
+// runGit runs git with the given arguments in the specified directory and returns its output.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	var cmd = exec.Command("git", append([]string{
+		"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false",
+	}, args...)...)
+
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, out)
+	}
+
+	return string(out)
+}
+
+// openaiMessages decodes the contents of the messages from the OpenAI-compatible request body.
+func openaiMessages(t *testing.T, body string) []string {
+	t.Helper()
+
+	var req struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+
+	var contents = make([]string, 0, len(req.Messages))
+
+	for _, m := range req.Messages {
+		contents = append(contents, m.Content)
+	}
+
+	return contents
+}
+
+// okClient returns the fake HTTP client that always responds with the given OpenAI-compatible answer.
+func okClient(answer string) *fakeHttpClient {
+	return &fakeHttpClient{handler: func(*http.Request, int) (*http.Response, error) {
+		j, _ := json.Marshal(answer)
+
+		return newHttpResponse(http.StatusOK, `{"choices":[{"message":{"content":`+string(j)+`}}]}`), nil
+	}}
+}
+
+// allProviders returns the constructors of all the providers, using the given HTTP client.
+func allProviders() map[string]func(*fakeHttpClient) ai.Provider {
+	return map[string]func(*fakeHttpClient) ai.Provider{
+		"gemini": func(c *fakeHttpClient) ai.Provider { return ai.NewGemini("", "", ai.WithGeminiHttpClient(c)) },
+		"openai": func(c *fakeHttpClient) ai.Provider { return ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(c)) },
+		"openrouter": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewOpenRouter("", "", ai.WithOpenRouterHttpClient(c))
+		},
+		"huggingface": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewHuggingFace("", "", ai.WithHuggingFaceHttpClient(c))
+		},
+		"anthropic": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewAnthropic("", "", ai.WithAnthropicHttpClient(c))
+		},
+		"ollama": func(c *fakeHttpClient) ai.Provider { return ai.NewOllama("", "", ai.WithOllamaHttpClient(c)) },
+		"mistral": func(c *fakeHttpClient) ai.Provider {
+			return ai.NewMistral("", "", ai.WithMistralHttpClient(c))
+		},
+	}
+}
+
+// providerFunc is a function that implements the [ai.Provider] interface.
+type providerFunc func(ctx context.Context, changes, commits string, opts ...ai.Option) (*ai.Response, error)
+
+// Query implements the [ai.Provider] interface.
+func (f providerFunc) Query(ctx context.Context, changes, commits string, opts ...ai.Option) (*ai.Response, error) {
+	return f(ctx, changes, commits, opts...)
+}
+
+// Name implements the [ai.Provider] interface.
+func (providerFunc) Name() string { return "func" }
@@ -0,0 +1,50 @@
+package ai
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultTrailerFormat is the format of the model trailer used by default (see [WithModelTrailer]).
+const defaultTrailerFormat = "Generated-by: describe-commit (model: {model})"
+
+// trailerRegex matches the trailer lines (e.g. `Signed-off-by: John <john@example.com>`, `BREAKING CHANGE: ...`, or
+// `Refs #123`).
+var trailerRegex = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*|BREAKING CHANGE)(: | #)\S`)
+
+// withModelTrailer appends the model trailer to the message. The trailer is added to the existing trailers block
+// (the last paragraph of the body consisting of the trailers only), or as a separate paragraph otherwise. The
+// message already containing the trailer is returned as is.
+func withModelTrailer(message, model, format string) string {
+	if format == "" {
+		format = defaultTrailerFormat
+	}
+
+	var trailer = strings.ReplaceAll(format, "{model}", model)
+
+	message = strings.TrimRight(message, "\n")
+
+	var lines = strings.Split(message, "\n")
+
+	if slices.Contains(lines, trailer) {
+		return message
+	}
+
+	var last = len(lines) // the beginning of the last paragraph
+
+	for last > 0 && strings.TrimSpace(lines[last-1]) != "" {
+		last--
+	}
+
+	if last > 0 && isTrailers(lines[last:]) {
+		return message + "\n" + trailer // the last paragraph (not the subject) is the trailers block
+	}
+
+	return message + "\n\n" + trailer
+}
+
+// isTrailers reports whether the paragraph lines are all trailers (e.g. the trailers block at the end of the body).
+func isTrailers(lines []string) bool {
+	return len(lines) > 0 && !slices.ContainsFunc(lines, func(l string) bool { return !trailerRegex.MatchString(l) })
+}
@@ -0,0 +1,70 @@
+package ai_test
+
+import (
+	"context"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_ModelTrailer(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		giveOpts   []ai.Option
+		want       string
+	}{
+		"subject only": {
+			giveAnswer: "feat: Add foo",
+			want:       "feat: Add foo\n\nGenerated-by: describe-commit (model: gpt-4o-mini)",
+		},
+		"with body": {
+			giveAnswer: "feat: Add foo\n\n- Add bar\n- Add baz\n",
+			want:       "feat: Add foo\n\n- Add bar\n- Add baz\n\nGenerated-by: describe-commit (model: gpt-4o-mini)",
+		},
+		"after other trailers": {
+			giveAnswer: "feat: Add foo\n\n- Add bar\n\nRefs #123\nSigned-off-by: John Doe <john@example.com>",
+			want: "feat: Add foo\n\n- Add bar\n\nRefs #123\nSigned-off-by: John Doe <john@example.com>\n" +
+				"Generated-by: describe-commit (model: gpt-4o-mini)",
+		},
+		"not a trailers block": {
+			giveAnswer: "feat: Add foo\n\nRefs #123\nThe bar is added too",
+			want:       "feat: Add foo\n\nRefs #123\nThe bar is added too\n\nGenerated-by: describe-commit (model: gpt-4o-mini)",
+		},
+		"custom format": {
+			giveAnswer: "fix: Fix foo",
+			giveOpts:   []ai.Option{ai.WithTrailerFormat("Assisted-by: {model}")},
+			want:       "fix: Fix foo\n\nAssisted-by: gpt-4o-mini",
+		},
+		"already present": {
+			giveAnswer: "fix: Fix foo\n\nGenerated-by: describe-commit (model: gpt-4o-mini)",
+			want:       "fix: Fix foo\n\nGenerated-by: describe-commit (model: gpt-4o-mini)",
+		},
+		"short message only": {
+			giveAnswer: "fix: Fix foo\n\n- Fix bar",
+			giveOpts:   []ai.Option{ai.WithShortMessageOnly(true)},
+			want:       "fix: Fix foo",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := ai.NewOpenAI("", "gpt-4o-mini", ai.WithOpenAIHttpClient(okClient(tc.giveAnswer))).
+				Query(context.Background(), "diff", "log", append(tc.giveOpts, ai.WithModelTrailer(true))...)
+			assertNoError(t, err)
+
+			assertEqual(t, resp.Answer, tc.want)
+		})
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := ai.NewOpenAI("", "gpt-4o-mini", ai.WithOpenAIHttpClient(okClient("feat: Add foo"))).
+			Query(context.Background(), "diff", "log")
+		assertNoError(t, err)
+
+		assertEqual(t, resp.Answer, "feat: Add foo")
+	})
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Translate translates the existing commit message to the target language (e.g. "German" or "ja"). Only the human
+// text is translated: the conventional commit type, scope, and breaking change mark, the code identifiers (in
+// backticks), the file paths, and the trailer tokens are kept intact. The options affecting the message format (e.g.
+// [WithShortMessageOnly] or [WithCandidates]) and the request delivery (e.g. [WithRetries]) are respected, while the
+// rest of them (e.g. the ones inspecting the changes) are ignored.
+func Translate(ctx context.Context, p Provider, message, targetLang string, opts ...Option) (*Response, error) {
+	if message = strings.TrimSpace(message); message == "" {
+		return nil, errors.New("nothing to translate: the message is empty")
+	}
+
+	if targetLang = strings.TrimSpace(targetLang); targetLang == "" {
+		return nil, errors.New("the target language is not set")
+	}
+
+	return p.Query(ctx, message, "", append(opts[:len(opts):len(opts)], withTranslation(targetLang))...)
+}
+
+// withTranslation switches the query to the translation of the message passed as the changes. The options are
+// rebuilt from the allowlist: only the ones affecting the message format and the delivery of the request are kept,
+// so the features that inspect (or alter) the changes never apply to the message, including the future ones.
+func withTranslation(targetLang string) Option {
+	return func(o *options) {
+		*o = options{
+			TranslateTo:    targetLang,
+			KeepIndexLines: true, // the message is passed to the model as is
+			MaxLineLength:  -1,
+
+			// the message format
+			ShortMessageOnly: o.ShortMessageOnly,
+			EnableEmoji:      o.EnableEmoji,
+			ASCIIOnly:        o.ASCIIOnly,
+			MaxOutputTokens:  o.MaxOutputTokens,
+			Candidates:       o.Candidates,
+			UniqueCandidates: o.UniqueCandidates,
+			Temperature:      o.Temperature,
+			TopP:             o.TopP,
+			StopSequences:    o.StopSequences,
+			PostProcess:      o.PostProcess,
+
+			// the request delivery
+			UserAgent:        o.UserAgent,
+			Retries:          o.Retries,
+			RetryBase:        o.RetryBase,
+			TotalBudget:      o.TotalBudget,
+			SkipDecodeRetry:  o.SkipDecodeRetry,
+			OverflowModel:    o.OverflowModel,
+			MaxCandidateCost: o.MaxCandidateCost,
+			Tokenizer:        o.Tokenizer,
+			Stream:           o.Stream,
+			StreamFallback:   o.StreamFallback,
+			StreamGrace:      o.StreamGrace,
+			AuditLog:         o.AuditLog,
+			AuditLogBody:     o.AuditLogBody,
+			DryRun:           o.DryRun,
+		}
+	}
+}
+
+// generateTranslatePrompt generates the system prompt for the translation of the commit message.
+func generateTranslatePrompt(opt options) string {
+	var b strings.Builder
+
+	b.WriteString("## Role\n")
+	b.WriteString("You are an AI assistant translating Git commit messages.\n\n")
+
+	b.WriteString("## Task\n")
+	b.WriteString(fmt.Sprintf("Translate the provided commit message to %s.\n\n", opt.TranslateTo))
+
+	b.WriteString("## Input\n")
+	b.WriteString(fmt.Sprintf("The commit message is wrapped between `%s` and `%s`.\n\n", messageBegin, messageEnd))
+
+	b.WriteString("## Output\n")
+	b.WriteString("Produce only the translated commit message in plain text without wrapping it in backticks, ")
+	b.WriteString("quotes, or code blocks, and without any explanations.\n\n")
+
+	b.WriteString("## Guidelines\n")
+	b.WriteString("- Translate only the human text. Keep the structure of the message: the subject line, the blank ")
+	b.WriteString("lines, the paragraphs, and the bullet points.\n")
+	b.WriteString("- Keep the Conventional Commit header tokens intact: the type (e.g. `feat`, `fix`), the scope in ")
+	b.WriteString("parentheses, the `!` mark, and the colon (e.g. `feat(api)!: `) are never translated; translate only ")
+	b.WriteString("the description after the colon.\n")
+	b.WriteString("- Keep the code identifiers, the text in backticks, the file paths, the commands, the URLs, and ")
+	b.WriteString("the issue references (e.g. `#123`) exactly as they are.\n")
+	b.WriteString("- Keep the trailer tokens (e.g. `BREAKING CHANGE:`, `Signed-off-by:`, `Refs:`) and their values ")
+	b.WriteString("(names, emails) as they are; only the human text of the `BREAKING CHANGE` description is ")
+	b.WriteString("translated.\n")
+	b.WriteString("- Keep the emojis, and the imperative mood of the subject (in the target language grammar).\n")
+	b.WriteString("- If the message is already in the target language, output it unchanged.\n")
+
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package ai_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestTranslate(t *testing.T) {
+	t.Parallel()
+
+	const message = "feat(api)!: Add the `RateLimiter` middleware\n\n- Limit the requests per client\n\nRefs #42"
+
+	var client = okClient("feat(api)!: Füge die `RateLimiter`-Middleware hinzu\n\n- Begrenze die Anfragen pro Client")
+
+	resp, err := ai.Translate(context.Background(), ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)),
+		message, "German",
+		ai.WithAdditionsOnly(true),   // must not strip the bullet points of the message
+		ai.WithPlanThenWrite(true),   // the message is not planned
+		ai.WithFixupTarget("abc123"), // the fixup message is not emitted
+		ai.WithContextLabels("backend"),
+		ai.WithModelTrailer(true),
+	)
+	assertNoError(t, err)
+
+	assertEqual(t, resp.Answer, "feat(api)!: Füge die `RateLimiter`-Middleware hinzu\n\n- Begrenze die Anfragen pro Client")
+
+	var requests = client.Requests()
+
+	assertEqual(t, len(requests), 1)
+
+	var messages = openaiMessages(t, requests[0])
+
+	assertEqual(t, len(messages), 2)
+
+	var prompt = messages[0]
+
+	for _, want := range []string{
+		"Translate the provided commit message to German",
+		"Keep the Conventional Commit header tokens intact: the type (e.g. `feat`, `fix`), the scope",
+		"translate only the description after the colon",
+		"Keep the code identifiers, the text in backticks",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected the prompt to contain %q", want)
+		}
+	}
+
+	assertEqual(t, strings.Contains(prompt, "git diff"), false, "no diff instructions")
+	assertEqual(t, strings.Contains(prompt, "backend"), false, "no context labels")
+	assertEqual(t, messages[1], "[---COMMIT-MESSAGE-BEGIN---]\n"+message+"\n[---COMMIT-MESSAGE-END---]")
+	assertEqual(t, resp.Prompt, prompt)
+}
+
+func TestTranslate_InvalidInput(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct{ giveMessage, giveLang string }{
+		"empty message":  {giveMessage: " \n", giveLang: "German"},
+		"empty language": {giveMessage: "feat: Add foo", giveLang: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var client = okClient("feat: Add foo")
+
+			_, err := ai.Translate(context.Background(), ai.NewOpenAI("", "", ai.WithOpenAIHttpClient(client)),
+				tc.giveMessage, tc.giveLang,
+			)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			assertEqual(t, len(client.Requests()), 0)
+		})
+	}
+}
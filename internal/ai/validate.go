@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// sourceCodeExtensions are the extensions of the files considered to be the source code.
+var sourceCodeExtensions = [...]string{ //nolint:gochecknoglobals
+	".go", ".py", ".js", ".jsx", ".ts", ".tsx", ".java", ".kt", ".rb", ".rs", ".c", ".h", ".cc", ".cpp", ".hpp",
+	".cs", ".php", ".swift", ".scala", ".vue", ".svelte", ".dart", ".ex", ".exs", ".lua", ".m", ".sh",
+}
+
+// maxDescriptionLength is the maximum length (in characters) of the commit header description.
+const maxDescriptionLength = 72
+
+// conventionalTypes are the commit types allowed by [Response.Validate].
+var conventionalTypes = [...]string{ //nolint:gochecknoglobals
+	"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert",
+}
+
+// Validate checks that the answer follows the Conventional Commits format the model is asked for: the header is
+// `[<emoji> ]<type>[(<scope>)][!]: <description>` with a known type, the description is not longer than 72
+// characters (and the words limit, see [WithMaxSubjectWords]) and has no period at the end, and the body (if any)
+// is separated by a blank line. The returned error wraps the [ErrInvalidMessage].
+func (r *Response) Validate() error {
+	var subject, rest, hasBody = strings.Cut(strings.TrimSpace(r.Answer), "\n")
+
+	header, ok := ParseHeader(subject)
+	if !ok {
+		return fmt.Errorf("%w: the header does not follow the `<type>(<scope>): <description>` format", ErrInvalidMessage)
+	}
+
+	if !slices.Contains(conventionalTypes[:], header.Type) {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidMessage, header.Type)
+	}
+
+	if n := len([]rune(header.Description)); n > maxDescriptionLength {
+		return fmt.Errorf("%w: the description is too long (%d > %d characters)",
+			ErrInvalidMessage, n, maxDescriptionLength,
+		)
+	}
+
+	if n := len(strings.Fields(header.Description)); r.maxSubjectWords > 0 && n > r.maxSubjectWords {
+		return fmt.Errorf("%w: the description is too long (%d > %d words)", ErrInvalidMessage, n, r.maxSubjectWords)
+	}
+
+	if strings.HasSuffix(header.Description, ".") {
+		return fmt.Errorf("%w: the description ends with a period", ErrInvalidMessage)
+	}
+
+	if hasBody && !strings.HasPrefix(rest, "\n") {
+		return fmt.Errorf("%w: the body is not separated from the header by a blank line", ErrInvalidMessage)
+	}
+
+	return nil
+}
+
+// validateAnswer checks the generated commit message and returns the warnings (non-fatal issues) found.
+func validateAnswer(answer, changes string, o options) []string {
+	if o.OutputFormat != FormatCommitMessage {
+		return nil
+	}
+
+	var warnings []string
+
+	if o.DiscourageChore {
+		var subject, _, _ = strings.Cut(answer, "\n")
+
+		if header, ok := ParseHeader(subject); ok && header.Type == "chore" {
+			if touchesSourceCode(git.ChangedFiles(changes)) {
+				warnings = append(warnings, "the generic `chore` type is used for changes that touch the source code")
+			}
+		}
+	}
+
+	return warnings
+}
+
+// touchesSourceCode reports whether any of the files is a source code file.
+func touchesSourceCode(files []string) bool { return slices.ContainsFunc(files, isSourceFile) }
+
+// isSourceFile reports whether the file contains the code (see [sourceCodeExtensions]), as opposed to the
+// documentation, the configuration, or the assets.
+func isSourceFile(file string) bool {
+	return slices.Contains(sourceCodeExtensions[:], strings.ToLower(path.Ext(file)))
+}
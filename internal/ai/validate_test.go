@@ -0,0 +1,137 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+func TestQuery_DiscourageChoreWarning(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sourceDiff = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n"
+		configDiff = "diff --git a/.editorconfig b/.editorconfig\n--- a/.editorconfig\n+++ b/.editorconfig\n" +
+			"@@ -1 +1 @@\n-a\n+b\n"
+	)
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		giveDiff   string
+		giveOpts   []ai.Option
+		wantCount  int
+	}{
+		"chore for the source code": {
+			giveAnswer: "chore(app): Update main", giveDiff: sourceDiff,
+			giveOpts:  []ai.Option{ai.WithDiscourageChore(true)},
+			wantCount: 1,
+		},
+		"chore for the config": {
+			giveAnswer: "chore: Update editorconfig", giveDiff: configDiff,
+			giveOpts: []ai.Option{ai.WithDiscourageChore(true)},
+		},
+		"specific type": {
+			giveAnswer: "fix(app): Handle the empty input", giveDiff: sourceDiff,
+			giveOpts: []ai.Option{ai.WithDiscourageChore(true)},
+		},
+		"disabled": {
+			giveAnswer: "chore(app): Update main", giveDiff: sourceDiff,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(okClient(tc.giveAnswer))).
+				Query(context.Background(), tc.giveDiff, "log", tc.giveOpts...)
+			assertNoError(t, err)
+
+			if len(resp.Warnings) != tc.wantCount {
+				t.Errorf("want %d warning(s), got %q", tc.wantCount, resp.Warnings)
+			}
+		})
+	}
+}
+
+func TestResponse_Validate(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		wantErr    bool
+	}{
+		"subject only":         {giveAnswer: "feat(api): Add rate-limiting to endpoints"},
+		"with body":            {giveAnswer: "fix: Handle the empty input\n\nThe input was not checked"},
+		"with emoji":           {giveAnswer: "✨ feat: Add foo"},
+		"breaking":             {giveAnswer: "refactor(core)!: Drop the legacy API"},
+		"not conventional":     {giveAnswer: "Added foo", wantErr: true},
+		"unknown type":         {giveAnswer: "feature: Add foo", wantErr: true},
+		"too long":             {giveAnswer: "feat: " + strings.Repeat("a", 73), wantErr: true},
+		"period at the end":    {giveAnswer: "feat: Add foo.", wantErr: true},
+		"no blank line":        {giveAnswer: "feat: Add foo\nThe body", wantErr: true},
+		"empty":                {giveAnswer: "", wantErr: true},
+		"max length":           {giveAnswer: "feat: " + strings.Repeat("ы", 72)},
+		"trailing whitespaces": {giveAnswer: "\nfeat: Add foo\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = (&ai.Response{Answer: tc.giveAnswer}).Validate()
+
+			if tc.wantErr {
+				if !errors.Is(err, ai.ErrInvalidMessage) {
+					t.Fatalf("expected %v, got %v", ai.ErrInvalidMessage, err)
+				}
+
+				return
+			}
+
+			assertNoError(t, err)
+		})
+	}
+}
+
+func TestResponse_ValidateMaxSubjectWords(t *testing.T) {
+	t.Parallel()
+
+	var query = func(t *testing.T, answer string, opts ...ai.Option) *ai.Response {
+		t.Helper()
+
+		resp, err := ai.NewOpenAI("key", "model", ai.WithOpenAIHttpClient(okClient(answer))).
+			Query(context.Background(), "diff", "log", opts...)
+		assertNoError(t, err)
+
+		return resp
+	}
+
+	for name, tc := range map[string]struct {
+		giveAnswer string
+		giveMax    int
+		wantErr    bool
+	}{
+		"within limit":   {giveAnswer: "feat(api): Add rate-limiting", giveMax: 3},
+		"exactly":        {giveAnswer: "feat(api): Add the rate-limiting", giveMax: 3},
+		"too many words": {giveAnswer: "feat(api): Add the rate-limiting to endpoints", giveMax: 3, wantErr: true},
+		"scope ignored":  {giveAnswer: "✨ feat(some api)!: Add rate-limiting", giveMax: 2},
+		"extra spaces":   {giveAnswer: "fix:  Fix   foo\n\nthe body is not counted at all", giveMax: 2},
+		"no limit":       {giveAnswer: "feat: Add the rate-limiting to all the endpoints"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = query(t, tc.giveAnswer, ai.WithMaxSubjectWords(tc.giveMax)).Validate()
+
+			if tc.wantErr {
+				if !errors.Is(err, ai.ErrInvalidMessage) || !strings.Contains(err.Error(), "words") {
+					t.Fatalf("expected the words limit error, got %v", err)
+				}
+
+				return
+			}
+
+			assertNoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+type (
+	// WeightedProvider routes every query to one of the underlying providers, chosen randomly according to their
+	// weights. It can be used to spread the load (and cost) across several API keys or providers.
+	//
+	// Every failure of a provider halves its weight (down to 1/1024 of the configured one) for a while, so a failing
+	// provider gets less traffic. The weight is restored on the first successful query, or when the penalty expires.
+	WeightedProvider struct {
+		mu       sync.Mutex
+		backends []weightedBackend
+	}
+
+	// Weighted is a provider with its weight (the share of the queries relative to the other providers).
+	Weighted struct {
+		Provider Provider
+		Weight   uint
+	}
+
+	weightedBackend struct {
+		Weighted
+
+		failures int       // number of consecutive failures
+		failedAt time.Time // the time of the last failure
+	}
+)
+
+var _ Provider = (*WeightedProvider)(nil) // ensure the interface is implemented
+
+const (
+	weightedPenaltyTTL = time.Minute // how long the failure penalty is applied
+	weightedMaxPenalty = 10          // the max number of weight halvings (1/1024)
+)
+
+// NewWeightedProvider creates a new [WeightedProvider]. Providers with zero weight (or nil ones) are ignored.
+func NewWeightedProvider(providers ...Weighted) *WeightedProvider {
+	var w WeightedProvider
+
+	for _, p := range providers {
+		if p.Provider != nil && p.Weight > 0 {
+			w.backends = append(w.backends, weightedBackend{Weighted: p})
+		}
+	}
+
+	return &w
+}
+
+// Query sends the query to one of the providers.
+func (w *WeightedProvider) Query(ctx context.Context, changes, commits string, opts ...Option) (*Response, error) {
+	var idx = w.pick(time.Now())
+	if idx < 0 {
+		return nil, errors.New("no providers configured")
+	}
+
+	resp, err := w.backends[idx].Provider.Query(ctx, changes, commits, opts...)
+
+	w.report(idx, err, ctx.Err() != nil, time.Now())
+
+	return resp, err
+}
+
+// Name implements the [Provider] interface. The name of the provider that served the query is reported by the
+// [Response.Provider].
+func (*WeightedProvider) Name() string { return "weighted" }
+
+// pick chooses the provider index randomly, using the effective (penalized) weights. Returns -1 if there are no
+// providers.
+func (w *WeightedProvider) pick(now time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.backends) == 0 {
+		return -1
+	}
+
+	var (
+		weights = make([]float64, len(w.backends))
+		total   float64
+	)
+
+	for i, b := range w.backends {
+		weights[i] = float64(b.Weight)
+
+		if b.failures > 0 && now.Sub(b.failedAt) < weightedPenaltyTTL {
+			weights[i] /= math.Exp2(float64(min(b.failures, weightedMaxPenalty)))
+		}
+
+		total += weights[i]
+	}
+
+	var r = rand.Float64() * total //nolint:gosec // no need for the cryptographically secure randomness
+
+	for i, weight := range weights {
+		if r < weight {
+			return i
+		}
+
+		r -= weight
+	}
+
+	return len(weights) - 1 // floating point rounding
+}
+
+// report updates the failure tracking of the provider. Canceled queries are not counted as failures.
+func (w *WeightedProvider) report(idx int, err error, canceled bool, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var b = &w.backends[idx]
+
+	switch {
+	case err == nil:
+		b.failures = 0
+	case !canceled:
+		if now.Sub(b.failedAt) >= weightedPenaltyTTL {
+			b.failures = 0 // the previous penalty has expired
+		}
+
+		b.failures, b.failedAt = b.failures+1, now
+	}
+}
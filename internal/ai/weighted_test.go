@@ -0,0 +1,89 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/ai"
+)
+
+// countingProvider returns the provider that counts the queries and responds with the given error (if any).
+func countingProvider(counter *atomic.Int64, err error) ai.Provider {
+	return providerFunc(func(context.Context, string, string, ...ai.Option) (*ai.Response, error) {
+		counter.Add(1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &ai.Response{Answer: "feat: Add foo"}, nil
+	})
+}
+
+func TestWeightedProvider_Distribution(t *testing.T) {
+	t.Parallel()
+
+	const total = 10_000
+
+	var one, two, three atomic.Int64
+
+	var w = ai.NewWeightedProvider(
+		ai.Weighted{Provider: countingProvider(&one, nil), Weight: 1},
+		ai.Weighted{Provider: countingProvider(&two, nil), Weight: 3},
+		ai.Weighted{Provider: countingProvider(&three, nil), Weight: 0}, // ignored
+		ai.Weighted{Provider: nil, Weight: 10},                          // ignored
+	)
+
+	for range total {
+		_, err := w.Query(context.Background(), "diff", "log")
+		assertNoError(t, err)
+	}
+
+	for _, tc := range []struct {
+		got  int64
+		want float64
+	}{
+		{one.Load(), 0.25},
+		{two.Load(), 0.75},
+		{three.Load(), 0},
+	} {
+		if share := float64(tc.got) / total; math.Abs(share-tc.want) > 0.03 {
+			t.Errorf("want the share of ~%.2f, got %.3f", tc.want, share)
+		}
+	}
+}
+
+func TestWeightedProvider_FailingProviderGetsLessTraffic(t *testing.T) {
+	t.Parallel()
+
+	const total = 2_000
+
+	var healthy, failing atomic.Int64
+
+	var w = ai.NewWeightedProvider(
+		ai.Weighted{Provider: countingProvider(&healthy, nil), Weight: 1},
+		ai.Weighted{Provider: countingProvider(&failing, errors.New("boom")), Weight: 1},
+	)
+
+	for range total {
+		_, _ = w.Query(context.Background(), "diff", "log")
+	}
+
+	// without the penalty, the failing provider would get ~50% of the queries
+	if got := failing.Load(); got > total/10 {
+		t.Errorf("the failing provider got too many queries: %d of %d", got, total)
+	}
+
+	assertEqual(t, healthy.Load()+failing.Load(), int64(total))
+}
+
+func TestWeightedProvider_NoProviders(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ai.NewWeightedProvider().Query(context.Background(), "diff", "log"); err == nil {
+		t.Error("expected an error")
+	}
+}
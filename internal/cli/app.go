@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gh.tarampamp.am/describe-commit/internal/ai"
 	"gh.tarampamp.am/describe-commit/internal/cli/cmd"
@@ -73,6 +74,36 @@ func NewApp(name string) *App { //nolint:funlen
 			},
 			Default: app.opt.MaxOutputTokens,
 		}
+		stashIndex = cmd.Flag[int64]{
+			Names:   []string{"stash"},
+			Usage:   "Describe the stash entry with the given index (stash@{N}) instead of the staged changes",
+			EnvVars: []string{"STASH_INDEX"},
+			Validator: func(_ *cmd.Command, i int64) error {
+				if i < 0 {
+					return errors.New("stash index must not be negative")
+				}
+
+				return nil
+			},
+		}
+		includeUnstaged = cmd.Flag[bool]{
+			Names:   []string{"include-unstaged"},
+			Usage:   "Include the unstaged changes (in addition to the staged ones)",
+			EnvVars: []string{"INCLUDE_UNSTAGED"},
+			Default: app.opt.IncludeUnstaged,
+		}
+		includeUntracked = cmd.Flag[bool]{
+			Names:   []string{"include-untracked"},
+			Usage:   "Include the untracked files (in addition to the staged changes)",
+			EnvVars: []string{"INCLUDE_UNTRACKED"},
+			Default: app.opt.IncludeUntracked,
+		}
+		noRenames = cmd.Flag[bool]{
+			Names:   []string{"no-renames"},
+			Usage:   "Disable the rename and copy detection (the moved files are shown as deleted and added)",
+			EnvVars: []string{"NO_RENAMES"},
+			Default: app.opt.NoRenames,
+		}
 		aiProviderName = cmd.Flag[string]{
 			Names:   []string{"ai-provider", "ai"},
 			Usage:   fmt.Sprintf("AI provider name (%s)", strings.Join(ai.SupportedProviders(), "|")),
@@ -110,6 +141,12 @@ func NewApp(name string) *App { //nolint:funlen
 			EnvVars: []string{"OPENAI_MODEL_NAME"},
 			Default: app.opt.Providers.OpenAI.ModelName,
 		}
+		openAIBaseURL = cmd.Flag[string]{
+			Names:   []string{"openai-base-url", "ou"},
+			Usage:   "OpenAI API base URL (change it to use any OpenAI-compatible gateway)",
+			EnvVars: []string{"OPENAI_BASE_URL"},
+			Default: app.opt.Providers.OpenAI.BaseURL,
+		}
 		openRouterApiKey = cmd.Flag[string]{
 			Names:   []string{"openrouter-api-key", "ora"},
 			Usage:   "OpenRouter API key (https://bit.ly/4hU1yY1)",
@@ -122,6 +159,60 @@ func NewApp(name string) *App { //nolint:funlen
 			EnvVars: []string{"OPENROUTER_MODEL_NAME"},
 			Default: app.opt.Providers.OpenRouter.ModelName,
 		}
+		huggingFaceApiKey = cmd.Flag[string]{
+			Names:   []string{"huggingface-api-key", "hfa"},
+			Usage:   "Hugging Face access token (https://huggingface.co/settings/tokens)",
+			EnvVars: []string{"HUGGINGFACE_API_KEY", "HF_TOKEN"},
+			Default: app.opt.Providers.HuggingFace.ApiKey,
+		}
+		huggingFaceModelName = cmd.Flag[string]{
+			Names:   []string{"huggingface-model-name", "hfm"},
+			Usage:   "Hugging Face model name (https://huggingface.co/models?inference_provider=all)",
+			EnvVars: []string{"HUGGINGFACE_MODEL_NAME"},
+			Default: app.opt.Providers.HuggingFace.ModelName,
+		}
+		anthropicApiKey = cmd.Flag[string]{
+			Names:   []string{"anthropic-api-key", "aa"},
+			Usage:   "Anthropic API key (https://console.anthropic.com/settings/keys)",
+			EnvVars: []string{"ANTHROPIC_API_KEY"},
+			Default: app.opt.Providers.Anthropic.ApiKey,
+		}
+		anthropicModelName = cmd.Flag[string]{
+			Names:   []string{"anthropic-model-name", "am"},
+			Usage:   "Anthropic model name (https://docs.anthropic.com/en/docs/about-claude/models)",
+			EnvVars: []string{"ANTHROPIC_MODEL_NAME"},
+			Default: app.opt.Providers.Anthropic.ModelName,
+		}
+		mistralApiKey = cmd.Flag[string]{
+			Names:   []string{"mistral-api-key", "ma"},
+			Usage:   "Mistral AI API key (https://console.mistral.ai/api-keys)",
+			EnvVars: []string{"MISTRAL_API_KEY"},
+			Default: app.opt.Providers.Mistral.ApiKey,
+		}
+		mistralModelName = cmd.Flag[string]{
+			Names:   []string{"mistral-model-name", "mm"},
+			Usage:   "Mistral AI model name (https://docs.mistral.ai/getting-started/models/)",
+			EnvVars: []string{"MISTRAL_MODEL_NAME"},
+			Default: app.opt.Providers.Mistral.ModelName,
+		}
+		ollamaBaseURL = cmd.Flag[string]{
+			Names:   []string{"ollama-url", "olu"},
+			Usage:   "Ollama server URL (https://ollama.com)",
+			EnvVars: []string{"OLLAMA_URL"},
+			Default: app.opt.Providers.Ollama.BaseURL,
+		}
+		ollamaModelName = cmd.Flag[string]{
+			Names:   []string{"ollama-model-name", "olm"},
+			Usage:   "Ollama model name (https://ollama.com/search)",
+			EnvVars: []string{"OLLAMA_MODEL_NAME"},
+			Default: app.opt.Providers.Ollama.ModelName,
+		}
+		ollamaTimeout = cmd.Flag[time.Duration]{
+			Names:   []string{"ollama-timeout", "olt"},
+			Usage:   "Ollama request timeout (the local models can be slow)",
+			EnvVars: []string{"OLLAMA_TIMEOUT"},
+			Default: app.opt.Providers.Ollama.Timeout,
+		}
 	)
 
 	app.cmd.Flags = []cmd.Flagger{
@@ -130,13 +221,27 @@ func NewApp(name string) *App { //nolint:funlen
 		&commitHistoryLength,
 		&enableEmoji,
 		&maxOutputTokens,
+		&stashIndex,
+		&includeUnstaged,
+		&includeUntracked,
+		&noRenames,
 		&aiProviderName,
 		&geminiApiKey,
 		&geminiModelName,
 		&openAIApiKey,
 		&openAIModelName,
+		&openAIBaseURL,
 		&openRouterApiKey,
 		&openRouterModelName,
+		&huggingFaceApiKey,
+		&huggingFaceModelName,
+		&anthropicApiKey,
+		&anthropicModelName,
+		&mistralApiKey,
+		&mistralModelName,
+		&ollamaBaseURL,
+		&ollamaModelName,
+		&ollamaTimeout,
 	}
 
 	app.cmd.Action = func(ctx context.Context, c *cmd.Command, args []string) error {
@@ -156,13 +261,30 @@ func NewApp(name string) *App { //nolint:funlen
 			setIfFlagIsSet(&app.opt.CommitHistoryLength, commitHistoryLength)
 			setIfFlagIsSet(&app.opt.EnableEmoji, enableEmoji)
 			setIfFlagIsSet(&app.opt.MaxOutputTokens, maxOutputTokens)
+			setIfFlagIsSet(&app.opt.IncludeUnstaged, includeUnstaged)
+			setIfFlagIsSet(&app.opt.IncludeUntracked, includeUntracked)
+			setIfFlagIsSet(&app.opt.NoRenames, noRenames)
 			setIfFlagIsSet(&app.opt.AIProviderName, aiProviderName)
 			setIfFlagIsSet(&app.opt.Providers.Gemini.ApiKey, geminiApiKey)
 			setIfFlagIsSet(&app.opt.Providers.Gemini.ModelName, geminiModelName)
 			setIfFlagIsSet(&app.opt.Providers.OpenAI.ApiKey, openAIApiKey)
 			setIfFlagIsSet(&app.opt.Providers.OpenAI.ModelName, openAIModelName)
+			setIfFlagIsSet(&app.opt.Providers.OpenAI.BaseURL, openAIBaseURL)
 			setIfFlagIsSet(&app.opt.Providers.OpenRouter.ApiKey, openRouterApiKey)
 			setIfFlagIsSet(&app.opt.Providers.OpenRouter.ModelName, openRouterModelName)
+			setIfFlagIsSet(&app.opt.Providers.HuggingFace.ApiKey, huggingFaceApiKey)
+			setIfFlagIsSet(&app.opt.Providers.HuggingFace.ModelName, huggingFaceModelName)
+			setIfFlagIsSet(&app.opt.Providers.Anthropic.ApiKey, anthropicApiKey)
+			setIfFlagIsSet(&app.opt.Providers.Anthropic.ModelName, anthropicModelName)
+			setIfFlagIsSet(&app.opt.Providers.Mistral.ApiKey, mistralApiKey)
+			setIfFlagIsSet(&app.opt.Providers.Mistral.ModelName, mistralModelName)
+			setIfFlagIsSet(&app.opt.Providers.Ollama.BaseURL, ollamaBaseURL)
+			setIfFlagIsSet(&app.opt.Providers.Ollama.ModelName, ollamaModelName)
+			setIfFlagIsSet(&app.opt.Providers.Ollama.Timeout, ollamaTimeout)
+
+			if stashIndex.IsSet() && stashIndex.Value != nil {
+				app.opt.StashIndex = stashIndex.Value
+			}
 		}
 
 		if err := app.opt.Validate(); err != nil {
@@ -246,25 +368,72 @@ func (a *App) run(ctx context.Context, workingDir string) error { //nolint:funle
 		provider = ai.NewOpenAI(
 			a.opt.Providers.OpenAI.ApiKey,
 			a.opt.Providers.OpenAI.ModelName,
+			ai.WithOpenAIBaseURL(a.opt.Providers.OpenAI.BaseURL),
 		)
 	case ai.ProviderOpenRouter:
 		provider = ai.NewOpenRouter(
 			a.opt.Providers.OpenRouter.ApiKey,
 			a.opt.Providers.OpenRouter.ModelName,
 		)
+	case ai.ProviderHuggingFace:
+		provider = ai.NewHuggingFace(
+			a.opt.Providers.HuggingFace.ApiKey,
+			a.opt.Providers.HuggingFace.ModelName,
+			ai.WithWaitForModel(true),
+		)
+	case ai.ProviderAnthropic:
+		provider = ai.NewAnthropic(
+			a.opt.Providers.Anthropic.ApiKey,
+			a.opt.Providers.Anthropic.ModelName,
+		)
+	case ai.ProviderMistral:
+		provider = ai.NewMistral(
+			a.opt.Providers.Mistral.ApiKey,
+			a.opt.Providers.Mistral.ModelName,
+		)
+	case ai.ProviderOllama:
+		provider = ai.NewOllama(
+			a.opt.Providers.Ollama.BaseURL,
+			a.opt.Providers.Ollama.ModelName,
+			ai.WithOllamaTimeout(a.opt.Providers.Ollama.Timeout),
+		)
 	default:
 		return fmt.Errorf("unsupported AI provider: %s", a.opt.AIProviderName)
 	}
 
 	debug.Printf("working directory: %s", workingDir)
 
+	if a.opt.StashIndex == nil { // the stash entries are not affected by the operation in progress
+		if done, err := a.describeOperation(workingDir); err != nil || done {
+			return err
+		}
+	}
+
 	var (
 		eg, _            = errgroup.New(ctx)
 		changes, commits string
 	)
 
 	eg.Go(func(ctx context.Context) (err error) {
-		changes, err = git.Diff(ctx, workingDir)
+		var opts []git.DiffOption
+
+		if a.opt.NoRenames {
+			opts = append(opts, git.WithRenameDetection(0))
+		}
+
+		if idx := a.opt.StashIndex; idx != nil {
+			changes, err = git.Stash(ctx, workingDir, int(*idx), opts...)
+		} else {
+			if a.opt.IncludeUnstaged {
+				opts = append(opts, git.WithIncludeUnstaged())
+			}
+
+			if a.opt.IncludeUntracked {
+				opts = append(opts, git.WithIncludeUntracked())
+			}
+
+			changes, err = git.Diff(ctx, workingDir, opts...)
+		}
 
 		return
 	})
@@ -297,6 +466,7 @@ func (a *App) run(ctx context.Context, workingDir string) error { //nolint:funle
 		ai.WithShortMessageOnly(a.opt.ShortMessageOnly),
 		ai.WithEmoji(a.opt.EnableEmoji),
 		ai.WithMaxOutputTokens(a.opt.MaxOutputTokens),
+		ai.WithGitDir(workingDir),
 	)
 	if respErr != nil {
 		return respErr
@@ -311,3 +481,35 @@ func (a *App) run(ctx context.Context, workingDir string) error { //nolint:funle
 
 	return nil
 }
+
+// describeOperation handles the operation (merge, rebase, or cherry-pick) in progress, since the staged changes are
+// not written by the user then. For the merge (and the conflicted cherry-pick), the message prepared by git is
+// suggested instead of the generated one (true is returned in this case).
+func (a *App) describeOperation(workingDir string) (bool, error) {
+	state, err := git.RepoState(workingDir)
+	if err != nil {
+		return false, err
+	}
+
+	debug.Printf("repository state: %s", state)
+
+	switch state {
+	case git.StateMerging, git.StateCherryPicking:
+		msg, mErr := git.MergeMessage(workingDir)
+		if mErr != nil || msg == "" {
+			return false, mErr
+		}
+
+		if a.opt.ShortMessageOnly {
+			msg, _, _ = strings.Cut(msg, "\n")
+		}
+
+		_, err = fmt.Fprintln(os.Stdout, msg)
+
+		return true, err
+	case git.StateRebasing:
+		_, _ = fmt.Fprintln(os.Stderr, "warning: a rebase is in progress, the staged changes may not be yours")
+	}
+
+	return false, nil
+}
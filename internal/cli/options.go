@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"gh.tarampamp.am/describe-commit/internal/ai"
 	"gh.tarampamp.am/describe-commit/internal/config"
@@ -17,11 +18,22 @@ type options struct {
 	EnableEmoji         bool
 	MaxOutputTokens     int64
 	AIProviderName      string
+	StashIndex          *int64 // nil = describe the staged changes
+	IncludeUnstaged     bool   // describe the unstaged changes too
+	IncludeUntracked    bool   // describe the untracked files too
+	NoRenames           bool   // disable the rename and copy detection in the diff
 
 	Providers struct {
-		Gemini     struct{ ApiKey, ModelName string }
-		OpenAI     struct{ ApiKey, ModelName string }
-		OpenRouter struct{ ApiKey, ModelName string }
+		Gemini      struct{ ApiKey, ModelName string }
+		OpenAI      struct{ ApiKey, ModelName, BaseURL string }
+		OpenRouter  struct{ ApiKey, ModelName string }
+		HuggingFace struct{ ApiKey, ModelName string }
+		Anthropic   struct{ ApiKey, ModelName string }
+		Mistral     struct{ ApiKey, ModelName string }
+		Ollama      struct {
+			BaseURL, ModelName string
+			Timeout            time.Duration
+		}
 	}
 }
 
@@ -34,7 +46,14 @@ func newOptionsWithDefaults() options {
 
 	opt.Providers.Gemini.ModelName = "gemini-2.0-flash"
 	opt.Providers.OpenAI.ModelName = "gpt-4o-mini"
+	opt.Providers.OpenAI.BaseURL = ai.OpenAIDefaultBaseURL
 	opt.Providers.OpenRouter.ModelName = "nvidia/llama-3.1-nemotron-70b-instruct:free"
+	opt.Providers.HuggingFace.ModelName = "meta-llama/Llama-3.1-8B-Instruct"
+	opt.Providers.Anthropic.ModelName = "claude-3-5-haiku-latest"
+	opt.Providers.Mistral.ModelName = ai.MistralDefaultModel
+	opt.Providers.Ollama.BaseURL = ai.OllamaDefaultBaseURL
+	opt.Providers.Ollama.ModelName = "llama3.2"
+	opt.Providers.Ollama.Timeout = 5 * time.Minute //nolint:mnd
 
 	return opt
 }
@@ -78,6 +97,7 @@ func (o *options) UpdateFromConfigFile(filePath []string) error {
 	if sub := cfg.OpenAI; sub != nil {
 		setIfSourceNotNil(&o.Providers.OpenAI.ApiKey, sub.ApiKey)
 		setIfSourceNotNil(&o.Providers.OpenAI.ModelName, sub.ModelName)
+		setIfSourceNotNil(&o.Providers.OpenAI.BaseURL, sub.BaseURL)
 	}
 
 	if sub := cfg.OpenRouter; sub != nil {
@@ -85,6 +105,35 @@ func (o *options) UpdateFromConfigFile(filePath []string) error {
 		setIfSourceNotNil(&o.Providers.OpenRouter.ModelName, sub.ModelName)
 	}
 
+	if sub := cfg.HuggingFace; sub != nil {
+		setIfSourceNotNil(&o.Providers.HuggingFace.ApiKey, sub.ApiKey)
+		setIfSourceNotNil(&o.Providers.HuggingFace.ModelName, sub.ModelName)
+	}
+
+	if sub := cfg.Anthropic; sub != nil {
+		setIfSourceNotNil(&o.Providers.Anthropic.ApiKey, sub.ApiKey)
+		setIfSourceNotNil(&o.Providers.Anthropic.ModelName, sub.ModelName)
+	}
+
+	if sub := cfg.Mistral; sub != nil {
+		setIfSourceNotNil(&o.Providers.Mistral.ApiKey, sub.ApiKey)
+		setIfSourceNotNil(&o.Providers.Mistral.ModelName, sub.ModelName)
+	}
+
+	if sub := cfg.Ollama; sub != nil {
+		setIfSourceNotNil(&o.Providers.Ollama.BaseURL, sub.BaseURL)
+		setIfSourceNotNil(&o.Providers.Ollama.ModelName, sub.ModelName)
+
+		if sub.Timeout != nil {
+			d, err := time.ParseDuration(*sub.Timeout)
+			if err != nil {
+				return fmt.Errorf("wrong Ollama timeout: %w", err)
+			}
+
+			o.Providers.Ollama.Timeout = d
+		}
+	}
+
 	return nil
 }
 
@@ -136,5 +185,45 @@ func (o *options) Validate() error {
 		}
 	}
 
+	if o.AIProviderName == ai.ProviderHuggingFace {
+		if o.Providers.HuggingFace.ApiKey == "" {
+			return errors.New("Hugging Face API key is required")
+		}
+
+		if o.Providers.HuggingFace.ModelName == "" {
+			return errors.New("Hugging Face model name is required")
+		}
+	}
+
+	if o.AIProviderName == ai.ProviderAnthropic {
+		if o.Providers.Anthropic.ApiKey == "" {
+			return errors.New("Anthropic API key is required")
+		}
+
+		if o.Providers.Anthropic.ModelName == "" {
+			return errors.New("Anthropic model name is required")
+		}
+	}
+
+	if o.AIProviderName == ai.ProviderMistral {
+		if o.Providers.Mistral.ApiKey == "" {
+			return errors.New("Mistral API key is required")
+		}
+
+		if o.Providers.Mistral.ModelName == "" {
+			return errors.New("Mistral model name is required")
+		}
+	}
+
+	if o.AIProviderName == ai.ProviderOllama {
+		if o.Providers.Ollama.ModelName == "" {
+			return errors.New("Ollama model name is required")
+		}
+
+		if o.Providers.Ollama.Timeout <= 0 {
+			return errors.New("Ollama timeout must be positive")
+		}
+	}
+
 	return nil
 }
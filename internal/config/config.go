@@ -13,14 +13,18 @@ type (
 	// Config is used to unmarshal the configuration file content.
 	Config struct {
 		// pointers are used to distinguish between unset and set values (nil = unset)
-		ShortMessageOnly    *bool       `yaml:"shortMessageOnly"`
-		CommitHistoryLength *int64      `yaml:"commitHistoryLength"`
-		EnableEmoji         *bool       `yaml:"enableEmoji"`
-		AIProviderName      *string     `yaml:"aiProvider"`
-		MaxOutputTokens     *int64      `yaml:"maxOutputTokens"`
-		Gemini              *Gemini     `yaml:"gemini"`
-		OpenAI              *OpenAI     `yaml:"openai"`
-		OpenRouter          *OpenRouter `yaml:"openrouter"`
+		ShortMessageOnly    *bool        `yaml:"shortMessageOnly"`
+		CommitHistoryLength *int64       `yaml:"commitHistoryLength"`
+		EnableEmoji         *bool        `yaml:"enableEmoji"`
+		AIProviderName      *string      `yaml:"aiProvider"`
+		MaxOutputTokens     *int64       `yaml:"maxOutputTokens"`
+		Gemini              *Gemini      `yaml:"gemini"`
+		OpenAI              *OpenAI      `yaml:"openai"`
+		OpenRouter          *OpenRouter  `yaml:"openrouter"`
+		HuggingFace         *HuggingFace `yaml:"huggingface"`
+		Anthropic           *Anthropic   `yaml:"anthropic"`
+		Mistral             *Mistral     `yaml:"mistral"`
+		Ollama              *Ollama      `yaml:"ollama"`
 	}
 
 	Gemini struct {
@@ -31,12 +35,34 @@ type (
 	OpenAI struct {
 		ApiKey    *string `yaml:"apiKey"`
 		ModelName *string `yaml:"modelName"`
+		BaseURL   *string `yaml:"baseURL"`
 	}
 
 	OpenRouter struct {
 		ApiKey    *string `yaml:"apiKey"`
 		ModelName *string `yaml:"modelName"`
 	}
+
+	HuggingFace struct {
+		ApiKey    *string `yaml:"apiKey"`
+		ModelName *string `yaml:"modelName"`
+	}
+
+	Anthropic struct {
+		ApiKey    *string `yaml:"apiKey"`
+		ModelName *string `yaml:"modelName"`
+	}
+
+	Mistral struct {
+		ApiKey    *string `yaml:"apiKey"`
+		ModelName *string `yaml:"modelName"`
+	}
+
+	Ollama struct {
+		BaseURL   *string `yaml:"baseURL"`
+		ModelName *string `yaml:"modelName"`
+		Timeout   *string `yaml:"timeout"`
+	}
 )
 
 // FromFile initializes self state by reading the configuration file from the provided path.
@@ -35,9 +35,23 @@ gemini:
 openai:
   apiKey: <openai-api-key>
   modelName: <openai-model-name>
+  baseURL: https://llm-gateway.example.com/v1
 openrouter:
   apiKey: <openrouter-api-key>
-  modelName: <openrouter-model-name>`,
+  modelName: <openrouter-model-name>
+huggingface:
+  apiKey: <huggingface-api-key>
+  modelName: <huggingface-model-name>
+anthropic:
+  apiKey: <anthropic-api-key>
+  modelName: <anthropic-model-name>
+mistral:
+  apiKey: <mistral-api-key>
+  modelName: <mistral-model-name>
+ollama:
+  baseURL: http://127.0.0.1:11434
+  modelName: <ollama-model-name>
+  timeout: 10m`,
 			wantStruct: func() (c config.Config) {
 				c.ShortMessageOnly = toPtr(true)
 				c.CommitHistoryLength = toPtr[int64](312312)
@@ -51,11 +65,29 @@ openrouter:
 				c.OpenAI = &config.OpenAI{
 					ApiKey:    toPtr("<openai-api-key>"),
 					ModelName: toPtr("<openai-model-name>"),
+					BaseURL:   toPtr("https://llm-gateway.example.com/v1"),
 				}
 				c.OpenRouter = &config.OpenRouter{
 					ApiKey:    toPtr("<openrouter-api-key>"),
 					ModelName: toPtr("<openrouter-model-name>"),
 				}
+				c.HuggingFace = &config.HuggingFace{
+					ApiKey:    toPtr("<huggingface-api-key>"),
+					ModelName: toPtr("<huggingface-model-name>"),
+				}
+				c.Anthropic = &config.Anthropic{
+					ApiKey:    toPtr("<anthropic-api-key>"),
+					ModelName: toPtr("<anthropic-model-name>"),
+				}
+				c.Mistral = &config.Mistral{
+					ApiKey:    toPtr("<mistral-api-key>"),
+					ModelName: toPtr("<mistral-model-name>"),
+				}
+				c.Ollama = &config.Ollama{
+					BaseURL:   toPtr("http://127.0.0.1:11434"),
+					ModelName: toPtr("<ollama-model-name>"),
+					Timeout:   toPtr("10m"),
+				}
 
 				return
 			}(),
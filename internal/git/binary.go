@@ -0,0 +1,123 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"  // register the GIF format for the image dimensions detection
+	_ "image/jpeg" // register the JPEG format for the image dimensions detection
+	_ "image/png"  // register the PNG format for the image dimensions detection
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxBinaryInspectSize limits the size of the binary file read to detect its type and the image dimensions. The
+// type of the larger files is guessed by the extension.
+const maxBinaryInspectSize = 8 << 20 // 8 MiB
+
+// BinaryFile is the metadata of a single state of the binary file.
+type BinaryFile struct {
+	Size          int64  // the size in bytes
+	Type          string // the detected MIME type (e.g. "image/png"), empty if unknown
+	Width, Height int    // the image dimensions (zero if not an image, or the format is not supported)
+}
+
+// BinaryChange describes the change of a binary file using the metadata of its states.
+type BinaryChange struct {
+	Path     string
+	Old, New *BinaryFile // nil if the file is added (Old), or deleted (New)
+}
+
+// BinaryChanges returns the metadata of the binary files changed by the patch (the ones listed as "Binary files
+// ... differ", or with the "GIT binary patch"). The previous state of the file is taken from HEAD, and the new one
+// from the index. The files missing in both are skipped.
+func BinaryChanges(ctx context.Context, dirPath, patch string) ([]BinaryChange, error) {
+	var changes []BinaryChange
+
+	for _, file := range SplitPatch(patch) {
+		if !isBinarySection(file.Text) {
+			continue
+		}
+
+		var change = BinaryChange{Path: file.Path}
+
+		if file.OldPath != "" {
+			old, err := inspectBlob(ctx, dirPath, "HEAD:"+file.OldPath)
+			if err != nil {
+				return nil, err
+			}
+
+			change.Old = old
+		}
+
+		if !strings.Contains(file.Text, "\ndeleted file mode ") {
+			current, err := inspectBlob(ctx, dirPath, ":"+file.Path)
+			if err != nil {
+				return nil, err
+			}
+
+			change.New = current
+		}
+
+		if change.Old != nil || change.New != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// isBinarySection reports whether the file section of the patch describes the binary file change.
+func isBinarySection(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if line == "GIT binary patch" || strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inspectBlob returns the metadata of the blob (e.g. `HEAD:path`), or nil if it doesn't exist.
+func inspectBlob(ctx context.Context, dirPath, object string) (*BinaryFile, error) {
+	out, err := run(ctx, dirPath, 32, "cat-file", "-s", object) //nolint:mnd
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, nil //nolint:nilnil // the object doesn't exist (e.g. the file is added)
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		_, name, _ = strings.Cut(object, ":")
+		file       = BinaryFile{Size: size, Type: mime.TypeByExtension(path.Ext(name))}
+	)
+
+	if size == 0 || size > maxBinaryInspectSize {
+		return &file, nil
+	}
+
+	content, err := run(ctx, dirPath, int(size), "cat-file", "blob", object)
+	if err != nil {
+		return nil, err
+	}
+
+	if detected := http.DetectContentType([]byte(content)); detected != "application/octet-stream" || file.Type == "" {
+		file.Type = detected
+	}
+
+	if cfg, _, dErr := image.DecodeConfig(bytes.NewReader([]byte(content))); dErr == nil {
+		file.Width, file.Height = cfg.Width, cfg.Height
+	}
+
+	return &file, nil
+}
@@ -0,0 +1,87 @@
+package git_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+// newPNG returns the PNG image of the given size.
+func newPNG(t *testing.T, width, height int) string {
+	t.Helper()
+
+	var img = image.NewGray(image.Rect(0, 0, width, height))
+
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7 % 251) //nolint:gosec
+	}
+
+	var b bytes.Buffer
+
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func TestBinaryChanges(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir   = newRepo(t)
+		ctx   = context.Background()
+		large = newPNG(t, 64, 64)
+		small = newPNG(t, 32, 16)
+	)
+
+	writeFile(t, dir, "logo.png", large)
+	writeFile(t, dir, "old.bin", "\x00\x01\x02")
+	writeFile(t, dir, "main.go", "package main\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+	writeFile(t, dir, "logo.png", small)
+	writeFile(t, dir, "new.bin", "\x00\x01\x02\x03")
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	runGit(t, dir, "rm", "--quiet", "old.bin")
+	runGit(t, dir, "add", "-A")
+
+	diff, err := git.Diff(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := git.BinaryChanges(ctx, dir, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var byPath = make(map[string]git.BinaryChange, len(changes))
+
+	for _, c := range changes {
+		byPath[c.Path] = c
+	}
+
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 binary changes, got %+v", changes)
+	}
+
+	if c := byPath["logo.png"]; c.Old == nil || c.New == nil ||
+		*c.Old != (git.BinaryFile{Size: int64(len(large)), Type: "image/png", Width: 64, Height: 64}) ||
+		*c.New != (git.BinaryFile{Size: int64(len(small)), Type: "image/png", Width: 32, Height: 16}) {
+		t.Errorf("unexpected logo.png change: %+v, %+v", c.Old, c.New)
+	}
+
+	if c := byPath["new.bin"]; c.Old != nil || c.New == nil || c.New.Size != 4 {
+		t.Errorf("unexpected new.bin change: %+v, %+v", c.Old, c.New)
+	}
+
+	if c := byPath["old.bin"]; c.New != nil || c.Old == nil || c.Old.Size != 3 {
+		t.Errorf("unexpected old.bin change: %+v, %+v", c.Old, c.New)
+	}
+}
@@ -0,0 +1,159 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxBlameHunks limits the number of hunks blamed, since every hunk requires a separate git call.
+const maxBlameHunks = 20
+
+// blameEntry is the authorship of the lines changed in a single commit.
+type blameEntry struct {
+	Commit, Author, Summary string
+	Lines                   int
+	Files                   map[string]struct{}
+}
+
+// Blame summarizes who last touched the lines modified or removed by the patch (only the previous state of the
+// changed regions, taken from HEAD, is blamed). The summary is limited to the given number of the most relevant
+// commits. Files that can't be blamed (e.g. new ones) are skipped.
+func Blame(ctx context.Context, dirPath, patch string, limit int) (string, error) {
+	var (
+		entries = make(map[string]*blameEntry)
+		hunks   int
+	)
+
+	for _, file := range SplitPatch(patch) {
+		if file.OldPath == "" {
+			continue // new file, nothing to blame
+		}
+
+		for _, hunk := range file.Hunks() {
+			if hunk.OldLines == 0 || hunk.OldStart == 0 {
+				continue // pure addition, there is no previous state
+			}
+
+			if hunks++; hunks > maxBlameHunks {
+				break
+			}
+
+			out, err := run(ctx, dirPath, 1024*4, "blame", "--porcelain", //nolint:mnd // 4KB
+				fmt.Sprintf("-L%d,+%d", hunk.OldStart, hunk.OldLines), "HEAD", "--", file.OldPath,
+			)
+			if err != nil {
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+
+				continue // the file is not in HEAD (or something similar), skip it
+			}
+
+			parseBlamePorcelain(out, file.OldPath, entries)
+		}
+	}
+
+	return formatBlame(entries, limit), nil
+}
+
+// parseBlamePorcelain parses the `git blame --porcelain` output and accumulates the authorship into the entries.
+func parseBlamePorcelain(out, path string, entries map[string]*blameEntry) {
+	var (
+		scanner = bufio.NewScanner(strings.NewReader(out))
+		current *blameEntry
+	)
+
+	for scanner.Scan() {
+		var line = scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "\t"): // the line content
+			continue
+		case isBlameHeader(line):
+			var sha = line[:40]
+
+			if current = entries[sha]; current == nil {
+				current = &blameEntry{Commit: sha, Files: make(map[string]struct{})}
+				entries[sha] = current
+			}
+
+			current.Lines++
+			current.Files[path] = struct{}{}
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "author "):
+			current.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "summary "):
+			current.Summary = strings.TrimPrefix(line, "summary ")
+		}
+	}
+}
+
+// isBlameHeader reports whether the line is a `<sha> <orig-line> <final-line>[ <lines>]` porcelain header.
+func isBlameHeader(line string) bool {
+	if len(line) < 42 || line[40] != ' ' { //nolint:mnd
+		return false
+	}
+
+	for _, r := range line[:40] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// formatBlame formats the most relevant (by the number of lines) entries, one per line.
+func formatBlame(entries map[string]*blameEntry, limit int) string {
+	var list = make([]*blameEntry, 0, len(entries))
+
+	for _, e := range entries {
+		if strings.Trim(e.Commit, "0") == "" {
+			continue // not committed yet
+		}
+
+		list = append(list, e)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Lines != list[j].Lines {
+			return list[i].Lines > list[j].Lines
+		}
+
+		return list[i].Commit < list[j].Commit
+	})
+
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+
+	var b strings.Builder
+
+	for _, e := range list {
+		var files = make([]string, 0, len(e.Files))
+
+		for f := range e.Files {
+			files = append(files, f)
+		}
+
+		sort.Strings(files)
+
+		_, _ = fmt.Fprintf(&b, "- %s by %s: %q (%d changed line(s) in %s)\n",
+			e.Commit[:7], e.Author, e.Summary, e.Lines, strings.Join(files, ", "),
+		)
+	}
+
+	return b.String()
+}
+
+// atoi converts the string to int, ignoring errors (the input is validated by the regular expression).
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+
+	return i
+}
@@ -0,0 +1,58 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBlamePorcelain(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sha1 = "1111111111111111111111111111111111111111"
+		sha2 = "2222222222222222222222222222222222222222"
+		zero = "0000000000000000000000000000000000000000"
+	)
+
+	var out = strings.Join([]string{
+		sha1 + " 10 10 2",
+		"author Jane Doe",
+		"author-mail <jane@example.com>",
+		"summary feat(api): Add the rate limiter",
+		"filename internal/api/limit.go",
+		"\tfunc limit() {",
+		sha1 + " 11 11",
+		"\t}",
+		sha2 + " 3 12 1",
+		"author John Smith",
+		"summary refactor: Extract helpers",
+		"filename internal/api/limit.go",
+		"\t// summary not a header",
+		zero + " 4 13 1",
+		"author Not Committed Yet",
+		"summary Version of internal/api/limit.go from internal/api/limit.go",
+		"\t+",
+	}, "\n")
+
+	var entries = make(map[string]*blameEntry)
+
+	parseBlamePorcelain(out, "internal/api/limit.go", entries)
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	if e := entries[sha1]; e.Author != "Jane Doe" || e.Lines != 2 || e.Summary != "feat(api): Add the rate limiter" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	var got = formatBlame(entries, 1)
+
+	if want := "- 1111111 by Jane Doe: \"feat(api): Add the rate limiter\" (2 changed line(s) in internal/api/limit.go)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got = formatBlame(entries, 0); strings.Contains(got, "Not Committed Yet") || !strings.Contains(got, "John Smith") {
+		t.Errorf("unexpected summary: %q", got)
+	}
+}
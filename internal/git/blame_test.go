@@ -0,0 +1,35 @@
+package git_test
+
+import (
+	"context"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestBlame(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {\n\tprintln(1)\n}\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "feat: Print the number")
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {\n\tprintln(2)\n}\n")
+	writeFile(t, dir, "new.go", "package main\n")
+	runGit(t, dir, "add", "-A")
+
+	patch, err := git.Diff(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := git.Blame(context.Background(), dir, patch, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, summary, "by Test", `"feat: Print the number"`, "in main.go")
+	assertNotContains(t, summary, "new.go")
+}
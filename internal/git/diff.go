@@ -3,60 +3,375 @@ package git
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 )
 
-// Diff returns the diff of the staged changes or changes between the index and the working tree.
-func Diff(ctx context.Context, dirPath string) (string, error) {
-	// ensure git is installed and available to run
-	gitFilePath, lookErr := binPath()
-	if lookErr != nil {
-		return "", lookErr
-	}
-
-	// get the diff
-	var cmd = exec.CommandContext(ctx, gitFilePath, "diff",
-		"--cached",                 // show all staged changes or changes between the index and the working tree
-		"--ignore-submodules=all",  // ignore changes to submodules
-		"--diff-algorithm=minimal", // use the minimal diff algorithm
-		"--no-ext-diff",            // do not use external diff helper
-		"--ignore-all-space",       // ignore whitespace when comparing lines
-		"--ignore-blank-lines",     // ignore changes whose lines are all blank
-		"--no-color",               // do not use any color in the output
-		"--patch",                  // generate patch (unified diff) format
-		"--",
-		":(exclude)*.sum",  // exclude .sum files
-		":(exclude)*.lock", // exclude .lock files
-		":(exclude)*.log",  // exclude .log files
-		":(exclude)*.out",  // exclude .out files
-		":(exclude)*.tmp",  // exclude .tmp files
-		":(exclude)*.bak",  // exclude .bak files
-		":(exclude)*.swp",  // exclude .swp files
-		":(exclude)*.env",  // exclude .env files
+type (
+	diffOptions struct {
+		Unstaged, Untracked bool
+		Excludes            []string // the additional exclude patterns
+		NoDefaultExcludes   bool     // do not exclude the [defaultExcludes]
+		NoRenames           bool     // disable the rename and copy detection
+		RenameThreshold     int      // the similarity (%) of the renamed and copied files (0 = git's default, 50%)
+		ContextLines        *int     // the number of the context lines around the changes (nil = git's default, 3)
+	}
+
+	// DiffOption allows to include more changes into the [Diff] output, or to change the excluded paths.
+	DiffOption func(*diffOptions)
+)
+
+// WithIncludeUnstaged includes the unstaged changes (between the index and the working tree) into the diff.
+func WithIncludeUnstaged() DiffOption { return func(o *diffOptions) { o.Unstaged = true } }
+
+// WithIncludeUntracked includes the untracked (and not ignored) files into the diff, as the newly added ones.
+func WithIncludeUntracked() DiffOption { return func(o *diffOptions) { o.Untracked = true } }
+
+// WithExcludePatterns excludes the paths matching the patterns (e.g. `*.pb.go` or `vendor/*`) from the diff, in
+// addition to the default ones (see [WithIncludeDefaults]). The patterns are passed to git as the `:(exclude)`
+// pathspecs.
+func WithExcludePatterns(patterns ...string) DiffOption {
+	return func(o *diffOptions) { o.Excludes = append(o.Excludes, patterns...) }
+}
+
+// WithIncludeDefaults enables (the default) or disables excluding the lock, log, and other noisy files (`*.sum`,
+// `*.lock`, `*.log`, etc.) from the diff. When disabled, only the patterns set by [WithExcludePatterns] are excluded.
+func WithIncludeDefaults(on bool) DiffOption {
+	return func(o *diffOptions) { o.NoDefaultExcludes = !on }
+}
+
+// WithRenameDetection sets the similarity threshold (in percent, up to 100) of the rename and copy detection (the
+// `-M` and `-C` flags), so the moved and copied files are shown compactly, instead of the whole deleted and added
+// content. The detection is enabled by default, with the git's default threshold (50%). Zero or negative threshold
+// disables it, so the raw diff is returned.
+func WithRenameDetection(threshold int) DiffOption {
+	return func(o *diffOptions) { o.NoRenames, o.RenameThreshold = threshold <= 0, min(threshold, 100) } //nolint:mnd
+}
+
+// WithContextLines sets the number of the context lines around the changes (the `-U<n>` flag), to shrink the diff
+// for the token-constrained models. Git shows 3 lines by default; the negative number makes the [Diff] fail.
+func WithContextLines(n int) DiffOption { return func(o *diffOptions) { o.ContextLines = &n } }
+
+// flags returns the flags of the rename and copy detection, and of the context lines.
+func (o diffOptions) flags() []string {
+	var flags = make([]string, 0, 3) //nolint:mnd
+
+	if o.NoRenames {
+		flags = append(flags, "--no-renames")
+	} else {
+		var similarity string
+
+		if o.RenameThreshold > 0 {
+			similarity = strconv.Itoa(o.RenameThreshold) + "%"
+		}
+
+		flags = append(flags, "-M"+similarity, "-C"+similarity)
+	}
+
+	if o.ContextLines != nil {
+		flags = append(flags, "-U"+strconv.Itoa(*o.ContextLines))
+	}
+
+	return flags
+}
+
+// excludes returns the pathspecs excluded from the diff.
+func (o diffOptions) excludes() []string {
+	var excludes = make([]string, 0, len(o.Excludes)+8) //nolint:mnd
+
+	if !o.NoDefaultExcludes {
+		excludes = append(excludes, defaultExcludes()...)
+	}
+
+	for _, pattern := range o.Excludes {
+		if pattern != "" {
+			excludes = append(excludes, ":(exclude)"+pattern)
+		}
+	}
+
+	return excludes
+}
+
+// ignoreFileName is the name of the file with the patterns (one per line, `.gitignore`-like) of the paths excluded
+// from the diff, in addition to the default ones. It is read from the directory the diff is taken in.
+const ignoreFileName = ".describe-commit-ignore"
+
+// readIgnoreFile returns the exclude patterns read from the [ignoreFileName] file in the directory. The blank lines
+// and the comments (the lines starting with `#`) are skipped. Nil is returned if the file doesn't exist.
+func readIgnoreFile(dirPath string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dirPath, ignoreFileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var patterns []string
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+
+	return patterns, nil
+}
+
+// newDiffOptions applies the options, and adds the patterns from the [ignoreFileName] file in the directory to the
+// excluded ones. The error is returned if the options are invalid.
+func newDiffOptions(dirPath string, opts ...DiffOption) (diffOptions, error) {
+	var o diffOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.ContextLines != nil && *o.ContextLines < 0 {
+		return o, fmt.Errorf("invalid number of the context lines %d: must not be negative", *o.ContextLines)
+	}
+
+	ignored, err := readIgnoreFile(dirPath)
+	if err != nil {
+		return o, err
+	}
+
+	o.Excludes = append(o.Excludes[:len(o.Excludes):len(o.Excludes)], ignored...)
+
+	return o, nil
+}
+
+// maxUntrackedFileSize limits the size of the untracked file included into the diff; the larger files are listed
+// without the content.
+const maxUntrackedFileSize = 1 << 20 // 1 MiB
+
+// Diff returns the diff of the staged changes. The unstaged changes and the untracked files can be included using
+// the options; the output is concatenated in a stable order: the staged changes, the unstaged ones, and the
+// untracked files (sorted by path). The paths matching the patterns from the `.describe-commit-ignore` file in the
+// directory (if any) are excluded, in addition to the ones set by the options. The renamed and copied files are
+// detected (see [WithRenameDetection]).
+func Diff(ctx context.Context, dirPath string, opts ...DiffOption) (string, error) {
+	o, err := newDiffOptions(dirPath, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		excludes = o.excludes()
+		flags    = o.flags()
 	)
 
-	cmd.Dir = dirPath
-	cmd.Env = []string{
-		"LC_ALL=C", "LANG=C", // forces the system to use the "C" (POSIX) locale, English-based output with no localization
-		"NO_COLOR=1",            // disables colored output
-		"GIT_CONFIG_NOSYSTEM=1", // do not use the system-wide configuration file
+	out, err := runDiff(ctx, dirPath, excludes, append([]string{
+		"--cached", // show all staged changes or changes between the index and the working tree
+	}, flags...)...)
+	if err != nil {
+		return "", err
+	}
+
+	if o.Unstaged {
+		unstaged, uErr := runDiff(ctx, dirPath, excludes, flags...) // the working tree vs the index
+		if uErr != nil {
+			return "", uErr
+		}
+
+		out += unstaged
+	}
+
+	if o.Untracked {
+		untracked, uErr := untrackedDiff(ctx, dirPath, excludes)
+		if uErr != nil {
+			return "", uErr
+		}
+
+		out += untracked
+	}
+
+	return out, nil
+}
+
+// ChangedPaths returns the paths (relative to the repository root, sorted) of the staged files. The default excludes
+// (see [Diff]) are not applied, so the lock files and the like are listed too.
+func ChangedPaths(ctx context.Context, dirPath string) ([]string, error) {
+	out, err := run(ctx, dirPath, 1024, "diff", "--cached", "--name-only", "--no-renames", "-z") //nolint:mnd
+	if err != nil {
+		return nil, err
+	}
+
+	var paths = strings.Split(strings.TrimRight(out, "\x00"), "\x00")
+
+	paths = slices.DeleteFunc(paths, func(p string) bool { return p == "" })
+	slices.Sort(paths)
+
+	return paths, nil
+}
+
+// untrackedDiff returns the synthetic diff adding the untracked (and not ignored) files of the repository. The
+// binary and too large files are listed without the content.
+func untrackedDiff(ctx context.Context, dirPath string, excludes []string) (string, error) {
+	topLevel, err := run(ctx, dirPath, 256, "rev-parse", "--show-toplevel") //nolint:mnd
+	if err != nil {
+		return "", err
 	}
 
-	var stdOut, stdErr bytes.Buffer
+	topLevel = strings.TrimSpace(topLevel)
+
+	v, err := GitVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var args = []string{"ls-files", "--others", "--exclude-standard", "-z"}
+
+	if v.AtLeast(excludeMagicVersion) && len(excludes) > 0 {
+		args = append(append(args, "--"), excludes...)
+	}
+
+	list, err := run(ctx, topLevel, 1024, args...) //nolint:mnd
+	if err != nil {
+		return "", err
+	}
+
+	var files = strings.Split(strings.TrimRight(list, "\x00"), "\x00")
+
+	slices.Sort(files)
 
-	stdOut.Grow(1024 * 8) //nolint:mnd // 8KB
+	var b strings.Builder
 
-	cmd.Stdout = &stdOut
-	cmd.Stderr = &stdErr
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
 
-	if err := cmd.Run(); err != nil {
-		if stdErr.Len() > 0 {
-			err = fmt.Errorf("%s: %w", stdErrToString(stdErr.String()), err)
+		var patch, pErr = newFilePatch(filepath.Join(topLevel, filepath.FromSlash(file)), file)
+		if pErr != nil {
+			return "", pErr
 		}
 
-		return "", fmt.Errorf("git diff failed: %w", err)
+		b.WriteString(patch)
+	}
+
+	return b.String(), nil
+}
+
+// newFilePatch returns the diff adding the file with the given path (relative to the repository root).
+func newFilePatch(absPath, path string) (string, error) {
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) { // removed in the meantime
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if !info.Mode().IsRegular() || info.Size() > maxUntrackedFileSize {
+		return fileDiff(path, path, true, false, ""), nil // symlinks and too large files are listed only
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) == 0 {
+		return fileDiff(path, path, true, false, ""), nil
+	}
+
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 { //nolint:mnd // the same heuristic as git uses
+		return fileDiff(path, path, true, false, "") + fmt.Sprintf("Binary files /dev/null and b/%s differ\n", path), nil
+	}
+
+	var (
+		content = string(data)
+		lines   = strings.SplitAfter(content, "\n")
+		b       strings.Builder
+	)
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	b.Grow(len(content) + len(lines) + 32) //nolint:mnd
+	_, _ = fmt.Fprintf(&b, "@@ -0,0 +1,%d @@\n", len(lines))
+
+	for _, line := range lines {
+		b.WriteByte('+')
+		b.WriteString(line)
+	}
+
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+
+	return fileDiff(path, path, true, false, b.String()), nil
+}
+
+// runDiff runs `git diff` with the given arguments, the common flags, and the excluded pathspecs. The flags the
+// installed git does not support are dropped (ErrGitTooOld is returned if it's too old to be used at all).
+func runDiff(ctx context.Context, dirPath string, excludes []string, args ...string) (string, error) {
+	v, err := GitVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if !v.AtLeast(minGitVersion) {
+		return "", fmt.Errorf("%w: %s is installed, but at least %s is required", ErrGitTooOld, v, minGitVersion)
+	}
+
+	return run(ctx, dirPath, 1024*8, diffArgs(v, excludes, args...)...) //nolint:mnd // 8KB
+}
+
+// diffArgs returns the `git diff` arguments: the given ones, the common flags, and the excluded pathspecs (if
+// supported by the git version).
+func diffArgs(v Version, excludes []string, args ...string) []string {
+	args = append(append([]string{"diff"}, args...), diffFlags(v)...)
+
+	if v.AtLeast(excludeMagicVersion) && len(excludes) > 0 {
+		args = append(append(args, "--"), excludes...)
+	}
+
+	return args
+}
+
+// diffFlags returns the common flags for the diff-like commands supported by the given git version.
+func diffFlags(v Version) []string {
+	var flags = []string{
+		"--ignore-submodules=all", // ignore changes to submodules
+		"--no-ext-diff",           // do not use external diff helper
+		"--ignore-all-space",      // ignore whitespace when comparing lines
+		"--no-color",              // do not use any color in the output
+		"--patch",                 // generate patch (unified diff) format
+	}
+
+	if v.AtLeast(diffAlgorithmVersion) {
+		flags = append(flags, "--diff-algorithm=minimal") // use the minimal diff algorithm
+	} else {
+		flags = append(flags, "--minimal") // the same, but the older syntax
 	}
 
-	return stdOut.String(), nil
+	if v.AtLeast(ignoreBlankLinesVersion) {
+		flags = append(flags, "--ignore-blank-lines") // ignore changes whose lines are all blank
+	}
+
+	return flags
+}
+
+// defaultExcludes returns the pathspecs excluded from the diff by default.
+func defaultExcludes() []string {
+	return []string{
+		":(exclude)*.sum",  // exclude .sum files
+		":(exclude)*.lock", // exclude .lock files
+		":(exclude)*.log",  // exclude .log files
+		":(exclude)*.out",  // exclude .out files
+		":(exclude)*.tmp",  // exclude .tmp files
+		":(exclude)*.bak",  // exclude .bak files
+		":(exclude)*.swp",  // exclude .swp files
+		":(exclude)*.env",  // exclude .env files
+	}
 }
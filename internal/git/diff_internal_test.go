@@ -0,0 +1,124 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffArgs_Excludes(t *testing.T) {
+	t.Parallel()
+
+	var defaults = defaultExcludes()
+
+	for name, tc := range map[string]struct {
+		giveOpts    []DiffOption
+		giveVersion Version
+		want        []string // the pathspecs after the "--"
+	}{
+		"default": {
+			giveVersion: Version{2, 39, 5},
+			want:        defaults,
+		},
+		"appended": {
+			giveOpts:    []DiffOption{WithExcludePatterns("*.pb.go", "vendor/*"), WithExcludePatterns("", "dist/*")},
+			giveVersion: Version{2, 39, 5},
+			want:        append(slices.Clone(defaults), ":(exclude)*.pb.go", ":(exclude)vendor/*", ":(exclude)dist/*"),
+		},
+		"replaced": {
+			giveOpts:    []DiffOption{WithIncludeDefaults(false), WithExcludePatterns("*.pb.go")},
+			giveVersion: Version{2, 39, 5},
+			want:        []string{":(exclude)*.pb.go"},
+		},
+		"nothing excluded": {
+			giveOpts:    []DiffOption{WithIncludeDefaults(false)},
+			giveVersion: Version{2, 39, 5},
+		},
+		"not supported": {
+			giveOpts:    []DiffOption{WithExcludePatterns("*.pb.go")},
+			giveVersion: Version{1, 8, 5},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var o diffOptions
+
+			for _, opt := range tc.giveOpts {
+				opt(&o)
+			}
+
+			var args = diffArgs(tc.giveVersion, o.excludes(), "--cached")
+
+			if args[0] != "diff" || args[1] != "--cached" {
+				t.Fatalf("unexpected args: %q", args)
+			}
+
+			var got []string
+
+			if i := slices.Index(args, "--"); i >= 0 {
+				got = args[i+1:]
+			}
+
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDiffArgs_Flags(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveOpts    []DiffOption
+		wantFlags   []string
+		wantMissing []string
+	}{
+		"default": {
+			wantFlags:   []string{"-M", "-C"},
+			wantMissing: []string{"--no-renames", "-U3"},
+		},
+		"context lines": {
+			giveOpts:  []DiffOption{WithContextLines(1)},
+			wantFlags: []string{"-M", "-C", "-U1"},
+		},
+		"no context lines": {
+			giveOpts:  []DiffOption{WithContextLines(0)},
+			wantFlags: []string{"-U0"},
+		},
+		"rename threshold": {
+			giveOpts:    []DiffOption{WithRenameDetection(75)},
+			wantFlags:   []string{"-M75%", "-C75%"},
+			wantMissing: []string{"-M", "-C"},
+		},
+		"renames disabled": {
+			giveOpts:    []DiffOption{WithRenameDetection(0), WithContextLines(5)},
+			wantFlags:   []string{"--no-renames", "-U5"},
+			wantMissing: []string{"-M", "-C"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var o diffOptions
+
+			for _, opt := range tc.giveOpts {
+				opt(&o)
+			}
+
+			var args = diffArgs(Version{2, 39, 5}, o.excludes(), append([]string{"--cached"}, o.flags()...)...)
+
+			for _, want := range tc.wantFlags {
+				if !slices.Contains(args, want) {
+					t.Errorf("expected %q to contain %q", args, want)
+				}
+			}
+
+			for _, want := range tc.wantMissing {
+				if slices.Contains(args, want) {
+					t.Errorf("expected %q to not contain %q", args, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package git_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, ".gitignore", "*.ignored\n")
+	writeFile(t, dir, "main.go", "package main\n")
+	writeFile(t, dir, "util.go", "package main\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc staged() {}\n")
+	runGit(t, dir, "add", "main.go")
+	writeFile(t, dir, "util.go", "package main\n\nfunc unstaged() {}\n")
+	writeFile(t, dir, "pkg/new.go", "package pkg\n\nfunc untracked() {}")
+	writeFile(t, dir, "a.txt", "first\n")
+	writeFile(t, dir, "logo.bin", "\x00\x01\x02")
+	writeFile(t, dir, "empty.txt", "")
+	writeFile(t, dir, "debug.ignored", "secret\n")
+	writeFile(t, dir, "deps.lock", "v1\n")
+
+	t.Run("staged only", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := git.Diff(context.Background(), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertContains(t, out, "diff --git a/main.go b/main.go", "+func staged() {}")
+		assertNotContains(t, out, "util.go", "new.go", "a.txt")
+	})
+
+	t.Run("unstaged", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := git.Diff(context.Background(), dir, git.WithIncludeUnstaged())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertContains(t, out, "+func staged() {}", "diff --git a/util.go b/util.go", "+func unstaged() {}")
+		assertNotContains(t, out, "new.go")
+
+		if strings.Index(out, "main.go") > strings.Index(out, "util.go") {
+			t.Error("the staged changes must go first")
+		}
+	})
+
+	t.Run("untracked", func(t *testing.T) {
+		t.Parallel()
+
+		// the untracked files of the whole repository are included, even if run from a subdirectory
+		out, err := git.Diff(context.Background(), filepath.Join(dir, "pkg"), git.WithIncludeUntracked())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertContains(t, out,
+			"+func staged() {}",
+			"diff --git a/a.txt b/a.txt\nnew file mode 100644\n--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1,1 @@\n+first\n",
+			"diff --git a/pkg/new.go b/pkg/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/pkg/new.go\n"+
+				"@@ -0,0 +1,3 @@\n+package pkg\n+\n+func untracked() {}\n\\ No newline at end of file\n",
+			"diff --git a/logo.bin b/logo.bin\nnew file mode 100644\nBinary files /dev/null and b/logo.bin differ\n",
+			"diff --git a/empty.txt b/empty.txt\nnew file mode 100644\n",
+		)
+		assertNotContains(t, out, "unstaged", "debug.ignored", "secret", "deps.lock")
+
+		if strings.Index(out, "a.txt") > strings.Index(out, "pkg/new.go") {
+			t.Error("the untracked files must be sorted")
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := git.Diff(context.Background(), dir, git.WithIncludeUnstaged(), git.WithIncludeUntracked())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var staged, unstaged, untracked = strings.Index(out, "+func staged"), strings.Index(out, "+func unstaged"),
+			strings.Index(out, "+func untracked")
+
+		if staged < 0 || staged > unstaged || unstaged > untracked {
+			t.Errorf("unexpected order of the changes: %q", out)
+		}
+	})
+}
+
+func TestDiff_ExcludePatterns(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "main.go", "package main\n")
+	writeFile(t, dir, "api.pb.go", "package main\n")
+	writeFile(t, dir, "vendor/lib/lib.go", "package lib\n")
+	writeFile(t, dir, "deps.lock", "v1\n")
+	runGit(t, dir, "add", "-A")
+
+	out, err := git.Diff(context.Background(), dir, git.WithExcludePatterns("*.pb.go", "vendor/*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, out, "b/main.go")
+	assertNotContains(t, out, "api.pb.go", "vendor/lib", "deps.lock")
+
+	out, err = git.Diff(context.Background(), dir, git.WithIncludeDefaults(false), git.WithExcludePatterns("*.pb.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, out, "b/main.go", "b/deps.lock", "b/vendor/lib/lib.go")
+	assertNotContains(t, out, "api.pb.go")
+}
+
+func TestDiff_IgnoreFile(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, ".describe-commit-ignore", "# generated code\n*.pb.go\n\n  docs/*  \n")
+	writeFile(t, dir, "main.go", "package main\n")
+	writeFile(t, dir, "api.pb.go", "package main\n")
+	writeFile(t, dir, "docs/index.md", "# Docs\n")
+	writeFile(t, dir, "notes.md", "# Notes\n")
+	writeFile(t, dir, "deps.lock", "v1\n")
+	runGit(t, dir, "add", "-A")
+
+	out, err := git.Diff(context.Background(), dir, git.WithExcludePatterns("notes.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, out, "b/main.go", "b/.describe-commit-ignore")
+	assertNotContains(t, out, "api.pb.go", "docs/index.md", "notes.md", "deps.lock")
+
+	t.Run("untracked", func(t *testing.T) {
+		t.Parallel()
+
+		var dir = newRepo(t)
+
+		writeFile(t, dir, ".describe-commit-ignore", "*.pb.go\n")
+		writeFile(t, dir, "main.go", "package main\n")
+		writeFile(t, dir, "api.pb.go", "package main\n")
+
+		out, err := git.Diff(context.Background(), dir, git.WithIncludeUntracked())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertContains(t, out, "b/main.go")
+		assertNotContains(t, out, "api.pb.go")
+	})
+}
+
+func TestDiff_RenameDetection(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir     = newRepo(t)
+		content = strings.Repeat("the line of the moved file\n", 20)
+		copied  = strings.Repeat("the line of the copied file\n", 20)
+	)
+
+	writeFile(t, dir, "old/file.txt", content)
+	writeFile(t, dir, "orig.txt", copied)
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+	runGit(t, dir, "mv", "old", "new")
+	writeFile(t, dir, "new/file.txt", content+"one more line\n")
+	writeFile(t, dir, "orig.txt", copied+"changed\n")
+	writeFile(t, dir, "copy.txt", copied)
+	runGit(t, dir, "add", "-A")
+
+	for name, tc := range map[string]struct {
+		giveOpts    []git.DiffOption
+		wantContain []string
+		wantMissing []string
+	}{
+		"default": {
+			wantContain: []string{
+				"rename from old/file.txt\nrename to new/file.txt", "+one more line",
+				"copy from orig.txt\ncopy to copy.txt",
+			},
+			wantMissing: []string{"-the line of the moved file", "deleted file mode"},
+		},
+		"exact renames only": {
+			giveOpts:    []git.DiffOption{git.WithRenameDetection(100)},
+			wantContain: []string{"deleted file mode", "copy from orig.txt"}, // the copy is exact
+			wantMissing: []string{"rename from"},
+		},
+		"disabled": {
+			giveOpts:    []git.DiffOption{git.WithRenameDetection(0)},
+			wantContain: []string{"deleted file mode", "-the line of the moved file", "new file mode"},
+			wantMissing: []string{"rename from", "copy from"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			out, err := git.Diff(context.Background(), dir, tc.giveOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertContains(t, out, tc.wantContain...)
+			assertNotContains(t, out, tc.wantMissing...)
+		})
+	}
+}
+
+func TestDiff_ContextLines(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "file.txt", "one\ntwo\nthree\nfour\nfive\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+	writeFile(t, dir, "file.txt", "one\ntwo\nthree\nFOUR\nfive\n")
+	runGit(t, dir, "add", "-A")
+
+	out, err := git.Diff(context.Background(), dir, git.WithContextLines(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, out, "@@ -4 +4 @@", "-four\n+FOUR\n")
+	assertNotContains(t, out, "\n three", "\n five")
+
+	out, err = git.Diff(context.Background(), dir, git.WithContextLines(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, out, " three\n-four\n+FOUR\n five\n")
+	assertNotContains(t, out, "\n two")
+
+	if _, err = git.Diff(context.Background(), dir, git.WithContextLines(-1)); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestChangedPaths(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "main.go", "package main\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc staged() {}\n")
+	writeFile(t, dir, "internal/ai/query.go", "package ai\n")
+	writeFile(t, dir, "api/handler.go", "package api\n")
+	runGit(t, dir, "add", "-A")
+	writeFile(t, dir, "main.go", "package main\n\nfunc unstaged() {}\n")
+	writeFile(t, dir, "docs/untracked.md", "# Docs\n")
+
+	paths, err := git.ChangedPaths(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := strings.Join(paths, ","), "api/handler.go,internal/ai/query.go,main.go"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -1,48 +1,102 @@
 package git
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"strings"
 )
 
+// ErrUnknownRevision is returned when the revision (e.g. the commit hash) does not exist in the repository.
+var ErrUnknownRevision = errors.New("unknown revision")
+
 // Log returns the commit log of the repository limited to the specified number of commits.
 func Log(ctx context.Context, dirPath string, len int) (string, error) {
-	// ensure git is installed and available to run
-	gitFilePath, lookErr := binPath()
-	if lookErr != nil {
-		return "", lookErr
+	return run(ctx, dirPath, 1024*2, "log", //nolint:mnd // 2KB
+		"--format=%s",
+		fmt.Sprintf("--max-count=%d", len),
+		"--no-color",
+	)
+}
+
+// TopContributor returns the name of the author of the most (non-merge) commits reachable from HEAD, as reported by
+// `git shortlog -sn`. An empty string is returned for the repository without commits.
+func TopContributor(ctx context.Context, dirPath string) (string, error) {
+	if _, err := run(ctx, dirPath, 64, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil { //nolint:mnd
+		return "", nil // no commits yet
+	}
+
+	out, err := run(ctx, dirPath, 1024, "shortlog", "-sn", "--no-merges", "HEAD") //nolint:mnd
+	if err != nil {
+		return "", err
+	}
+
+	return parseShortlog(out), nil
+}
+
+// parseShortlog returns the author name from the first line of the `git shortlog -sn` output (`<count>\t<name>`).
+func parseShortlog(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if _, name, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok && strings.TrimSpace(name) != "" {
+			return strings.TrimSpace(name)
+		}
 	}
 
-	// get the log
-	var cmd = exec.CommandContext(ctx, gitFilePath, "log",
+	return ""
+}
+
+// AuthorLog returns the subjects of the recent (non-merge) commits of the author, limited to the specified number
+// of commits. The author name is matched as a fixed string, not as a pattern.
+func AuthorLog(ctx context.Context, dirPath, author string, len int) (string, error) {
+	return run(ctx, dirPath, 1024*2, "log", //nolint:mnd // 2KB
 		"--format=%s",
 		fmt.Sprintf("--max-count=%d", len),
+		"--author="+author,
+		"--fixed-strings",
+		"--no-merges",
 		"--no-color",
 	)
+}
 
-	cmd.Dir = dirPath
-	cmd.Env = []string{
-		"LC_ALL=C", "LANG=C", // forces the system to use the "C" (POSIX) locale, English-based output with no localization
-		"NO_COLOR=1",            // disables colored output
-		"GIT_CONFIG_NOSYSTEM=1", // do not use the system-wide configuration file
+// RecentCommits returns the full messages (the subjects and the bodies) of the recent commits, limited to the
+// specified number of commits, as printed by `git log --pretty=%B` (the messages are separated by blank lines). An
+// empty string is returned for the repository without commits, or if the number is not positive.
+func RecentCommits(ctx context.Context, dirPath string, n int) (string, error) {
+	if n <= 0 {
+		return "", nil
 	}
 
-	var stdOut, stdErr bytes.Buffer
+	if _, err := run(ctx, dirPath, 64, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil { //nolint:mnd
+		return "", nil // no commits yet
+	}
 
-	stdOut.Grow(1024 * 2) //nolint:mnd // 2KB
+	out, err := run(ctx, dirPath, 1024*4, "log", //nolint:mnd // 4KB
+		"--pretty=%B",
+		fmt.Sprintf("--max-count=%d", n),
+		"--no-color",
+	)
+	if err != nil {
+		return "", err
+	}
 
-	cmd.Stdout = &stdOut
-	cmd.Stderr = &stdErr
+	return strings.TrimSpace(out), nil
+}
 
-	if err := cmd.Run(); err != nil {
-		if stdErr.Len() > 0 {
-			err = fmt.Errorf("%s: %w", stdErrToString(stdErr.String()), err)
-		}
+// CommitSubject returns the subject (the first line of the message) of the commit. [ErrUnknownRevision] is returned
+// if the commit does not exist.
+func CommitSubject(ctx context.Context, dirPath, rev string) (string, error) {
+	if rev = strings.TrimSpace(rev); rev == "" || strings.HasPrefix(rev, "-") { // not to be taken as an option
+		return "", fmt.Errorf("%w: %q", ErrUnknownRevision, rev)
+	}
+
+	if _, err := run(ctx, dirPath, 64, "rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil { //nolint:mnd
+		return "", fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
+	}
 
-		return "", fmt.Errorf("git log failed: %w", err)
+	out, err := run(ctx, dirPath, 256, "log", "-1", "--format=%s", "--no-color", rev) //nolint:mnd
+	if err != nil {
+		return "", err
 	}
 
-	return stdOut.String(), nil
+	return strings.TrimSpace(out), nil
 }
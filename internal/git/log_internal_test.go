@@ -0,0 +1,19 @@
+package git
+
+import "testing"
+
+func TestParseShortlog(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]string{
+		"    42\tJane Doe\n     7\tJohn Smith\n": "Jane Doe",
+		"  3\tJosé da Silva  \n":                 "José da Silva",
+		"\n     1\t\n     1\tBot\n":              "Bot",
+		"":                                       "",
+		"garbage\n":                              "",
+	} {
+		if got := parseShortlog(give); got != want {
+			t.Errorf("parseShortlog(%q) = %q, want %q", give, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package git_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestTopContributor(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	// no commits yet
+	if name, err := git.TopContributor(context.Background(), dir); err != nil || name != "" {
+		t.Fatalf("unexpected result for the empty repository: %q, %v", name, err)
+	}
+
+	for i, c := range []struct{ author, subject string }{
+		{"Jane Doe <jane@example.com>", "feat(api): Add the endpoint"},
+		{"C++ Bot <bot@example.com>", "fix: Typo"},
+		{"Jane Doe <jane@example.com>", "fix(api): Handle the timeout"},
+		{"Jane Doe <jane@example.com>", "docs: Describe the endpoint"},
+	} {
+		writeFile(t, dir, "file.txt", string(rune('a'+i)))
+		runGit(t, dir, "add", "-A")
+		runGit(t, dir, "commit", "--quiet", "--author", c.author, "-m", c.subject)
+	}
+
+	name, err := git.TopContributor(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "Jane Doe" {
+		t.Fatalf("unexpected top contributor: %q", name)
+	}
+
+	log, err := git.AuthorLog(context.Background(), dir, "C++ Bot", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log != "fix: Typo\n" { // the pluses are not treated as a pattern
+		t.Errorf("unexpected author log: %q", log)
+	}
+
+	if log, err = git.AuthorLog(context.Background(), dir, name, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if log != "docs: Describe the endpoint\nfix(api): Handle the timeout\n" {
+		t.Errorf("unexpected author log: %q", log)
+	}
+}
+
+func TestCommitSubject(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "file.txt", "a")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "feat(api): Add the endpoint\n\nThe body")
+
+	var hash = strings.TrimSpace(runGit(t, dir, "rev-parse", "HEAD"))
+
+	for name, rev := range map[string]string{"full hash": hash, "short hash": hash[:7], "ref": "HEAD"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			subject, err := git.CommitSubject(context.Background(), dir, rev)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if subject != "feat(api): Add the endpoint" {
+				t.Fatalf("unexpected subject: %q", subject)
+			}
+		})
+	}
+
+	for name, rev := range map[string]string{
+		"unknown": "0123456789abcdef0123456789abcdef01234567",
+		"empty":   "",
+		"option":  "--all",
+		"tree":    hash + "^{tree}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := git.CommitSubject(context.Background(), dir, rev); !errors.Is(err, git.ErrUnknownRevision) {
+				t.Fatalf("expected the unknown revision error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRecentCommits(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	// no commits yet
+	if log, err := git.RecentCommits(context.Background(), dir, 5); err != nil || log != "" {
+		t.Fatalf("unexpected result for the empty repository: %q, %v", log, err)
+	}
+
+	for i, message := range []string{
+		"feat(api): Add the endpoint\n\n- Add the handler\n- Add the route",
+		"fix: Typo",
+		"docs: Describe the endpoint\n\nThe usage is described.",
+	} {
+		writeFile(t, dir, "file.txt", string(rune('a'+i)))
+		runGit(t, dir, "add", "-A")
+		runGit(t, dir, "commit", "--quiet", "-m", message)
+	}
+
+	log, err := git.RecentCommits(context.Background(), dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "docs: Describe the endpoint\n\nThe usage is described.\n\nfix: Typo"; log != want {
+		t.Fatalf("got %q, want %q", log, want)
+	}
+
+	if log, err = git.RecentCommits(context.Background(), dir, 0); err != nil || log != "" {
+		t.Fatalf("unexpected result for zero commits: %q, %v", log, err)
+	}
+}
@@ -0,0 +1,233 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileDiff is a part of the patch (unified diff) related to a single file.
+type FileDiff struct {
+	Path    string // the path of the file (the old one for deleted files)
+	OldPath string // the path before the change (differs from Path for renames and copies)
+	Text    string // the whole section, including the `diff --git` line
+}
+
+// Hunk is a single changed region of the file (as described by the `@@ ... @@` header).
+type Hunk struct {
+	OldStart, OldLines int // the region in the previous state of the file
+	NewStart, NewLines int // the region in the new state of the file
+}
+
+const diffBoundary = "diff --git "
+
+// hunkHeaderRegex matches the `@@ -<old-start>[,<old-len>] +<new-start>[,<new-len>] @@` hunk header.
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// SplitPatch splits the patch into per-file sections using the `diff --git` lines as boundaries. Anything before
+// the first boundary is ignored.
+func SplitPatch(patch string) []FileDiff {
+	var (
+		files []FileDiff
+		start = -1
+	)
+
+	var flush = func(end int) {
+		if start >= 0 {
+			files = append(files, newFileDiff(patch[start:end]))
+		}
+	}
+
+	for offset := 0; offset < len(patch); {
+		var lineEnd = strings.IndexByte(patch[offset:], '\n')
+		if lineEnd == -1 {
+			lineEnd = len(patch)
+		} else {
+			lineEnd += offset + 1
+		}
+
+		if strings.HasPrefix(patch[offset:], diffBoundary) {
+			flush(offset)
+
+			start = offset
+		}
+
+		offset = lineEnd
+	}
+
+	flush(len(patch))
+
+	return files
+}
+
+// Hunks parses the hunk headers of the section. The omitted lengths default to 1.
+func (fd FileDiff) Hunks() []Hunk {
+	var hunks []Hunk
+
+	for _, line := range strings.Split(fd.Text, "\n") {
+		var m = hunkHeaderRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		var h = Hunk{OldStart: atoi(m[1]), OldLines: 1, NewStart: atoi(m[3]), NewLines: 1}
+
+		if m[2] != "" {
+			h.OldLines = atoi(m[2])
+		}
+
+		if m[4] != "" {
+			h.NewLines = atoi(m[4])
+		}
+
+		hunks = append(hunks, h)
+	}
+
+	return hunks
+}
+
+// SplitHunks splits the section into the header (everything before the first hunk) and the hunks, each one
+// starting with its `@@` header line. Joined together, they form the original section.
+func (fd FileDiff) SplitHunks() (header string, hunks []string) {
+	var start = -1
+
+	for offset := 0; offset < len(fd.Text); {
+		var lineEnd = strings.IndexByte(fd.Text[offset:], '\n')
+		if lineEnd == -1 {
+			lineEnd = len(fd.Text)
+		} else {
+			lineEnd += offset + 1
+		}
+
+		if hunkHeaderRegex.MatchString(fd.Text[offset:lineEnd]) {
+			if start < 0 {
+				header = fd.Text[:offset]
+			} else {
+				hunks = append(hunks, fd.Text[start:offset])
+			}
+
+			start = offset
+		}
+
+		offset = lineEnd
+	}
+
+	if start < 0 {
+		return fd.Text, nil
+	}
+
+	return header, append(hunks, fd.Text[start:])
+}
+
+// newFileDiff parses the file paths from the section headers.
+func newFileDiff(text string) FileDiff {
+	var (
+		fd               = FileDiff{Text: text}
+		firstLine, _, _  = strings.Cut(text, "\n")
+		oldPath, newPath = parseDiffLine(strings.TrimPrefix(firstLine, diffBoundary))
+	)
+
+headers:
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			if p := unquotePath(strings.TrimPrefix(line, "--- ")); p != "/dev/null" {
+				oldPath = strings.TrimPrefix(p, "a/")
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if p := unquotePath(strings.TrimPrefix(line, "+++ ")); p != "/dev/null" {
+				newPath = strings.TrimPrefix(p, "b/")
+			} else {
+				newPath = "" // the file was deleted
+			}
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "copy from "):
+			_, p, _ := strings.Cut(line, " from ")
+			oldPath = unquotePath(p)
+		case strings.HasPrefix(line, "rename to "), strings.HasPrefix(line, "copy to "):
+			_, p, _ := strings.Cut(line, " to ")
+			newPath = unquotePath(p)
+		case strings.HasPrefix(line, "@@"):
+			break headers // the hunks begin, the headers are over
+		}
+	}
+
+	fd.OldPath, fd.Path = oldPath, newPath
+
+	if fd.Path == "" {
+		fd.Path = fd.OldPath
+	}
+
+	return fd
+}
+
+// parseDiffLine extracts the paths from the `a/<old> b/<new>` part of the `diff --git` line.
+func parseDiffLine(s string) (oldPath, newPath string) {
+	// quoted paths (with special characters)
+	if strings.HasPrefix(s, `"`) {
+		if q, err := strconv.QuotedPrefix(s); err == nil {
+			var rest = unquotePath(strings.TrimSpace(s[len(q):]))
+
+			return strings.TrimPrefix(unquotePath(q), "a/"), strings.TrimPrefix(rest, "b/")
+		}
+	}
+
+	// the most common case - both paths are the same: "a/<path> b/<path>"
+	if l := (len(s) - 5) / 2; l > 0 && strings.HasPrefix(s, "a/") { //nolint:mnd // len("a/") + len(" b/")
+		if oldPath, newPath = s[2:l+2], s[l+5:]; s[l+2:l+5] == " b/" && oldPath == newPath {
+			return oldPath, newPath
+		}
+	}
+
+	if i := strings.LastIndex(s, " b/"); i > 0 {
+		return strings.TrimPrefix(s[:i], "a/"), s[i+3:]
+	}
+
+	return s, s
+}
+
+// unquotePath removes the quotes git adds around the paths with special characters.
+func unquotePath(p string) string {
+	p = strings.TrimRight(p, "\t\r ")
+
+	if strings.HasPrefix(p, `"`) {
+		if u, err := strconv.Unquote(p); err == nil {
+			return u
+		}
+	}
+
+	return p
+}
+
+// ChangedFiles returns the paths of the files changed in the patch, in the order they appear.
+func ChangedFiles(patch string) []string {
+	var (
+		files = SplitPatch(patch)
+		paths = make([]string, 0, len(files))
+	)
+
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+
+	return paths
+}
+
+// FilterByPath keeps only the file sections of the already captured patch whose path (or the old path, for
+// renames) matches the regular expression. Unlike the git pathspecs, it works without a repository.
+func FilterByPath(patch, expr string) (string, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("wrong path filter: %w", err)
+	}
+
+	var b strings.Builder
+
+	for _, f := range SplitPatch(patch) {
+		if re.MatchString(f.Path) || (f.OldPath != "" && re.MatchString(f.OldPath)) {
+			b.WriteString(f.Text)
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,155 @@
+package git_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+const testPatch = `diff --git a/internal/api/server.go b/internal/api/server.go
+index 1111111..2222222 100644
+--- a/internal/api/server.go
++++ b/internal/api/server.go
+@@ -1,3 +1,4 @@
+ package api
++
++// Server serves the API.
+--- a/not/a/header
+diff --git a/README.md b/README.md
+index 3333333..4444444 100644
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-# Old
++# New
+diff --git a/internal/api/old.go b/internal/api/old.go
+deleted file mode 100644
+index 5555555..0000000
+--- a/internal/api/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package api
+diff --git a/cmd/app/main.go b/cmd/main.go
+similarity index 90%
+rename from cmd/app/main.go
+rename to cmd/main.go
+diff --git a/with space.txt b/with space.txt
+new file mode 100644
+index 0000000..6666666
+--- /dev/null
++++ b/with space.txt
+@@ -0,0 +1 @@
++hello
+`
+
+func TestChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	var want = []string{"internal/api/server.go", "README.md", "internal/api/old.go", "cmd/main.go", "with space.txt"}
+
+	if got := git.ChangedFiles(testPatch); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := git.ChangedFiles(""); len(got) != 0 {
+		t.Errorf("expected no files, got %v", got)
+	}
+}
+
+func TestSplitPatch_Rename(t *testing.T) {
+	t.Parallel()
+
+	var files = git.SplitPatch(testPatch)
+
+	if len(files) != 5 {
+		t.Fatalf("expected 5 files, got %d", len(files))
+	}
+
+	if files[3].OldPath != "cmd/app/main.go" || files[3].Path != "cmd/main.go" {
+		t.Errorf("unexpected rename paths: %q -> %q", files[3].OldPath, files[3].Path)
+	}
+}
+
+func TestFilterByPath(t *testing.T) {
+	t.Parallel()
+
+	got, err := git.FilterByPath(testPatch, `^internal/api/`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if files := git.ChangedFiles(got); !reflect.DeepEqual(files, []string{"internal/api/server.go", "internal/api/old.go"}) {
+		t.Errorf("unexpected files: %v", files)
+	}
+
+	assertContains(t, got, "+// Server serves the API.", "-package api")
+	assertNotContains(t, got, "README.md", "# New")
+
+	// the old path of the renamed file matches too
+	if got, _ = git.FilterByPath(testPatch, `^cmd/app/`); len(git.ChangedFiles(got)) != 1 {
+		t.Errorf("expected the renamed file to match, got %q", got)
+	}
+
+	if got, _ = git.FilterByPath(testPatch, `\.rs$`); got != "" {
+		t.Errorf("expected nothing to match, got %q", got)
+	}
+
+	if _, err = git.FilterByPath(testPatch, `(`); err == nil {
+		t.Error("expected an error for the broken expression")
+	}
+}
+
+func TestFileDiff_Hunks(t *testing.T) {
+	t.Parallel()
+
+	var files = git.SplitPatch(testPatch + "@@ -10,2 +11,0 @@\n-foo\n-bar\n")
+
+	for i, want := range [][]git.Hunk{
+		{{OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 4}},
+		{{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1}},
+		{{OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0}},
+		nil,
+	} {
+		if got := files[i].Hunks(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: want %+v, got %+v", files[i].Path, want, got)
+		}
+	}
+
+	var last = files[len(files)-1].Hunks()
+
+	if got := last[len(last)-1]; got != (git.Hunk{OldStart: 10, OldLines: 2, NewStart: 11}) {
+		t.Errorf("unexpected last hunk: %+v", got)
+	}
+}
+
+func TestFileDiff_SplitHunks(t *testing.T) {
+	t.Parallel()
+
+	const section = "diff --git a/foo.go b/foo.go\n--- a/foo.go\n+++ b/foo.go\n" +
+		"@@ -1,2 +1,2 @@\n-a\n+b\n c\n" +
+		"@@ -10 +10 @@ func main() {\n-d\n+e"
+
+	var fd = git.SplitPatch(section)[0]
+
+	header, hunks := fd.SplitHunks()
+
+	if want := "diff --git a/foo.go b/foo.go\n--- a/foo.go\n+++ b/foo.go\n"; header != want {
+		t.Errorf("want header %q, got %q", want, header)
+	}
+
+	if want := []string{"@@ -1,2 +1,2 @@\n-a\n+b\n c\n", "@@ -10 +10 @@ func main() {\n-d\n+e"}; !reflect.DeepEqual(hunks, want) {
+		t.Errorf("want hunks %q, got %q", want, hunks)
+	}
+
+	if header+strings.Join(hunks, "") != section {
+		t.Error("the parts do not form the original section")
+	}
+
+	header, hunks = git.SplitPatch("diff --git a/bin b/bin\nBinary files differ\n")[0].SplitHunks()
+
+	if header != "diff --git a/bin b/bin\nBinary files differ\n" || hunks != nil {
+		t.Errorf("unexpected split of the section without hunks: %q, %q", header, hunks)
+	}
+}
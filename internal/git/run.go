@@ -0,0 +1,50 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// run executes git with the given arguments in the specified directory and returns its standard output. The
+// failed command name (e.g. "diff") is used in the error message.
+func run(ctx context.Context, dirPath string, outSize int, args ...string) (string, error) {
+	// ensure git is installed and available to run
+	gitFilePath, lookErr := binPath()
+	if lookErr != nil {
+		return "", lookErr
+	}
+
+	var cmd = exec.CommandContext(ctx, gitFilePath, args...)
+
+	cmd.Dir = dirPath
+	cmd.Env = []string{
+		"LC_ALL=C", "LANG=C", // forces the system to use the "C" (POSIX) locale, English-based output with no localization
+		"NO_COLOR=1",            // disables colored output
+		"GIT_CONFIG_NOSYSTEM=1", // do not use the system-wide configuration file
+	}
+
+	var stdOut, stdErr bytes.Buffer
+
+	stdOut.Grow(outSize)
+
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+
+	if err := cmd.Run(); err != nil {
+		if stdErr.Len() > 0 {
+			err = fmt.Errorf("%s: %w", stdErrToString(stdErr.String()), err)
+		}
+
+		var name = "command"
+
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		return "", fmt.Errorf("git %s failed: %w", name, err)
+	}
+
+	return stdOut.String(), nil
+}
@@ -0,0 +1,64 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrStashNotFound is returned when the requested stash entry does not exist.
+var ErrStashNotFound = errors.New("stash entry not found")
+
+// Stash returns the diff of the stash entry with the given index (the same as `git stash show -p stash@{index}`
+// does, but the common diff flags are applied). The paths are excluded, and the renames are detected the same way
+// as by the [Diff] (including the `.describe-commit-ignore` file); the options including more changes (e.g.
+// [WithIncludeUnstaged]) are ignored.
+func Stash(ctx context.Context, dirPath string, index int, opts ...DiffOption) (string, error) {
+	if index < 0 {
+		return "", fmt.Errorf("wrong stash index: %d", index)
+	}
+
+	o, err := newDiffOptions(dirPath, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var ref = fmt.Sprintf("stash@{%d}", index)
+
+	// validate the stash entry existence
+	entries, err := stashEntries(ctx, dirPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify the stash entry %s: %w", ref, err)
+	}
+
+	if index >= entries {
+		return "", fmt.Errorf("%w: %s", ErrStashNotFound, ref)
+	}
+
+	// `git stash show` does not accept pathspecs, so the stash is compared with its first parent directly (this
+	// is exactly what `git stash show -p` does)
+	return runDiff(ctx, dirPath, o.excludes(), append(o.flags(), ref+"^1", ref)...)
+}
+
+// stashEntries returns the number of the stash entries (zero if there is no stash).
+func stashEntries(ctx context.Context, dirPath string) (int, error) {
+	// with `--quiet`, git exits with 1 if the stash ref does not exist, and fails with another code if, for example,
+	// the directory is not a repository
+	if _, err := run(ctx, dirPath, 64, "rev-parse", "--verify", "--quiet", "refs/stash"); err != nil { //nolint:mnd
+		if exitErr := (*exec.ExitError)(nil); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	out, err := run(ctx, dirPath, 16, "rev-list", "--walk-reflogs", "--count", "refs/stash") //nolint:mnd
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(out))
+}
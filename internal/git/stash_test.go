@@ -0,0 +1,134 @@
+package git_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestStash(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "main.go", "package main\n")
+	writeFile(t, dir, "deps.lock", "v1\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc first() {}\n")
+	writeFile(t, dir, "deps.lock", "v2\n")
+	runGit(t, dir, "stash", "--quiet")
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc second() {}\n")
+	runGit(t, dir, "stash", "--quiet")
+
+	// stash@{0} is the latest one
+	latest, err := git.Stash(context.Background(), dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, latest, "diff --git a/main.go b/main.go", "+func second() {}")
+	assertNotContains(t, latest, "first")
+
+	older, err := git.Stash(context.Background(), dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, older, "+func first() {}")
+	assertNotContains(t, older, "deps.lock") // excluded by default
+
+	if _, err = git.Stash(context.Background(), dir, 2); !errors.Is(err, git.ErrStashNotFound) {
+		t.Errorf("expected ErrStashNotFound, got %v", err)
+	}
+
+	if _, err = git.Stash(context.Background(), dir, -1); err == nil {
+		t.Error("expected an error for the negative index")
+	}
+}
+
+func TestStash_Errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not a repository", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := git.Stash(context.Background(), t.TempDir(), 0)
+		if err == nil || errors.Is(err, git.ErrStashNotFound) {
+			t.Errorf("expected the git error, got %v", err)
+		}
+	})
+
+	t.Run("no stash", func(t *testing.T) {
+		t.Parallel()
+
+		var dir = newRepo(t)
+
+		writeFile(t, dir, "main.go", "package main\n")
+		runGit(t, dir, "add", "-A")
+		runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+		if _, err := git.Stash(context.Background(), dir, 0); !errors.Is(err, git.ErrStashNotFound) {
+			t.Errorf("expected ErrStashNotFound, got %v", err)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		var dir = newRepo(t)
+
+		writeFile(t, dir, "main.go", "package main\n")
+		runGit(t, dir, "add", "-A")
+		runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := git.Stash(ctx, dir, 0)
+		if !errors.Is(err, context.Canceled) || errors.Is(err, git.ErrStashNotFound) {
+			t.Errorf("expected the cancellation error, got %v", err)
+		}
+	})
+}
+
+func TestStash_Options(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "main.go", "package main\n")
+	writeFile(t, dir, "api.pb.go", "package main\n")
+	writeFile(t, dir, "old.txt", "some long enough content\nto be detected as renamed\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+
+	writeFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, dir, "api.pb.go", "package main\n\nvar generated = true\n")
+	runGit(t, dir, "mv", "old.txt", "new.txt")
+	runGit(t, dir, "stash", "--quiet")
+
+	writeFile(t, dir, ".describe-commit-ignore", "*.pb.go\n")
+
+	stash, err := git.Stash(context.Background(), dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, stash, "+func main() {}", "rename from old.txt")
+	assertNotContains(t, stash, "api.pb.go") // excluded by the ignore file
+
+	stash, err = git.Stash(context.Background(), dir, 0,
+		git.WithExcludePatterns("main.go"), git.WithRenameDetection(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContains(t, stash, "deleted file mode", "new file mode")
+	assertNotContains(t, stash, "main.go", "api.pb.go", "rename from")
+}
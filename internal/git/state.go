@@ -0,0 +1,133 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrNotRepository is returned when the directory is not inside a git repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// State is the state of the repository. If an operation is in progress, the staged changes are (usually) not
+// written by the user, so describing them as a regular commit is misleading.
+type State int
+
+const (
+	StateClean         State = iota // no operation is in progress
+	StateMerging                    // a merge is in progress (`MERGE_HEAD` exists)
+	StateRebasing                   // a rebase is in progress (`REBASE_HEAD` or the rebase directory exists)
+	StateCherryPicking              // a cherry-pick is in progress (`CHERRY_PICK_HEAD` exists)
+)
+
+// String returns the human-readable state name.
+func (s State) String() string {
+	switch s {
+	case StateClean:
+		return "clean"
+	case StateMerging:
+		return "merging"
+	case StateRebasing:
+		return "rebasing"
+	case StateCherryPicking:
+		return "cherry-picking"
+	}
+
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// RepoState detects the operation in progress in the repository containing the directory. The git binary is not
+// required, the state files are checked directly. The rebase takes precedence, since it may merge or cherry-pick
+// the commits itself.
+func RepoState(dirPath string) (State, error) {
+	gitDir, err := findGitDir(dirPath)
+	if err != nil {
+		return StateClean, err
+	}
+
+	switch {
+	case exists(gitDir, "REBASE_HEAD"), exists(gitDir, "rebase-merge"), exists(gitDir, "rebase-apply"):
+		return StateRebasing, nil
+	case exists(gitDir, "CHERRY_PICK_HEAD"):
+		return StateCherryPicking, nil
+	case exists(gitDir, "MERGE_HEAD"):
+		return StateMerging, nil
+	}
+
+	return StateClean, nil
+}
+
+// MergeMessage returns the default message git prepared for the merge (or the conflicted cherry-pick) in progress,
+// without the comment lines. An empty string is returned if there is no such message.
+func MergeMessage(dirPath string) (string, error) {
+	gitDir, err := findGitDir(dirPath)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(gitDir, "MERGE_MSG"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	var lines = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	lines = slices.DeleteFunc(lines, func(l string) bool { return strings.HasPrefix(l, "#") })
+
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// findGitDir returns the git directory of the repository containing the directory, looking up the parent
+// directories. The `.git` file (used by the worktrees and submodules) pointing to the git directory is supported.
+func findGitDir(dirPath string) (string, error) {
+	dir, err := filepath.Abs(dirPath)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		var dotGit = filepath.Join(dir, ".git")
+
+		if info, sErr := os.Stat(dotGit); sErr == nil {
+			if info.IsDir() {
+				return dotGit, nil
+			}
+
+			data, rErr := os.ReadFile(dotGit)
+			if rErr != nil {
+				return "", rErr
+			}
+
+			if gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:"); ok {
+				if gitDir = strings.TrimSpace(gitDir); !filepath.IsAbs(gitDir) {
+					gitDir = filepath.Join(dir, gitDir)
+				}
+
+				return gitDir, nil
+			}
+
+			return "", fmt.Errorf("%w: malformed %s", ErrNotRepository, dotGit)
+		}
+
+		var parent = filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%w: %s", ErrNotRepository, dirPath)
+		}
+
+		dir = parent
+	}
+}
+
+// exists reports whether the file (or directory) exists in the directory.
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+
+	return err == nil
+}
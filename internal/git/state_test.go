@@ -0,0 +1,123 @@
+package git_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestRepoState(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveFiles []string // created in the git directory
+		want      git.State
+	}{
+		"clean":                {want: git.StateClean},
+		"merging":              {giveFiles: []string{"MERGE_HEAD"}, want: git.StateMerging},
+		"rebasing":             {giveFiles: []string{"REBASE_HEAD"}, want: git.StateRebasing},
+		"rebasing (directory)": {giveFiles: []string{"rebase-merge/head-name"}, want: git.StateRebasing},
+		"cherry-picking":       {giveFiles: []string{"CHERRY_PICK_HEAD"}, want: git.StateCherryPicking},
+		"merge while rebasing": {giveFiles: []string{"MERGE_HEAD", "REBASE_HEAD"}, want: git.StateRebasing},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var dir = newRepo(t)
+
+			for _, f := range tc.giveFiles {
+				writeFile(t, dir, ".git/"+f, "0123456789abcdef0123456789abcdef01234567\n")
+			}
+
+			writeFile(t, dir, "sub/dir/file.txt", "")
+
+			for _, d := range []string{dir, filepath.Join(dir, "sub", "dir")} {
+				got, err := git.RepoState(d)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got != tc.want {
+					t.Errorf("want %s, got %s", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRepoState_Merge(t *testing.T) {
+	t.Parallel()
+
+	var dir = newRepo(t)
+
+	writeFile(t, dir, "main.go", "package main\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "init")
+	runGit(t, dir, "checkout", "--quiet", "-b", "feature")
+	writeFile(t, dir, "feature.go", "package main\n")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "--quiet", "-m", "feature")
+	runGit(t, dir, "checkout", "--quiet", "-")
+	runGit(t, dir, "merge", "--quiet", "--no-ff", "--no-commit", "feature")
+
+	state, err := git.RepoState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state != git.StateMerging {
+		t.Errorf("want %s, got %s", git.StateMerging, state)
+	}
+
+	msg, err := git.MergeMessage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg != "Merge branch 'feature'" {
+		t.Errorf("unexpected merge message: %q", msg)
+	}
+}
+
+func TestRepoState_Worktree(t *testing.T) {
+	t.Parallel()
+
+	var (
+		gitDir  = t.TempDir()
+		workDir = t.TempDir()
+	)
+
+	writeFile(t, gitDir, "CHERRY_PICK_HEAD", "")
+	writeFile(t, gitDir, "MERGE_MSG", "Fix the bug\n\n# Conflicts:\n#\tmain.go\n")
+	writeFile(t, workDir, ".git", "gitdir: "+gitDir+"\n")
+
+	state, err := git.RepoState(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state != git.StateCherryPicking {
+		t.Errorf("want %s, got %s", git.StateCherryPicking, state)
+	}
+
+	if msg, _ := git.MergeMessage(workDir); msg != "Fix the bug" {
+		t.Errorf("unexpected merge message: %q", msg)
+	}
+}
+
+func TestRepoState_NotRepository(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), ".git")); err == nil {
+		t.Skip("the temporary directory is inside a git repository")
+	}
+
+	if _, err := git.RepoState(dir); !errors.Is(err, git.ErrNotRepository) {
+		t.Errorf("expected ErrNotRepository, got %v", err)
+	}
+}
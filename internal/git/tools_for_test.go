@@ -0,0 +1,80 @@
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRepo initializes a new git repository in a temporary directory and returns its path.
+func newRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	var dir = t.TempDir()
+
+	runGit(t, dir, "init", "--quiet")
+
+	return dir
+}
+
+// runGit runs git with the given arguments in the specified directory and returns its output.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	var cmd = exec.Command("git", append([]string{
+		"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false",
+	}, args...)...)
+
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, out)
+	}
+
+	return string(out)
+}
+
+// writeFile writes the content to the file (relative to the dir), creating the parent directories if needed.
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	var path = filepath.Join(dir, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertContains checks if the given string contains all the expected substrings.
+func assertContains(t *testing.T, got string, want ...string) {
+	t.Helper()
+
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("expected %q to contain %q", got, w)
+		}
+	}
+}
+
+// assertNotContains checks if the given string does not contain any of the substrings.
+func assertNotContains(t *testing.T, got string, want ...string) {
+	t.Helper()
+
+	for _, w := range want {
+		if strings.Contains(got, w) {
+			t.Errorf("expected %q to not contain %q", got, w)
+		}
+	}
+}
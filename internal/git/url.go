@@ -0,0 +1,356 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxRemoteDiffSize = 32 << 20 // limits the size of the diff (or a single page of it) fetched by [FromURL]
+	remotePerPage     = 100      // the number of the files requested per page (the maximum for GitHub and GitLab)
+	maxRemotePages    = 100      // limits the number of the fetched pages
+	maxRedirects      = 10       // the same as the default limit of the [http.Client]
+)
+
+var (
+	// githubAPIRegex matches the paths of the GitHub (or GitHub Enterprise) pull request and compare API endpoints.
+	githubAPIRegex = regexp.MustCompile(`/repos/[^/]+/[^/]+/(pulls/\d+|compare/[^/]+)/?$`)
+
+	// githubPullRegex matches the path of the GitHub pull request API endpoint.
+	githubPullRegex = regexp.MustCompile(`/repos/[^/]+/[^/]+/pulls/\d+/?$`)
+
+	// gitlabAPIRegex matches the paths of the GitLab merge request diffs and compare API endpoints.
+	gitlabAPIRegex = regexp.MustCompile(`/api/v4/projects/[^/]+/(merge_requests/\d+/diffs|repository/compare)/?$`)
+
+	// nextLinkRegex extracts the next page URL from the `Link` response header.
+	nextLinkRegex = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+)
+
+// FromURL fetches the unified diff from the GitHub or GitLab compare or pull (merge) request URL, so the commit
+// message can be generated without a local clone. The token (optional) is used for the authentication. The
+// following URLs are supported:
+//
+//   - GitHub API: `https://api.github.com/repos/{owner}/{repo}/pulls/{number}` and
+//     `https://api.github.com/repos/{owner}/{repo}/compare/{base}...{head}`. The diff media type is requested; if
+//     the pull request diff is too large to be rendered by GitHub, the changed files are fetched page by page
+//     instead.
+//   - GitLab API: `https://gitlab.com/api/v4/projects/{id}/merge_requests/{iid}/diffs` (fetched page by page) and
+//     `https://gitlab.com/api/v4/projects/{id}/repository/compare?from={base}&to={head}`. The JSON responses are
+//     converted to the unified diff.
+//   - Any other URL returning the raw diff (e.g. `https://github.com/{owner}/{repo}/pull/{number}.diff`).
+//
+// The token is sent to the scheme and host of the given URL only (not to the next pages or redirects elsewhere).
+func FromURL(ctx context.Context, diffURL, token string) (string, error) {
+	u, err := url.Parse(diffURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid diff URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid diff URL: unsupported scheme %q", u.Scheme)
+	}
+
+	var r = &remote{origin: u, header: make(http.Header)}
+
+	r.client = &http.Client{Timeout: 60 * time.Second, CheckRedirect: r.checkRedirect} //nolint:mnd
+
+	switch {
+	case gitlabAPIRegex.MatchString(u.Path):
+		r.setGitLabAuth(token)
+
+		return r.gitlabDiff(ctx, u)
+
+	case githubAPIRegex.MatchString(u.Path):
+		r.setGitHubAuth(token)
+
+		return r.githubDiff(ctx, u)
+	}
+
+	if strings.Contains(u.Hostname(), "gitlab") {
+		r.setGitLabAuth(token)
+	} else {
+		r.setGitHubAuth(token)
+	}
+
+	body, _, err := r.get(ctx, u.String(), "")
+
+	return body, err
+}
+
+// remote fetches the diff from the remote API.
+type remote struct {
+	client *http.Client
+	origin *url.URL    // the URL the diff is fetched from
+	header http.Header // the authentication headers, added to the requests to the origin only
+}
+
+// sameOrigin reports whether the URL has the same scheme and host (including the port) as the origin.
+func (r *remote) sameOrigin(u *url.URL) bool {
+	return strings.EqualFold(u.Scheme, r.origin.Scheme) && strings.EqualFold(u.Host, r.origin.Host)
+}
+
+// checkRedirect drops the authentication headers on the redirects to another origin (the [http.Client] drops the
+// `Authorization` header for another domain only, while the `PRIVATE-TOKEN` one is always kept).
+func (r *remote) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	if !r.sameOrigin(req.URL) {
+		for k := range r.header {
+			req.Header.Del(k)
+		}
+	}
+
+	return nil
+}
+
+func (r *remote) setGitHubAuth(token string) {
+	if token != "" {
+		r.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (r *remote) setGitLabAuth(token string) {
+	if token != "" {
+		r.header.Set("PRIVATE-TOKEN", token)
+	}
+}
+
+// remoteStatusError is returned when the remote API responds with an unexpected status code.
+type remoteStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *remoteStatusError) Error() string {
+	return fmt.Sprintf("failed to fetch %s: unexpected status code %d (%s)",
+		e.URL, e.StatusCode, http.StatusText(e.StatusCode),
+	)
+}
+
+// get performs the GET request (with the given `Accept` header, if not empty) and returns the response body and
+// headers.
+func (r *remote) get(ctx context.Context, u, accept string) (string, http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if r.sameOrigin(req.URL) {
+		for k, v := range r.header {
+			req.Header[k] = v
+		}
+	}
+
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxRemoteDiffSize))
+
+		return "", nil, &remoteStatusError{URL: u, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteDiffSize+1))
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(body) > maxRemoteDiffSize {
+		return "", nil, fmt.Errorf("failed to fetch %s: the response is too large", u)
+	}
+
+	return string(body), resp.Header, nil
+}
+
+// githubDiff fetches the diff using the GitHub API. The pull request diff too large to be rendered is built from
+// the changed files instead.
+func (r *remote) githubDiff(ctx context.Context, u *url.URL) (string, error) {
+	body, _, err := r.get(ctx, u.String(), "application/vnd.github.diff")
+	if err == nil {
+		return body, nil
+	}
+
+	if !githubPullRegex.MatchString(u.Path) || !isDiffTooLarge(err) {
+		return "", err
+	}
+
+	var (
+		b    strings.Builder
+		next = u.JoinPath("files")
+	)
+
+	next.RawQuery = url.Values{"per_page": {strconv.Itoa(remotePerPage)}}.Encode()
+
+	for page, pageURL := 0, next.String(); pageURL != ""; page++ {
+		if page >= maxRemotePages {
+			return "", fmt.Errorf("failed to fetch the pull request files: more than %d pages", maxRemotePages)
+		}
+
+		body, header, gErr := r.get(ctx, pageURL, "application/vnd.github+json")
+		if gErr != nil {
+			return "", gErr
+		}
+
+		var files []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+			Status           string `json:"status"`
+			Patch            string `json:"patch"`
+		}
+
+		if dErr := json.Unmarshal([]byte(body), &files); dErr != nil {
+			return "", fmt.Errorf("failed to decode the pull request files: %w", dErr)
+		}
+
+		for _, f := range files {
+			var oldPath = f.Filename
+
+			if f.PreviousFilename != "" {
+				oldPath = f.PreviousFilename
+			}
+
+			b.WriteString(fileDiff(oldPath, f.Filename, f.Status == "added", f.Status == "removed", f.Patch))
+		}
+
+		pageURL = ""
+
+		if m := nextLinkRegex.FindStringSubmatch(header.Get("Link")); m != nil {
+			pageURL = m[1]
+		}
+	}
+
+	return b.String(), nil
+}
+
+// isDiffTooLarge reports whether GitHub refused to render the diff because it's too large.
+func isDiffTooLarge(err error) bool {
+	var sErr *remoteStatusError
+
+	return errors.As(err, &sErr) &&
+		(sErr.StatusCode == http.StatusNotAcceptable || sErr.StatusCode == http.StatusUnprocessableEntity)
+}
+
+// gitlabDiff fetches the diff using the GitLab API. The merge request diffs are fetched page by page.
+func (r *remote) gitlabDiff(ctx context.Context, u *url.URL) (string, error) {
+	type gitlabDiff struct {
+		OldPath     string `json:"old_path"`
+		NewPath     string `json:"new_path"`
+		Diff        string `json:"diff"`
+		NewFile     bool   `json:"new_file"`
+		DeletedFile bool   `json:"deleted_file"`
+	}
+
+	var (
+		b     strings.Builder
+		query = u.Query()
+	)
+
+	if strings.HasSuffix(strings.TrimSuffix(u.Path, "/"), "/diffs") {
+		query.Set("per_page", strconv.Itoa(remotePerPage))
+	}
+
+	for page := 1; ; page++ {
+		if page > maxRemotePages {
+			return "", fmt.Errorf("failed to fetch the merge request diffs: more than %d pages", maxRemotePages)
+		}
+
+		if query.Has("per_page") {
+			query.Set("page", strconv.Itoa(page))
+		}
+
+		var pageURL = *u
+
+		pageURL.RawQuery = query.Encode()
+
+		body, header, err := r.get(ctx, pageURL.String(), "")
+		if err != nil {
+			return "", err
+		}
+
+		var diffs []gitlabDiff
+
+		if trimmed := strings.TrimSpace(body); strings.HasPrefix(trimmed, "[") { // merge request diffs
+			err = json.Unmarshal([]byte(trimmed), &diffs)
+		} else { // compare
+			var compare struct {
+				Diffs []gitlabDiff `json:"diffs"`
+			}
+
+			err = json.Unmarshal([]byte(trimmed), &compare)
+			diffs = compare.Diffs
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to decode the diffs: %w", err)
+		}
+
+		for _, d := range diffs {
+			b.WriteString(fileDiff(d.OldPath, d.NewPath, d.NewFile, d.DeletedFile, d.Diff))
+		}
+
+		if header.Get("X-Next-Page") == "" || !query.Has("per_page") {
+			break
+		}
+	}
+
+	return b.String(), nil
+}
+
+// fileDiff formats the diff of a single file (the patch contains the hunks only) in the `git diff` format.
+func fileDiff(oldPath, newPath string, added, deleted bool, patch string) string {
+	var b strings.Builder
+
+	_, _ = fmt.Fprintf(&b, "diff --git a/%s b/%s\n", oldPath, newPath)
+
+	switch {
+	case added:
+		b.WriteString("new file mode 100644\n")
+	case deleted:
+		b.WriteString("deleted file mode 100644\n")
+	case oldPath != newPath:
+		_, _ = fmt.Fprintf(&b, "rename from %s\nrename to %s\n", oldPath, newPath)
+	}
+
+	if patch == "" { // binary, too large, or renamed without changes
+		return b.String()
+	}
+
+	if added {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		_, _ = fmt.Fprintf(&b, "--- a/%s\n", oldPath)
+	}
+
+	if deleted {
+		b.WriteString("+++ /dev/null\n")
+	} else {
+		_, _ = fmt.Fprintf(&b, "+++ b/%s\n", newPath)
+	}
+
+	b.WriteString(patch)
+
+	if !strings.HasSuffix(patch, "\n") {
+		b.WriteRune('\n')
+	}
+
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package git_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestFromURL(t *testing.T) {
+	t.Parallel()
+
+	const rawDiff = "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n"
+
+	var mux = http.NewServeMux()
+
+	mux.HandleFunc("/repos/o/r/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/vnd.github.diff" || r.Header.Get("Authorization") != "Bearer gh" {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		_, _ = fmt.Fprint(w, rawDiff)
+	})
+
+	mux.HandleFunc("/repos/o/r/pulls/2", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotAcceptable) // the diff is too large
+	})
+
+	mux.HandleFunc("/repos/o/r/pulls/2/files", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") != "100" || r.Header.Get("Authorization") != "Bearer gh" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(
+				`<http://%[1]s/repos/o/r/pulls/2/files?per_page=100&page=2>; rel="next", `+
+					`<http://%[1]s/repos/o/r/pulls/2/files?per_page=100&page=2>; rel="last"`, r.Host,
+			))
+			_, _ = fmt.Fprint(w, `[{"filename":"main.go","status":"modified","patch":"@@ -1 +1 @@\n-a\n+b"},`+
+				`{"filename":"new.go","status":"added","patch":"@@ -0,0 +1 @@\n+c"}]`)
+		case "2":
+			_, _ = fmt.Fprint(w, `[{"filename":"b.txt","previous_filename":"a.txt","status":"renamed"},`+
+				`{"filename":"logo.png","status":"removed"}]`)
+		}
+	})
+
+	mux.HandleFunc("/api/v4/projects/1/merge_requests/3/diffs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "gl" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			_, _ = fmt.Fprint(w, `[{"old_path":"main.go","new_path":"main.go","diff":"@@ -1 +1 @@\n-a\n+b\n"}]`)
+		case "2":
+			w.Header().Set("X-Next-Page", "")
+			_, _ = fmt.Fprint(w, `[{"old_path":"old.go","new_path":"old.go","deleted_file":true,`+
+				`"diff":"@@ -1 +0,0 @@\n-x\n"}]`)
+		}
+	})
+
+	mux.HandleFunc("/api/v4/projects/1/repository/compare", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("from") != "main" || r.URL.Query().Get("to") != "dev" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		_, _ = fmt.Fprint(w, `{"commits":[],"diffs":[{"old_path":"main.go","new_path":"main.go",`+
+			`"diff":"@@ -1 +1 @@\n-a\n+b\n"}]}`)
+	})
+
+	mux.HandleFunc("/o/r/pull/1.diff", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = fmt.Fprint(w, rawDiff)
+	})
+
+	var srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	for name, tc := range map[string]struct {
+		givePath  string
+		giveToken string
+		want      string
+		wantErr   bool
+	}{
+		"github pull request": {givePath: "/repos/o/r/pulls/1", giveToken: "gh", want: rawDiff},
+		"github large pull request": {
+			givePath: "/repos/o/r/pulls/2", giveToken: "gh",
+			want: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+				"diff --git a/new.go b/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/new.go\n@@ -0,0 +1 @@\n+c\n" +
+				"diff --git a/a.txt b/b.txt\nrename from a.txt\nrename to b.txt\n" +
+				"diff --git a/logo.png b/logo.png\ndeleted file mode 100644\n",
+		},
+		"github unauthorized": {givePath: "/repos/o/r/pulls/1", wantErr: true},
+		"gitlab merge request": {
+			givePath: "/api/v4/projects/1/merge_requests/3/diffs", giveToken: "gl",
+			want: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n" +
+				"diff --git a/old.go b/old.go\ndeleted file mode 100644\n--- a/old.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n",
+		},
+		"gitlab compare": {
+			givePath: "/api/v4/projects/1/repository/compare?from=main&to=dev",
+			want:     "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		"raw diff":  {givePath: "/o/r/pull/1.diff", giveToken: "gh", want: rawDiff},
+		"not found": {givePath: "/unknown", wantErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := git.FromURL(context.Background(), srv.URL+tc.givePath, tc.giveToken)
+
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("invalid scheme", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := git.FromURL(context.Background(), "file:///etc/passwd", ""); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestFromURL_ForeignHost(t *testing.T) {
+	t.Parallel()
+
+	var foreign = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("PRIVATE-TOKEN") != "" {
+			w.WriteHeader(http.StatusBadRequest) // the token is leaked
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/files":
+			_, _ = fmt.Fprint(w, `[{"filename":"b.go","status":"added","patch":"@@ -0,0 +1 @@\n+b"}]`)
+		case "/compare":
+			_, _ = fmt.Fprint(w, `{"diffs":[{"old_path":"c.go","new_path":"c.go","diff":"@@ -1 +1 @@\n-c\n+d\n"}]}`)
+		}
+	}))
+	t.Cleanup(foreign.Close)
+
+	var mux = http.NewServeMux()
+
+	mux.HandleFunc("/repos/o/r/pulls/2", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotAcceptable) // the diff is too large
+	})
+
+	mux.HandleFunc("/repos/o/r/pulls/2/files", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		w.Header().Set("Link", fmt.Sprintf(`<%s/files>; rel="next"`, foreign.URL))
+		_, _ = fmt.Fprint(w, `[{"filename":"a.go","status":"added","patch":"@@ -0,0 +1 @@\n+a"}]`)
+	})
+
+	mux.HandleFunc("/api/v4/projects/1/repository/compare", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, foreign.URL+"/compare", http.StatusFound)
+	})
+
+	var srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	t.Run("next page", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := git.FromURL(context.Background(), srv.URL+"/repos/o/r/pulls/2", "gh")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		const want = "diff --git a/a.go b/a.go\nnew file mode 100644\n--- /dev/null\n+++ b/a.go\n@@ -0,0 +1 @@\n+a\n" +
+			"diff --git a/b.go b/b.go\nnew file mode 100644\n--- /dev/null\n+++ b/b.go\n@@ -0,0 +1 @@\n+b\n"
+
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := git.FromURL(context.Background(), srv.URL+"/api/v4/projects/1/repository/compare", "gl")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		const want = "diff --git a/c.go b/c.go\n--- a/c.go\n+++ b/c.go\n@@ -1 +1 @@\n-c\n+d\n"
+
+		if got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// ErrGitTooOld is returned when the installed git version lacks the features required by this package.
+var ErrGitTooOld = errors.New("git is too old")
+
+// Version is the git version (e.g. 2.39.5).
+type Version struct{ Major, Minor, Patch int }
+
+// the versions that introduced the features used by this package
+var (
+	minGitVersion           = Version{1, 7, 2} //nolint:gochecknoglobals,mnd // --ignore-submodules=all
+	diffAlgorithmVersion    = Version{1, 8, 2} //nolint:gochecknoglobals,mnd // --diff-algorithm=<algorithm>
+	ignoreBlankLinesVersion = Version{1, 8, 4} //nolint:gochecknoglobals,mnd // --ignore-blank-lines
+	excludeMagicVersion     = Version{1, 9, 0} //nolint:gochecknoglobals,mnd // :(exclude)<pattern>
+)
+
+// versionRegex matches the version in the `git --version` output (e.g. "git version 2.39.5.windows.1" or "git
+// version 2.37.1 (Apple Git-137.1)").
+var versionRegex = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// cachedVersion is the version of the installed git (it's not expected to change while the process is running).
+var cachedVersion struct { //nolint:gochecknoglobals
+	sync.Mutex
+	v *Version
+}
+
+// String returns the version in the `<major>.<minor>.<patch>` format.
+func (v Version) String() string { return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch) }
+
+// AtLeast reports whether the version is equal to or newer than the given one.
+func (v Version) AtLeast(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major > o.Major
+	}
+
+	if v.Minor != o.Minor {
+		return v.Minor > o.Minor
+	}
+
+	return v.Patch >= o.Patch
+}
+
+// ParseVersion parses the `git --version` output.
+func ParseVersion(s string) (Version, error) {
+	var m = versionRegex.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("unexpected git version format: %q", s)
+	}
+
+	return Version{Major: atoi(m[1]), Minor: atoi(m[2]), Patch: atoi(m[3])}, nil
+}
+
+// GitVersion returns the version of the installed git. The result is cached.
+func GitVersion(ctx context.Context) (Version, error) {
+	cachedVersion.Lock()
+	defer cachedVersion.Unlock()
+
+	if cachedVersion.v != nil {
+		return *cachedVersion.v, nil
+	}
+
+	out, err := run(ctx, "", 64, "--version") //nolint:mnd
+	if err != nil {
+		return Version{}, err
+	}
+
+	v, err := ParseVersion(out)
+	if err != nil {
+		return Version{}, err
+	}
+
+	cachedVersion.v = &v
+
+	return v, nil
+}
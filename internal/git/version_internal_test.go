@@ -0,0 +1,45 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffFlags_Degrade(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		give        Version
+		wantFlags   []string
+		unsupported []string
+	}{
+		{
+			give:      Version{2, 39, 5},
+			wantFlags: []string{"--diff-algorithm=minimal", "--ignore-blank-lines", "--ignore-submodules=all"},
+		},
+		{
+			give:        Version{1, 8, 3},
+			wantFlags:   []string{"--diff-algorithm=minimal"},
+			unsupported: []string{"--ignore-blank-lines", "--minimal"},
+		},
+		{
+			give:        Version{1, 7, 12},
+			wantFlags:   []string{"--minimal", "--ignore-submodules=all"},
+			unsupported: []string{"--diff-algorithm=minimal", "--ignore-blank-lines"},
+		},
+	} {
+		var flags = diffFlags(tc.give)
+
+		for _, want := range tc.wantFlags {
+			if !slices.Contains(flags, want) {
+				t.Errorf("%s: expected %q in %q", tc.give, want, flags)
+			}
+		}
+
+		for _, want := range tc.unsupported {
+			if slices.Contains(flags, want) {
+				t.Errorf("%s: unexpected %q in %q", tc.give, want, flags)
+			}
+		}
+	}
+}
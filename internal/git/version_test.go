@@ -0,0 +1,71 @@
+package git_test
+
+import (
+	"context"
+	"testing"
+
+	"gh.tarampamp.am/describe-commit/internal/git"
+)
+
+func TestParseVersion(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]git.Version{
+		"git version 2.39.5\n":                  ver(2, 39, 5),
+		"git version 2.47.1.windows.2":          ver(2, 47, 1),
+		"git version 2.37.1 (Apple Git-137.1)":  ver(2, 37, 1),
+		"git version 1.8\n":                     ver(1, 8, 0),
+		"git version 1.7.1.4 (some distro git)": ver(1, 7, 1),
+	} {
+		got, err := git.ParseVersion(give)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", give, err)
+		}
+
+		if got != want {
+			t.Errorf("%q: want %s, got %s", give, want, got)
+		}
+	}
+
+	if _, err := git.ParseVersion("not a git"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestVersion_AtLeast(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		v, o git.Version
+		want bool
+	}{
+		{ver(2, 39, 5), ver(2, 39, 5), true},
+		{ver(2, 39, 5), ver(1, 9, 0), true},
+		{ver(1, 10, 0), ver(1, 9, 9), true},
+		{ver(1, 8, 5), ver(1, 9, 0), false},
+		{ver(1, 8, 3), ver(1, 8, 4), false},
+		{ver(0, 99, 9), ver(1, 0, 0), false},
+	} {
+		if got := tc.v.AtLeast(tc.o); got != tc.want {
+			t.Errorf("%s.AtLeast(%s): want %t, got %t", tc.v, tc.o, tc.want, got)
+		}
+	}
+}
+
+func TestGitVersion(t *testing.T) {
+	t.Parallel()
+
+	v, err := git.GitVersion(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !v.AtLeast(git.Version{Major: 1}) {
+		t.Errorf("unexpected version: %s", v)
+	}
+}
+
+// ver is a shortcut for the [git.Version] creation.
+func ver(major, minor, patch int) git.Version {
+	return git.Version{Major: major, Minor: minor, Patch: patch}
+}